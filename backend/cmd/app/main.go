@@ -3,21 +3,84 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/duplicates"
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/health"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/metrics"
+	"github.com/BomScoob12/homelab-file-manager/internal/mounts"
 	"github.com/BomScoob12/homelab-file-manager/internal/routes"
+	"github.com/BomScoob12/homelab-file-manager/internal/s3"
+	"github.com/BomScoob12/homelab-file-manager/internal/sftpd"
+	"github.com/BomScoob12/homelab-file-manager/internal/versions"
+	"github.com/BomScoob12/homelab-file-manager/internal/watch"
 	"github.com/joho/godotenv"
 )
 
 func main() {
 	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: .env file not found or could not be loaded: %v", err)
+	envErr := godotenv.Load()
+
+	// Configure structured logging from LOG_LEVEL and LOG_FORMAT
+	logging.Setup()
+	if envErr != nil {
+		slog.Warn(".env file not found or could not be loaded", "error", envErr)
+	}
+
+	// Load users and authentication settings
+	if err := auth.Setup(); err != nil {
+		slog.Error("failed to configure authentication", "error", err)
+		os.Exit(1)
+	}
+	if !auth.Default.Enabled() {
+		slog.Warn("authentication disabled, all requests are treated as anonymous admin")
+	}
+
+	// Open the audit log before any handler can mutate files
+	if err := audit.Setup(); err != nil {
+		slog.Error("failed to open audit log", "error", err)
+		os.Exit(1)
+	}
+
+	// Open the version store before any handler can overwrite files
+	if err := versions.Setup(); err != nil {
+		slog.Error("failed to open version store", "error", err)
+		os.Exit(1)
+	}
+
+	// Load earlier duplicate scans so their results survive restarts
+	if err := duplicates.Setup(); err != nil {
+		slog.Error("failed to open duplicate scan store", "error", err)
+		os.Exit(1)
+	}
+
+	// Background jobs, with the state of earlier jobs
+	if err := jobs.Setup(); err != nil {
+		slog.Error("failed to open job store", "error", err)
+		os.Exit(1)
+	}
+
+	// Directory watches behind /file/watch
+	if err := watch.Setup(); err != nil {
+		slog.Error("failed to configure directory watching", "error", err)
+		os.Exit(1)
+	}
+
+	// Open the change journal before any handler can mutate files
+	if err := changes.Setup(); err != nil {
+		slog.Error("failed to open change journal", "error", err)
+		os.Exit(1)
 	}
 
 	// Get port from environment variable or use default
@@ -35,9 +98,87 @@ func main() {
 		basePath = "localhost"
 	}
 
+	// Storage: the base path plus any mounted backends
+	backend, err := mounts.Setup()
+	if err != nil {
+		slog.Error("failed to configure storage mounts", "error", err)
+		os.Exit(1)
+	}
+
+	// Shared file service, used by the API and every other frontend
+	fileService := files.NewFileServiceWithBackend(backend)
+
+	// Journal changes made outside the API too
+	changes.Default.WatchTree(backend, watch.Default)
+
+	router, err := routes.NewRouter(fileService)
+	if err != nil {
+		slog.Error("failed to initialize router", "error", err)
+		os.Exit(1)
+	}
+
 	server := &http.Server{
 		Addr:    host + ":" + port,
-		Handler: routes.NewRouter(),
+		Handler: router,
+	}
+
+	// Optional dedicated metrics listener
+	var metricsServer *http.Server
+	if addr := metrics.ListenAddr(); addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:    addr,
+			Handler: metricsMux,
+		}
+
+		go func() {
+			slog.Info("📈 Metrics server listening", "addr", addr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("metrics server listen error", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Optional S3-compatible gateway
+	var s3Server *http.Server
+	if addr := s3.ListenAddr(); addr != "" {
+		gateway, err := s3.NewServer(fileService)
+		if err != nil {
+			slog.Error("failed to initialize S3 gateway", "error", err)
+			os.Exit(1)
+		}
+		s3Server = &http.Server{
+			Addr:    addr,
+			Handler: gateway.Handler(),
+		}
+
+		go func() {
+			slog.Info("🪣 S3 gateway listening", "addr", addr)
+			if err := s3Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("S3 gateway listen error", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Optional SFTP server for devices that can only push over SSH
+	var sftpServer *sftpd.Server
+	if addr := sftpd.ListenAddr(); addr != "" {
+		sftpServer, err = sftpd.NewServer(fileService, auth.Default)
+		if err != nil {
+			slog.Error("failed to initialize SFTP server", "error", err)
+			os.Exit(1)
+		}
+
+		go func() {
+			slog.Info("🔐 SFTP server listening", "addr", addr)
+			if err := sftpServer.ListenAndServe(addr); err != nil && err != sftpd.ErrServerClosed {
+				slog.Error("SFTP server listen error", "error", err)
+				os.Exit(1)
+			}
+		}()
 	}
 
 	go func() {
@@ -47,28 +188,70 @@ func main() {
 		fmt.Printf("🔧 Press Ctrl+C to stop\n\n")
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server listen error: %v", err)
+			slog.Error("server listen error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
-	handleStopProcess(server)
+	// Optional listeners that were started, shut down alongside the main server
+	var extraServers []shutdowner
+	if metricsServer != nil {
+		extraServers = append(extraServers, metricsServer)
+	}
+	if s3Server != nil {
+		extraServers = append(extraServers, s3Server)
+	}
+	if sftpServer != nil {
+		extraServers = append(extraServers, sftpServer)
+	}
+
+	handleStopProcess(server, extraServers...)
 }
 
-func handleStopProcess(server *http.Server) {
+// shutdowner is a listener that supports graceful shutdown
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// handleStopProcess waits for a stop signal and shuts down the main server and any optional listeners
+func handleStopProcess(server *http.Server, extraServers ...shutdowner) {
 	// stop signal
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	<-stop
-	log.Println("🛑 Shutdown signal received")
+	slog.Info("🛑 Shutdown signal received")
+
+	// Fail readiness first so probes stop routing traffic here
+	health.MarkShuttingDown()
+
+	// End event streams, which would otherwise keep their connections open until the timeout
+	if err := watch.Default.Close(); err != nil {
+		slog.Error("failed to stop directory watcher", "error", err)
+	}
 
 	// get process time from bg process + timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Cancel background jobs and let them record what they finished
+	if err := jobs.Default.Shutdown(ctx); err != nil {
+		slog.Error("background jobs did not stop cleanly", "error", err)
+	}
+
+	for _, extra := range extraServers {
+		if err := extra.Shutdown(ctx); err != nil {
+			slog.Error("listener shutdown failed", "error", err)
+		}
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("❌ Graceful shutdown failed: %v", err)
+		slog.Error("❌ Graceful shutdown failed", "error", err)
 	} else {
-		log.Println("✅ Server stopped successfully")
+		slog.Info("✅ Server stopped successfully")
+	}
+
+	if err := changes.Default.Close(); err != nil {
+		slog.Error("failed to close change journal", "error", err)
 	}
 }
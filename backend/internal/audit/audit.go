@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// Operations recorded in the audit log
+const (
+	OpDelete      = "delete"
+	OpUpload      = "upload"
+	OpWrite       = "write"
+	OpMkdir       = "mkdir"
+	OpMove        = "move"
+	OpCopy        = "copy"
+	OpChmod       = "chmod"
+	OpChown       = "chown"
+	OpXattrSet    = "xattr_set"
+	OpXattrRemove = "xattr_remove"
+	OpRestore     = "restore"
+	OpLink        = "link"
+	OpSymlink     = "symlink"
+	OpShareCreate = "share_create"
+	OpShareRevoke = "share_revoke"
+
+	OpFileRequestCreate = "file_request_create"
+	OpFileRequestRevoke = "file_request_revoke"
+)
+
+// Outcomes recorded in the audit log
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Entry is a single audit record
+type Entry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestId,omitempty"`
+	User      string    `json:"user"`
+	ClientIP  string    `json:"clientIp,omitempty"`
+	Operation string    `json:"operation"`
+	Path      string    `json:"path"`
+	Target    string    `json:"target,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// Filter selects entries returned by Query
+type Filter struct {
+	User       string
+	Operation  string
+	PathPrefix string
+	Outcome    string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+}
+
+// Recorder records mutating operations
+type Recorder interface {
+	Record(ctx context.Context, entry Entry)
+}
+
+// Log is a recorder whose entries can be queried back
+type Log interface {
+	Recorder
+	Query(filter Filter) ([]Entry, error)
+}
+
+// Default is the process-wide audit log; it discards entries until Setup is called
+var Default Log = nopLog{}
+
+// NewEntry builds an entry for an operation, filling caller details from the
+// context and the outcome from err
+func NewEntry(ctx context.Context, operation, path, target string, bytes int64, err error) Entry {
+	entry := Entry{
+		Time:      time.Now().UTC(),
+		RequestID: logging.RequestID(ctx),
+		User:      auth.FromContext(ctx).Username,
+		ClientIP:  auth.ClientIP(ctx),
+		Operation: operation,
+		Path:      path,
+		Target:    target,
+		Outcome:   OutcomeSuccess,
+		Bytes:     bytes,
+	}
+
+	if err != nil {
+		entry.Outcome = OutcomeFailure
+		entry.Error = err.Error()
+	}
+
+	return entry
+}
+
+// Matches reports whether an entry passes the filter
+func (f Filter) Matches(entry Entry) bool {
+	if f.User != "" && entry.User != f.User {
+		return false
+	}
+	if f.Operation != "" && entry.Operation != f.Operation {
+		return false
+	}
+	if f.PathPrefix != "" && !hasPathPrefix(entry.Path, f.PathPrefix) && !hasPathPrefix(entry.Target, f.PathPrefix) {
+		return false
+	}
+	if f.Outcome != "" && entry.Outcome != f.Outcome {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// hasPathPrefix reports whether path equals prefix or lies below it
+func hasPathPrefix(path, prefix string) bool {
+	if path == "" {
+		return false
+	}
+	if prefix == "/" || path == prefix {
+		return true
+	}
+	return len(path) > len(prefix) && path[:len(prefix)] == prefix && path[len(prefix)] == '/'
+}
+
+// nopLog discards entries
+type nopLog struct{}
+
+func (nopLog) Record(ctx context.Context, entry Entry) {}
+
+func (nopLog) Query(filter Filter) ([]Entry, error) {
+	return []Entry{}, nil
+}
@@ -0,0 +1,279 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/health"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+const (
+	defaultMaxSizeMB = 10
+	defaultMaxFiles  = 5
+
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// FileLog is an append-only JSON lines audit log with size-based rotation
+type FileLog struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// Setup opens the audit log configured by AUDIT_LOG_PATH, AUDIT_MAX_SIZE_MB
+// and AUDIT_MAX_FILES and installs it as the default log
+func Setup() error {
+	path := os.Getenv("AUDIT_LOG_PATH")
+	if path == "" {
+		path = config.StateDir("audit", "audit.log")
+	}
+
+	maxSizeMB := config.EnvInt("AUDIT_MAX_SIZE_MB", defaultMaxSizeMB, 0)
+	maxFiles := config.EnvInt("AUDIT_MAX_FILES", defaultMaxFiles, 0)
+
+	log, err := NewFileLog(path, int64(maxSizeMB)*1024*1024, maxFiles)
+	if err != nil {
+		return err
+	}
+
+	Default = log
+	health.Register("audit_log", log.Check)
+	return nil
+}
+
+// NewFileLog opens or creates the audit log at path
+func NewFileLog(path string, maxSize int64, maxFiles int) (*FileLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	l := &FileLog{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the current log file for appending
+func (l *FileLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// Record appends an entry to the log, rotating it when it grows too large
+func (l *FileLog) Record(ctx context.Context, entry Entry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to encode audit entry", "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			logging.FromContext(ctx).Error("failed to rotate audit log", "error", err)
+		}
+	}
+
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			logging.FromContext(ctx).Error("audit entry lost", "error", err, "operation", entry.Operation, "path", entry.Path)
+			return
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err == nil {
+		err = l.file.Sync()
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to write audit entry", "error", err, "operation", entry.Operation, "path", entry.Path)
+	}
+}
+
+// rotate shifts audit.log.N to audit.log.N+1 and starts a new current file
+func (l *FileLog) rotate() error {
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+
+	if l.maxFiles > 0 {
+		os.Remove(l.rotatedPath(l.maxFiles))
+		for i := l.maxFiles - 1; i >= 1; i-- {
+			if err := os.Rename(l.rotatedPath(i), l.rotatedPath(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		if err := os.Rename(l.path, l.rotatedPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Truncate(l.path, 0); err != nil {
+		return err
+	}
+
+	return l.open()
+}
+
+// rotatedPath returns the path of the n-th rotated file
+func (l *FileLog) rotatedPath(n int) string {
+	return l.path + "." + strconv.Itoa(n)
+}
+
+// Query returns matching entries, newest first
+func (l *FileLog) Query(filter Filter) ([]Entry, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	if limit > maxQueryLimit {
+		limit = maxQueryLimit
+	}
+
+	snapshot, err := l.snapshot()
+	defer func() {
+		for _, part := range snapshot {
+			part.file.Close()
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, part := range snapshot {
+		matches, err := readEntries(io.LimitReader(part.file, part.size), filter)
+		if err != nil {
+			return nil, err
+		}
+
+		// Entries within a file are oldest first
+		for i := len(matches) - 1; i >= 0; i-- {
+			entries = append(entries, matches[i])
+			if len(entries) == limit {
+				return entries, nil
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// snapshotFile is a log file opened for a query, to be read up to size
+type snapshotFile struct {
+	file *os.File
+	size int64
+}
+
+// snapshot opens the current and rotated log files, newest first. Only this
+// takes the lock: the open files stay readable when a later rotation renames
+// or removes them, and reading up to the size seen here leaves out entries
+// appended meanwhile, so queries never hold up Record.
+func (l *FileLog) snapshot() ([]snapshotFile, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Newest file first: audit.log, audit.log.1, audit.log.2, ...
+	paths := []string{l.path}
+	for i := 1; i <= l.maxFiles; i++ {
+		paths = append(paths, l.rotatedPath(i))
+	}
+
+	var snapshot []snapshotFile
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return snapshot, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return snapshot, err
+		}
+		snapshot = append(snapshot, snapshotFile{file: file, size: info.Size()})
+	}
+	return snapshot, nil
+}
+
+// readEntries reads the matching entries of one log file in file order
+func readEntries(r io.Reader, filter Filter) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // Skip torn or corrupted lines
+		}
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// Check reports whether the log file is open for writing
+func (l *FileLog) Check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return l.open()
+	}
+	return nil
+}
+
+// Close closes the current log file
+func (l *FileLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
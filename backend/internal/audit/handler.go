@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/httputil"
+)
+
+// QueryResponse is returned by GET /admin/audit
+type QueryResponse struct {
+	Success     bool      `json:"success"`
+	Entries     []Entry   `json:"entries"`
+	TotalItems  int       `json:"totalItems"`
+	RequestTime time.Time `json:"requestTime"`
+}
+
+// Handler serves GET /admin/audit with user, op, path, outcome, since, until
+// and limit query filters
+func Handler(log Log) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			httputil.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := Filter{
+			User:       query.Get("user"),
+			Operation:  query.Get("op"),
+			PathPrefix: query.Get("path"),
+			Outcome:    query.Get("outcome"),
+		}
+
+		var err error
+		if filter.Since, err = parseTime(query.Get("since")); err != nil {
+			httputil.SendError(w, "Invalid since parameter, expected RFC3339 time", http.StatusBadRequest)
+			return
+		}
+		if filter.Until, err = parseTime(query.Get("until")); err != nil {
+			httputil.SendError(w, "Invalid until parameter, expected RFC3339 time", http.StatusBadRequest)
+			return
+		}
+		if limit := query.Get("limit"); limit != "" {
+			if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+				httputil.SendError(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries, err := log.Query(filter)
+		if err != nil {
+			slog.Error("failed to query audit log", "error", err)
+			httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		httputil.SendJSON(w, QueryResponse{
+			Success:     true,
+			Entries:     entries,
+			TotalItems:  len(entries),
+			RequestTime: time.Now(),
+		}, http.StatusOK)
+	})
+}
+
+// parseTime parses an optional RFC3339 timestamp
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles understood by the permission checks
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// AnonymousUser is the identity name used when authentication is disabled
+const AnonymousUser = "anonymous"
+
+// ErrUnauthorized is returned when a request carries no valid credentials
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Identity describes the caller of a request
+type Identity struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// IsAdmin reports whether the identity holds the admin role
+func (i Identity) IsAdmin() bool {
+	return i.Role == RoleAdmin
+}
+
+// User is an entry of the users file
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"passwordHash"`
+	Role         string `json:"role"`
+}
+
+// APIToken is a bearer token entry of the users file, used by other
+// instances and scripts; only the SHA-256 hex digest of the token is stored
+type APIToken struct {
+	Name      string `json:"name"`
+	TokenHash string `json:"tokenHash"`
+	Role      string `json:"role"`
+}
+
+// usersFile is the on-disk format of AUTH_USERS_FILE
+type usersFile struct {
+	Users  []User     `json:"users"`
+	Tokens []APIToken `json:"tokens"`
+}
+
+// Authenticator resolves request identities from basic auth or a trusted proxy header
+type Authenticator struct {
+	users          map[string]User
+	tokens         map[string]APIToken
+	proxyHeader    string
+	trustedProxies []*net.IPNet
+	adminUsers     map[string]bool
+
+	// verified caches digests of credentials that already passed bcrypt
+	verified sync.Map
+}
+
+// Default is the process-wide authenticator; it allows anonymous access until Setup is called
+var Default = &Authenticator{}
+
+// Setup loads the default authenticator from the environment:
+// AUTH_USERS_FILE enables basic auth against a JSON users file,
+// AUTH_PROXY_HEADER trusts a username header set by a forward-auth proxy at
+// one of the AUTH_TRUSTED_PROXIES addresses and AUTH_ADMIN_USERS lists
+// proxy-authenticated users that get the admin role.
+func Setup() error {
+	authenticator, err := NewAuthenticator(
+		os.Getenv("AUTH_USERS_FILE"),
+		os.Getenv("AUTH_PROXY_HEADER"),
+		splitList(os.Getenv("AUTH_TRUSTED_PROXIES")),
+		splitList(os.Getenv("AUTH_ADMIN_USERS")),
+	)
+	if err != nil {
+		return err
+	}
+	Default = authenticator
+	return nil
+}
+
+// NewAuthenticator creates an authenticator; with no users file and no proxy
+// header every request is treated as an anonymous admin. The proxy header is
+// only honoured from trustedProxies, IP addresses or CIDR ranges, which are
+// required with it since anyone reaching the port directly could set it.
+func NewAuthenticator(usersPath, proxyHeader string, trustedProxies, adminUsers []string) (*Authenticator, error) {
+	a := &Authenticator{
+		users:       make(map[string]User),
+		tokens:      make(map[string]APIToken),
+		proxyHeader: proxyHeader,
+		adminUsers:  make(map[string]bool),
+	}
+
+	if proxyHeader != "" && len(trustedProxies) == 0 {
+		return nil, fmt.Errorf("AUTH_TRUSTED_PROXIES must list the proxy addresses allowed to set %s", proxyHeader)
+	}
+	for _, proxy := range trustedProxies {
+		network, err := parseNetwork(proxy)
+		if err != nil {
+			return nil, err
+		}
+		a.trustedProxies = append(a.trustedProxies, network)
+	}
+
+	for _, name := range adminUsers {
+		a.adminUsers[name] = true
+	}
+
+	if usersPath != "" {
+		data, err := os.ReadFile(usersPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read users file: %w", err)
+		}
+
+		var file usersFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse users file: %w", err)
+		}
+
+		for _, user := range file.Users {
+			if user.Username == "" || user.PasswordHash == "" {
+				return nil, fmt.Errorf("users file entry missing username or passwordHash")
+			}
+			if user.Role == "" {
+				user.Role = RoleUser
+			}
+			a.users[user.Username] = user
+		}
+
+		for _, token := range file.Tokens {
+			digest := strings.ToLower(token.TokenHash)
+			if token.Name == "" || len(digest) != sha256.Size*2 {
+				return nil, fmt.Errorf("users file token entry missing name or SHA-256 tokenHash")
+			}
+			if token.Role == "" {
+				token.Role = RoleUser
+			}
+			a.tokens[digest] = token
+		}
+	}
+
+	return a, nil
+}
+
+// Enabled reports whether any authentication method is configured
+func (a *Authenticator) Enabled() bool {
+	return len(a.users) > 0 || len(a.tokens) > 0 || a.proxyHeader != ""
+}
+
+// HasPasswordUsers reports whether the users file defines any accounts,
+// which non-HTTP frontends need since they cannot use proxy authentication
+func (a *Authenticator) HasPasswordUsers() bool {
+	return len(a.users) > 0
+}
+
+// Authenticate resolves the identity of a request
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	if !a.Enabled() {
+		return Identity{Username: AnonymousUser, Role: RoleAdmin}, nil
+	}
+
+	if a.proxyHeader != "" && a.fromTrustedProxy(r) {
+		if username := strings.TrimSpace(r.Header.Get(a.proxyHeader)); username != "" {
+			return a.identityFor(username), nil
+		}
+	}
+
+	if token, ok := bearerToken(r); ok {
+		if identity, ok := a.VerifyToken(token); ok {
+			return identity, nil
+		}
+		return Identity{}, ErrUnauthorized
+	}
+
+	if username, password, ok := r.BasicAuth(); ok {
+		if identity, ok := a.VerifyPassword(username, password); ok {
+			return identity, nil
+		}
+	}
+
+	return Identity{}, ErrUnauthorized
+}
+
+// fromTrustedProxy reports whether the request comes directly from a trusted proxy
+func (a *Authenticator) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range a.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetwork parses a CIDR range or a single IP address
+func parseNetwork(value string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q: expected an IP address or CIDR range", value)
+	}
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	} else {
+		ip = ip.To4()
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// VerifyPassword checks a username and password against the users file
+func (a *Authenticator) VerifyPassword(username, password string) (Identity, bool) {
+	user, exists := a.users[username]
+	if !exists {
+		return Identity{}, false
+	}
+
+	digest := sha256.Sum256([]byte(username + "\x00" + password + "\x00" + user.PasswordHash))
+	if _, ok := a.verified.Load(digest); !ok {
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+			return Identity{}, false
+		}
+		a.verified.Store(digest, struct{}{})
+	}
+
+	return Identity{Username: user.Username, Role: user.Role}, true
+}
+
+// VerifyToken checks a bearer token against the token entries of the users file
+func (a *Authenticator) VerifyToken(token string) (Identity, bool) {
+	digest := sha256.Sum256([]byte(token))
+	entry, exists := a.tokens[hex.EncodeToString(digest[:])]
+	if !exists {
+		return Identity{}, false
+	}
+	return Identity{Username: entry.Name, Role: entry.Role}, true
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// identityFor builds the identity of a proxy-authenticated user
+func (a *Authenticator) identityFor(username string) Identity {
+	if user, exists := a.users[username]; exists {
+		return Identity{Username: user.Username, Role: user.Role}
+	}
+	if a.adminUsers[username] {
+		return Identity{Username: username, Role: RoleAdmin}
+	}
+	return Identity{Username: username, Role: RoleUser}
+}
+
+// splitList splits a comma separated environment value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+type identityKey struct{}
+type clientIPKey struct{}
+
+// WithIdentity stores the caller identity in the context
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the caller identity stored in the context
+func FromContext(ctx context.Context) Identity {
+	if identity, ok := ctx.Value(identityKey{}).(Identity); ok {
+		return identity
+	}
+	return Identity{Username: AnonymousUser}
+}
+
+// WithClientIP stores the client IP in the context
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the client IP stored in the context
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// RandomToken returns a URL-safe random token built from n random bytes
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
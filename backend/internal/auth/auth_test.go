@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyHeaderNeedsTrustedProxy(t *testing.T) {
+	if _, err := NewAuthenticator("", "Remote-User", nil, nil); err == nil {
+		t.Fatal("NewAuthenticator() without trusted proxies succeeded")
+	}
+	if _, err := NewAuthenticator("", "Remote-User", []string{"proxy.local"}, nil); err == nil {
+		t.Fatal("NewAuthenticator() with an invalid trusted proxy succeeded")
+	}
+
+	a, err := NewAuthenticator("", "Remote-User", []string{"127.0.0.1", "172.18.0.0/16"}, []string{"alice"})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantUser   string
+		wantErr    error
+	}{
+		{name: "trusted address", remoteAddr: "127.0.0.1:40000", wantUser: "alice"},
+		{name: "trusted range", remoteAddr: "172.18.3.4:40000", wantUser: "alice"},
+		{name: "untrusted address", remoteAddr: "192.168.1.20:40000", wantErr: ErrUnauthorized},
+		{name: "untrusted IPv6 address", remoteAddr: "[::1]:40000", wantErr: ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/file/list", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("Remote-User", "alice")
+
+			identity, err := a.Authenticate(r)
+			if !errors.Is(err, tt.wantErr) || identity.Username != tt.wantUser {
+				t.Fatalf("Authenticate() = %q, %v, want %q, %v", identity.Username, err, tt.wantUser, tt.wantErr)
+			}
+			if tt.wantUser != "" && !identity.IsAdmin() {
+				t.Fatal("Authenticate() did not give an admin user the admin role")
+			}
+		})
+	}
+}
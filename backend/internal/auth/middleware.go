@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/httputil"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// Middleware authenticates every request with the default authenticator and
+// stores the identity and client IP in the request context
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithClientIP(r.Context(), RequestClientIP(r))
+
+		// Preflight requests never carry credentials
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		identity, err := Default.Authenticate(r)
+		if err != nil {
+			logging.FromContext(ctx).Warn("authentication failed", "path", r.URL.Path, "client_ip", ClientIP(ctx))
+			w.Header().Set("WWW-Authenticate", `Basic realm="file-manager"`)
+			httputil.SendError(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithIdentity(ctx, identity)))
+	})
+}
+
+// RequireAdmin rejects requests whose identity does not hold the admin role
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions && !FromContext(r.Context()).IsAdmin() {
+			httputil.SendError(w, "Admin permission required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestClientIP returns the client IP of a request, honouring
+// X-Forwarded-For only when TRUST_PROXY_HEADERS is enabled
+func RequestClientIP(r *http.Request) string {
+	if trust, _ := strconv.ParseBool(os.Getenv("TRUST_PROXY_HEADERS")); trust {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return strings.TrimSpace(realIP)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
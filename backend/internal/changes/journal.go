@@ -0,0 +1,453 @@
+package changes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/statefile"
+)
+
+// Change types
+const (
+	TypeCreate = "create"
+	TypeModify = "modify"
+	TypeDelete = "delete"
+	TypeRename = "rename"
+)
+
+// Sources of changes
+const (
+	SourceAPI      = "api"
+	SourceExternal = "external"
+)
+
+// Defaults used when the CHANGES_* variables are unset
+const (
+	defaultRetention = 7 * 24 * time.Hour
+	defaultMaxEvents = 100000
+	defaultMaxDirs   = 10000
+
+	defaultPageLimit = 1000
+	maxPageLimit     = 10000
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by a journal
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Event is one change in the journal
+type Event struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	Path string    `json:"path"`
+	// From is the previous path of a renamed entry
+	From  string `json:"from,omitempty"`
+	IsDir bool   `json:"isDir"`
+	// Size and ModTime describe the entry after the change; they are omitted for deletes
+	Size    int64      `json:"size,omitempty"`
+	ModTime *time.Time `json:"modTime,omitempty"`
+	Source  string     `json:"source"`
+	User    string     `json:"user,omitempty"`
+}
+
+// Page is a slice of the journal following a cursor
+type Page struct {
+	Events []Event
+	// Cursor resumes after the last event of the page
+	Cursor  string
+	HasMore bool
+	// Resync is set when events after the requested cursor were dropped; the
+	// client must reload everything and continue from Cursor
+	Resync bool
+}
+
+// state is the journal metadata persisted next to the events
+type state struct {
+	Epoch string `json:"epoch"`
+	// Floor is the sequence number of the newest dropped event
+	Floor uint64 `json:"floor"`
+	// Clean is set on orderly shutdown; otherwise events may have been lost
+	// and the journal starts a new epoch
+	Clean bool `json:"clean"`
+}
+
+// Journal is an ordered log of changes to files, kept for a retention window
+type Journal struct {
+	mu        sync.Mutex
+	dir       string
+	retention time.Duration
+	maxEvents int
+	maxDirs   int
+
+	state  state
+	seq    uint64
+	events []Event
+	file   *os.File
+	// lines counts the events in the file, including dropped ones not yet compacted away
+	lines int
+
+	tree *tree
+}
+
+// Default is the process-wide journal; it keeps changes in memory until Setup is called
+var Default = newJournal("", defaultRetention, defaultMaxEvents, defaultMaxDirs)
+
+// Setup opens the default journal in the state directory. CHANGES_RETENTION
+// sets how long changes are kept, as a duration such as 168h;
+// CHANGES_MAX_EVENTS caps how many are kept and CHANGES_WATCH_MAX_DIRS how
+// many directories are watched for changes made outside the API (0 disables
+// watching).
+func Setup() error {
+	retention := defaultRetention
+	if value := os.Getenv("CHANGES_RETENTION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("CHANGES_RETENTION must be a duration such as 168h")
+		}
+		retention = parsed
+	}
+
+	journal, err := Open(config.StateDir("changes"), retention,
+		config.EnvInt("CHANGES_MAX_EVENTS", defaultMaxEvents, 0), config.EnvInt("CHANGES_WATCH_MAX_DIRS", defaultMaxDirs, 0))
+	if err != nil {
+		return err
+	}
+	Default = journal
+	return nil
+}
+
+// newJournal creates an empty journal; dir is empty for in-memory journals
+func newJournal(dir string, retention time.Duration, maxEvents, maxDirs int) *Journal {
+	return &Journal{
+		dir:       dir,
+		retention: retention,
+		maxEvents: maxEvents,
+		maxDirs:   maxDirs,
+		state:     state{Epoch: newEpoch()},
+	}
+}
+
+// Open loads the journal persisted in dir
+func Open(dir string, retention time.Duration, maxEvents, maxDirs int) (*Journal, error) {
+	j := newJournal(dir, retention, maxEvents, maxDirs)
+
+	found, err := statefile.Load(j.statePath(), &j.state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load change journal: %w", err)
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	if found && !j.state.Clean {
+		slog.Warn("change journal was not closed cleanly, clients will resync")
+		j.state.Epoch = newEpoch()
+	}
+	if j.state.Epoch == "" {
+		j.state.Epoch = newEpoch()
+	}
+
+	j.prune(time.Now())
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// load reads the persisted events
+func (j *Journal) load() error {
+	file, err := os.Open(j.eventsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open change journal: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Seq <= j.seq {
+			continue // Skip torn or corrupted lines
+		}
+		j.events = append(j.events, event)
+		j.seq = event.Seq
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read change journal: %w", err)
+	}
+
+	// The metadata may be older than the events after a crash
+	if len(j.events) > 0 && j.events[0].Seq-1 > j.state.Floor {
+		j.state.Floor = j.events[0].Seq - 1
+	}
+	if j.state.Floor > j.seq {
+		j.seq = j.state.Floor
+	}
+	return nil
+}
+
+// Record appends a change made through the API, filling the user from the context
+func (j *Journal) Record(ctx context.Context, event Event) {
+	event.Source = SourceAPI
+	event.User = auth.FromContext(ctx).Username
+	if j.tree != nil {
+		j.tree.expect(event)
+	}
+	if err := j.append(event); err != nil {
+		logging.FromContext(ctx).Error("failed to record change", "error", err, "type", event.Type, "path", event.Path)
+	}
+}
+
+// append assigns the next sequence number to an event and stores it
+func (j *Journal) append(event Event) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.seq++
+	event.Seq = j.seq
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	j.events = append(j.events, event)
+	j.prune(event.Time)
+
+	if j.dir == "" {
+		return nil
+	}
+	if j.file == nil {
+		if err := j.open(); err != nil {
+			return err
+		}
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode change: %w", err)
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write change journal: %w", err)
+	}
+	j.lines++
+
+	// Rewrite the file once most of it is dropped events
+	if j.lines > 2*len(j.events)+1000 {
+		return j.compact()
+	}
+	return nil
+}
+
+// Since returns up to limit events after cursor, optionally only those at or
+// below pathPrefix. An empty cursor returns the current cursor without events.
+func (j *Journal) Since(cursor, pathPrefix string, limit int) (Page, error) {
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.prune(time.Now())
+	page := Page{Events: []Event{}, Cursor: j.cursor(j.seq)}
+	if cursor == "" {
+		return page, nil
+	}
+
+	epoch, after, err := parseCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+	if epoch != j.state.Epoch || after < j.state.Floor || after > j.seq {
+		page.Resync = true
+		return page, nil
+	}
+
+	last := after
+	start := sort.Search(len(j.events), func(i int) bool { return j.events[i].Seq > after })
+	for _, event := range j.events[start:] {
+		if len(page.Events) == limit {
+			page.HasMore = true
+			break
+		}
+		last = event.Seq
+		if pathPrefix == "" || hasPathPrefix(event.Path, pathPrefix) || hasPathPrefix(event.From, pathPrefix) {
+			page.Events = append(page.Events, event)
+		}
+	}
+	page.Cursor = j.cursor(last)
+	return page, nil
+}
+
+// ValidCursor reports whether cursor has the form of a journal cursor
+func ValidCursor(cursor string) bool {
+	_, _, err := parseCursor(cursor)
+	return err == nil
+}
+
+// Close flushes the journal and marks it as cleanly closed
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.dir == "" {
+		return nil
+	}
+	if j.file != nil {
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync change journal: %w", err)
+		}
+		j.file.Close()
+		j.file = nil
+	}
+	j.state.Clean = true
+	return j.saveState()
+}
+
+// prune drops events older than the retention window or beyond the maximum
+// count; the caller holds the lock
+func (j *Journal) prune(now time.Time) {
+	drop := 0
+	if j.maxEvents > 0 && len(j.events) > j.maxEvents {
+		drop = len(j.events) - j.maxEvents
+	}
+	cutoff := now.Add(-j.retention)
+	for drop < len(j.events) && j.events[drop].Time.Before(cutoff) {
+		drop++
+	}
+	if drop == 0 {
+		return
+	}
+	j.state.Floor = j.events[drop-1].Seq
+	j.events = append([]Event(nil), j.events[drop:]...)
+}
+
+// compact rewrites the events file with the retained events and saves the
+// metadata; the caller holds the lock
+func (j *Journal) compact() error {
+	if j.dir == "" {
+		return nil
+	}
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+
+	if err := os.MkdirAll(j.dir, 0750); err != nil {
+		return fmt.Errorf("failed to create change journal directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(j.dir, ".events.*")
+	if err != nil {
+		return fmt.Errorf("failed to compact change journal: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, event := range j.events {
+		if err := encoder.Encode(event); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact change journal: %w", err)
+		}
+	}
+	if err := writer.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compact change journal: %w", err)
+	}
+
+	// Save the floor first: a crash in between leaves a floor that load corrects
+	j.state.Clean = false
+	if err := j.saveState(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), j.eventsPath()); err != nil {
+		return fmt.Errorf("failed to compact change journal: %w", err)
+	}
+	j.lines = len(j.events)
+	return j.open()
+}
+
+// open opens the events file for appending; the caller holds the lock
+func (j *Journal) open() error {
+	file, err := os.OpenFile(j.eventsPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open change journal: %w", err)
+	}
+	j.file = file
+	return nil
+}
+
+// saveState persists the metadata; the caller holds the lock
+func (j *Journal) saveState() error {
+	if err := statefile.Save(j.statePath(), j.state); err != nil {
+		return fmt.Errorf("failed to save change journal: %w", err)
+	}
+	return nil
+}
+
+func (j *Journal) eventsPath() string {
+	return filepath.Join(j.dir, "events.log")
+}
+
+func (j *Journal) statePath() string {
+	return filepath.Join(j.dir, "journal.json")
+}
+
+// cursor returns the cursor resuming after seq
+func (j *Journal) cursor(seq uint64) string {
+	return fmt.Sprintf("%s-%d", j.state.Epoch, seq)
+}
+
+// parseCursor splits a cursor into the journal epoch and a sequence number
+func parseCursor(cursor string) (string, uint64, error) {
+	epoch, seq, ok := strings.Cut(cursor, "-")
+	if !ok || epoch == "" {
+		return "", 0, ErrInvalidCursor
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	return epoch, n, nil
+}
+
+// newEpoch returns a random journal epoch; cursors of other epochs require a resync
+func newEpoch() string {
+	epoch, err := auth.RandomToken(6)
+	if err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return strings.ReplaceAll(epoch, "-", "_")
+}
+
+// hasPathPrefix reports whether path equals prefix or lies below it
+func hasPathPrefix(path, prefix string) bool {
+	if path == "" {
+		return false
+	}
+	if prefix == "/" || path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, prefix+"/")
+}
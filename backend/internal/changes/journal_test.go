@@ -0,0 +1,142 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+)
+
+// record adds an API change by alice
+func record(j *Journal, changeType, path, from string) {
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Username: "alice"})
+	j.Record(ctx, Event{Type: changeType, Path: path, From: from})
+}
+
+// paths returns the paths of events
+func paths(events []Event) []string {
+	list := []string{}
+	for _, event := range events {
+		list = append(list, event.Path)
+	}
+	return list
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestJournalSince(t *testing.T) {
+	j := newJournal("", time.Hour, 0, 0)
+
+	start, err := j.Since("", "", 0)
+	if err != nil || len(start.Events) != 0 {
+		t.Fatalf("Since() without a cursor = %+v, %v, want only a cursor", start, err)
+	}
+
+	record(j, TypeCreate, "/docs/a.txt", "")
+	record(j, TypeModify, "/music/b.mp3", "")
+	record(j, TypeRename, "/music/c.txt", "/docs/c.txt")
+	record(j, TypeDelete, "/docs", "")
+
+	page, err := j.Since(start.Cursor, "", 0)
+	if err != nil {
+		t.Fatalf("Since() error = %v", err)
+	}
+	if got := paths(page.Events); !equal(got, []string{"/docs/a.txt", "/music/b.mp3", "/music/c.txt", "/docs"}) {
+		t.Fatalf("Since() = %v, want every change in order", got)
+	}
+	if event := page.Events[0]; event.User != "alice" || event.Source != SourceAPI || event.Seq == 0 {
+		t.Fatalf("event = %+v, want an API change by alice", event)
+	}
+
+	// Renames out of a directory concern it too
+	page, _ = j.Since(start.Cursor, "/docs", 0)
+	if got := paths(page.Events); !equal(got, []string{"/docs/a.txt", "/music/c.txt", "/docs"}) {
+		t.Fatalf("Since() below /docs = %v, want its changes and the rename out of it", got)
+	}
+
+	page, _ = j.Since(start.Cursor, "", 3)
+	if len(page.Events) != 3 || !page.HasMore {
+		t.Fatalf("Since() with limit 3 = %d events, more %v, want 3 and more", len(page.Events), page.HasMore)
+	}
+	page, _ = j.Since(page.Cursor, "", 3)
+	if got := paths(page.Events); !equal(got, []string{"/docs"}) || page.HasMore {
+		t.Fatalf("next page = %v, more %v, want the last change", got, page.HasMore)
+	}
+
+	page, _ = j.Since(page.Cursor, "", 0)
+	if len(page.Events) != 0 || page.Resync {
+		t.Fatalf("Since() at the end = %+v, want nothing new", page)
+	}
+}
+
+func TestJournalCursors(t *testing.T) {
+	j := newJournal("", time.Hour, 2, 0)
+	start, _ := j.Since("", "", 0)
+
+	if _, err := j.Since("garbage", "", 0); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("Since() with a malformed cursor error = %v, want %v", err, ErrInvalidCursor)
+	}
+	if page, _ := j.Since("other-0", "", 0); !page.Resync {
+		t.Fatal("Since() with a cursor of another journal did not ask for a resync")
+	}
+
+	// Only the two newest changes are kept, so a client behind them must resync
+	record(j, TypeCreate, "/a", "")
+	record(j, TypeCreate, "/b", "")
+	record(j, TypeCreate, "/c", "")
+	page, _ := j.Since(start.Cursor, "", 0)
+	if !page.Resync || len(page.Events) != 0 {
+		t.Fatalf("Since() behind the kept changes = %+v, want a resync", page)
+	}
+	page, _ = j.Since(page.Cursor, "", 0)
+	if page.Resync || len(page.Events) != 0 {
+		t.Fatalf("Since() with the resync cursor = %+v, want nothing new", page)
+	}
+}
+
+func TestJournalPersistence(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, time.Hour, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	start, _ := j.Since("", "", 0)
+	record(j, TypeCreate, "/a", "")
+	record(j, TypeModify, "/a", "")
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// A clean restart keeps cursors valid
+	j, err = Open(dir, time.Hour, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	page, err := j.Since(start.Cursor, "", 0)
+	if err != nil || page.Resync || len(page.Events) != 2 {
+		t.Fatalf("Since() after a clean restart = %+v, %v, want both changes", page, err)
+	}
+	record(j, TypeDelete, "/a", "")
+
+	// A crash may have lost changes, so clients start over
+	crashed, err := Open(dir, time.Hour, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if page, _ := crashed.Since(start.Cursor, "", 0); !page.Resync {
+		t.Fatalf("Since() after a crash = %+v, want a resync", page)
+	}
+	j.Close()
+}
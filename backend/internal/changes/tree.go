@@ -0,0 +1,276 @@
+package changes
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/watch"
+)
+
+// expectWindow is how long a change made through the API is expected to be
+// reported again by the watcher
+const expectWindow = 5 * time.Second
+
+// tree records changes made outside the API, reported by the watch hub for
+// every local directory of a backend
+type tree struct {
+	journal *Journal
+	backend fs.FileSystemInterface
+	hub     *watch.Hub
+
+	// dirs maps watched directories on disk to their virtual paths; it is
+	// only used by the run goroutine
+	dirs map[string]string
+	full bool
+
+	mu       sync.Mutex
+	queue    []batch
+	wake     chan struct{}
+	expected map[string]expectation
+}
+
+// batch is a set of events of one directory reported by the hub
+type batch struct {
+	dir    string
+	events []watch.Event
+}
+
+// expectation is the state of a path after a change made through the API
+type expectation struct {
+	exists  bool
+	isDir   bool
+	size    int64
+	modTime time.Time
+	until   time.Time
+}
+
+// WatchTree starts recording changes made outside the API, watching the
+// local directories of backend up to the journal's directory limit.
+// Directories created later are watched as they appear.
+func (j *Journal) WatchTree(backend fs.FileSystemInterface, hub *watch.Hub) {
+	if j.maxDirs == 0 {
+		return
+	}
+
+	t := &tree{
+		journal:  j,
+		backend:  backend,
+		hub:      hub,
+		dirs:     make(map[string]string),
+		wake:     make(chan struct{}, 1),
+		expected: make(map[string]expectation),
+	}
+	j.tree = t
+	hub.Listen(t.enqueue)
+	go t.run()
+}
+
+// enqueue queues events from the hub; it must not block since the hub holds a lock
+func (t *tree) enqueue(dir string, events []watch.Event) {
+	t.mu.Lock()
+	t.queue = append(t.queue, batch{dir: dir, events: events})
+	t.mu.Unlock()
+
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run watches the tree and then records queued events in order
+func (t *tree) run() {
+	start := time.Now()
+	t.add("/")
+	slog.Info("watching for external changes", "directories", len(t.dirs), "duration", time.Since(start))
+
+	for range t.wake {
+		t.mu.Lock()
+		queue := t.queue
+		t.queue = nil
+		t.mu.Unlock()
+
+		for _, batch := range queue {
+			t.process(batch)
+		}
+	}
+}
+
+// add watches a directory and the directories below it
+func (t *tree) add(root string) {
+	pending := []string{root}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+
+		if len(t.dirs) >= t.journal.maxDirs {
+			if !t.full {
+				slog.Warn("too many directories to watch, changes made outside the API are missed below the limit",
+					"limit", t.journal.maxDirs)
+				t.full = true
+			}
+			return
+		}
+
+		localPath, ok := fs.LocalPath(t.backend, dir)
+		if !ok {
+			continue
+		}
+		if err := t.hub.Pin(localPath); err != nil {
+			if errors.Is(err, watch.ErrClosed) {
+				return
+			}
+			slog.Warn("failed to watch directory", "path", dir, "error", err)
+			continue
+		}
+		t.dirs[localPath] = dir
+
+		entries, err := t.backend.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && entry.Mode()&os.ModeSymlink == 0 {
+				pending = append(pending, path.Join(dir, entry.Name()))
+			}
+		}
+	}
+}
+
+// forget stops tracking a directory that went away and the directories below it
+func (t *tree) forget(localPath string) bool {
+	if _, watched := t.dirs[localPath]; !watched {
+		return false
+	}
+	for dir := range t.dirs {
+		if dir == localPath || strings.HasPrefix(dir, localPath+string(filepath.Separator)) {
+			delete(t.dirs, dir)
+		}
+	}
+	t.full = false
+	return true
+}
+
+// process records the events of one directory that were not made through the API
+func (t *tree) process(batch batch) {
+	dir, watched := t.dirs[batch.dir]
+	if !watched {
+		return
+	}
+
+	for _, change := range batch.events {
+		event := Event{
+			Time:   change.Time,
+			Type:   change.Type,
+			Path:   path.Join(dir, change.Name),
+			Source: SourceExternal,
+		}
+		if change.From != "" {
+			event.From = path.Join(dir, change.From)
+		}
+
+		// Directories that went away were watched; new ones are watched from now on
+		switch change.Type {
+		case watch.EventDelete:
+			event.IsDir = t.forget(filepath.Join(batch.dir, change.Name))
+		case watch.EventRename:
+			t.forget(filepath.Join(batch.dir, change.From))
+		}
+		if info := change.Info; info != nil {
+			event.IsDir = info.IsDir()
+			if !info.IsDir() {
+				event.Size = info.Size()
+			}
+			modTime := info.ModTime().UTC()
+			event.ModTime = &modTime
+			if info.IsDir() && (change.Type == watch.EventCreate || change.Type == watch.EventRename) {
+				t.add(event.Path)
+			}
+		}
+
+		if t.matches(event) {
+			continue
+		}
+		if err := t.journal.append(event); err != nil {
+			slog.Error("failed to record change", "error", err, "type", event.Type, "path", event.Path)
+		}
+	}
+}
+
+// expect notes a change made through the API so the watcher does not record it again
+func (t *tree) expect(event Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if len(t.expected) > 256 {
+		for p, exp := range t.expected {
+			if now.After(exp.until) {
+				delete(t.expected, p)
+			}
+		}
+	}
+
+	until := now.Add(expectWindow)
+	switch event.Type {
+	case TypeDelete:
+		t.expected[event.Path] = expectation{isDir: event.IsDir, until: until}
+		return
+	case TypeRename:
+		t.expected[event.From] = expectation{isDir: event.IsDir, until: until}
+	}
+	exp := expectation{exists: true, isDir: event.IsDir, size: event.Size, until: until}
+	if event.ModTime != nil {
+		exp.modTime = *event.ModTime
+	}
+	t.expected[event.Path] = exp
+}
+
+// matches reports whether an external event repeats a change made through the API
+func (t *tree) matches(event Event) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	switch event.Type {
+	case TypeDelete:
+		return t.expectedGone(event.Path, now)
+	case TypeRename:
+		return t.expectedGone(event.From, now) && t.expectedState(event, now)
+	default:
+		return t.expectedState(event, now)
+	}
+}
+
+// expectedState reports whether the API left event.Path in the reported state;
+// the caller holds the lock
+func (t *tree) expectedState(event Event, now time.Time) bool {
+	exp, ok := t.expected[event.Path]
+	if !ok || now.After(exp.until) || !exp.exists || exp.isDir != event.IsDir {
+		return false
+	}
+	if exp.isDir {
+		return true
+	}
+	return event.ModTime != nil && exp.size == event.Size && exp.modTime.Equal(*event.ModTime)
+}
+
+// expectedGone reports whether the API removed p or a directory above it;
+// the caller holds the lock
+func (t *tree) expectedGone(p string, now time.Time) bool {
+	for current := p; ; current = path.Dir(current) {
+		exp, ok := t.expected[current]
+		if ok && now.Before(exp.until) && !exp.exists && (current == p || exp.isDir) {
+			return true
+		}
+		if current == "/" {
+			return false
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// BasePath returns the root directory exposed by the file manager
+func BasePath() string {
+	// Get base path from environment variable, with fallback
+	basePath := os.Getenv("FILE_MANAGER_BASE_PATH")
+	if basePath == "" {
+		// Default to Docker mount point
+		basePath = "/data"
+	}
+	return basePath
+}
+
+// StateDir returns the directory used for service state (audit log, shares,
+// jobs, ...), joined with the optional sub path. It is kept outside the base
+// path so internal files never show up in listings.
+func StateDir(elem ...string) string {
+	stateDir := os.Getenv("FILE_MANAGER_STATE_DIR")
+	if stateDir == "" {
+		stateDir = "./state"
+	}
+	return filepath.Join(append([]string{stateDir}, elem...)...)
+}
+
+// EnvInt reads an integer environment variable, falling back when it is
+// unset, malformed or below min
+func EnvInt(name string, fallback, min int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value < min {
+		return fallback
+	}
+	return value
+}
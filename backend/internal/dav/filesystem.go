@@ -0,0 +1,75 @@
+package dav
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem adapts the file service to webdav.FileSystem so WebDAV shares
+// the service's base path containment and audit logging
+type FileSystem struct {
+	svc files.FileServiceInterface
+}
+
+// NewFileSystem creates a WebDAV file system backed by svc
+func NewFileSystem(svc files.FileServiceInterface) *FileSystem {
+	return &FileSystem{svc: svc}
+}
+
+// Mkdir creates a directory
+func (fs *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return mapError(fs.svc.CreateDirectory(ctx, name))
+}
+
+// OpenFile opens a file or directory
+func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	file, err := fs.svc.OpenHandle(ctx, name, flag, perm)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return file, nil
+}
+
+// RemoveAll removes a file or directory tree
+func (fs *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	return mapError(fs.svc.DeleteFile(ctx, name))
+}
+
+// Rename moves a file or directory; the WebDAV handler removes an existing
+// target first when the client asked to overwrite it
+func (fs *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	return mapError(fs.svc.MoveFile(ctx, oldName, newName))
+}
+
+// Stat returns file information
+func (fs *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := fs.svc.Stat(ctx, name)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return info, nil
+}
+
+// mapError converts wrapped service errors into the os sentinel errors the
+// WebDAV handler checks for
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := err.Error()
+	switch {
+	case errors.Is(err, os.ErrNotExist) || strings.Contains(message, "not found"):
+		return os.ErrNotExist
+	case errors.Is(err, os.ErrExist) || strings.Contains(message, "already exists"):
+		return os.ErrExist
+	case errors.Is(err, os.ErrPermission) || strings.Contains(message, "access denied") || strings.Contains(message, "invalid path"):
+		return os.ErrPermission
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+package dav
+
+import (
+	"net/http"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"golang.org/x/net/webdav"
+)
+
+// Prefix is the URL prefix the WebDAV server is mounted at
+const Prefix = "/dav"
+
+// NewHandler creates a WebDAV (class 1 and 2) handler over the file service
+func NewHandler(svc files.FileServiceInterface) http.Handler {
+	return &webdav.Handler{
+		Prefix:     Prefix,
+		FileSystem: NewFileSystem(svc),
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				logging.FromContext(r.Context()).Warn("webdav request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+			}
+		},
+	}
+}
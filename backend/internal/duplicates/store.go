@@ -0,0 +1,156 @@
+package duplicates
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/statefile"
+)
+
+// ErrNotFound is returned when a path has not been scanned or a group is unknown
+var ErrNotFound = errors.New("duplicate scan not found")
+
+// Group is a set of files with identical content
+type Group struct {
+	// ID is the SHA-256 of the shared content
+	ID    string   `json:"id"`
+	Size  int64    `json:"size"`
+	Files []string `json:"files"`
+	// WastedBytes is the space freed by keeping a single copy; files that are
+	// already hard links of each other do not count
+	WastedBytes int64 `json:"wastedBytes"`
+}
+
+// Scan is the result of a duplicate scan of a directory tree
+type Scan struct {
+	Path         string    `json:"path"`
+	StartedAt    time.Time `json:"startedAt"`
+	FinishedAt   time.Time `json:"finishedAt"`
+	StartedBy    string    `json:"startedBy"`
+	FilesScanned int       `json:"filesScanned"`
+	BytesScanned int64     `json:"bytesScanned"`
+	WastedBytes  int64     `json:"wastedBytes"`
+	// Groups are ordered by wasted bytes, largest first
+	Groups []Group `json:"groups"`
+}
+
+// Store keeps the latest scan of each scanned path until that path is scanned again
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	scans map[string]Scan
+}
+
+// Default is the process-wide scan store; it keeps scans in memory until Setup is called
+var Default = &Store{scans: make(map[string]Scan)}
+
+// Setup loads the default store from the state directory
+func Setup() error {
+	store, err := NewStore(config.StateDir("duplicates.json"))
+	if err != nil {
+		return err
+	}
+	Default = store
+	return nil
+}
+
+// NewStore loads scans persisted at path
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, scans: make(map[string]Scan)}
+	if _, err := statefile.Load(path, &s.scans); err != nil {
+		return nil, fmt.Errorf("failed to load duplicate scans: %w", err)
+	}
+	return s, nil
+}
+
+// Put replaces the scan of scan.Path
+func (s *Store) Put(scan Scan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.scans[scan.Path]
+	s.scans[scan.Path] = scan
+	if err := s.save(); err != nil {
+		if existed {
+			s.scans[scan.Path] = previous
+		} else {
+			delete(s.scans, scan.Path)
+		}
+		return err
+	}
+	return nil
+}
+
+// Get returns the latest scan of path
+func (s *Store) Get(path string) (Scan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scan, exists := s.scans[path]
+	if !exists {
+		return Scan{}, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	return scan, nil
+}
+
+// Group returns one group of the latest scan of path
+func (s *Store) Group(path, id string) (Group, error) {
+	scan, err := s.Get(path)
+	if err != nil {
+		return Group{}, err
+	}
+	for _, group := range scan.Groups {
+		if group.ID == id {
+			return group, nil
+		}
+	}
+	return Group{}, fmt.Errorf("%w: no group %s", ErrNotFound, id)
+}
+
+// UpdateGroup replaces a group of the latest scan of path with its remaining
+// files and wasted bytes; groups that no longer waste space are dropped
+func (s *Store) UpdateGroup(path string, group Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scan, exists := s.scans[path]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+
+	groups := make([]Group, 0, len(scan.Groups))
+	scan.WastedBytes = 0
+	for _, existing := range scan.Groups {
+		if existing.ID == group.ID {
+			existing = group
+		}
+		if len(existing.Files) < 2 || existing.WastedBytes == 0 {
+			continue
+		}
+		groups = append(groups, existing)
+		scan.WastedBytes += existing.WastedBytes
+	}
+	scan.Groups = groups
+
+	previous := s.scans[path]
+	s.scans[path] = scan
+	if err := s.save(); err != nil {
+		s.scans[path] = previous
+		return err
+	}
+	return nil
+}
+
+// save persists the scans; in-memory stores are not saved. The caller holds the lock.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := statefile.Save(s.path, s.scans); err != nil {
+		return fmt.Errorf("failed to save duplicate scans: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package filerequests
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/httputil"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// FileRequestInfo is the API representation of a file request link
+type FileRequestInfo struct {
+	FileRequest
+	URL    string `json:"url"`
+	Active bool   `json:"active"`
+}
+
+// CreateRequest is the body of POST /file-requests
+type CreateRequest struct {
+	Path              string     `json:"path"`
+	Title             string     `json:"title,omitempty"`
+	ExpiresIn         string     `json:"expiresIn,omitempty"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	MaxBytes          int64      `json:"maxBytes,omitempty"`
+	AllowedExtensions []string   `json:"allowedExtensions,omitempty"`
+}
+
+// FileRequestResponse is returned when a file request is created
+type FileRequestResponse struct {
+	Success     bool            `json:"success"`
+	FileRequest FileRequestInfo `json:"fileRequest"`
+}
+
+// FileRequestListResponse is returned by GET /file-requests
+type FileRequestListResponse struct {
+	Success      bool              `json:"success"`
+	FileRequests []FileRequestInfo `json:"fileRequests"`
+	TotalItems   int               `json:"totalItems"`
+	RequestTime  time.Time         `json:"requestTime"`
+}
+
+// Handler manages file request links for authenticated users
+type Handler struct {
+	store *Store
+	svc   files.FileServiceInterface
+	audit audit.Recorder
+}
+
+// NewHandler creates the management handler for GET, POST and DELETE /file-requests
+func NewHandler(store *Store, svc files.FileServiceInterface) http.Handler {
+	return &Handler{
+		store: store,
+		svc:   svc,
+		audit: audit.Default,
+	}
+}
+
+// ServeHTTP dispatches on the HTTP method
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.handleList(w, r)
+	case http.MethodPost:
+		h.handleCreate(w, r)
+	case http.MethodDelete:
+		h.handleRevoke(w, r)
+	default:
+		httputil.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleList handles GET /file-requests - Lists links and the uploads they received
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	identity := auth.FromContext(r.Context())
+
+	owner := identity.Username
+	if identity.IsAdmin() {
+		owner = r.URL.Query().Get("user")
+	}
+
+	requests := h.store.List(owner)
+	infos := make([]FileRequestInfo, 0, len(requests))
+	for _, request := range requests {
+		infos = append(infos, toInfo(request))
+	}
+
+	httputil.SendJSON(w, FileRequestListResponse{
+		Success:      true,
+		FileRequests: infos,
+		TotalItems:   len(infos),
+		RequestTime:  time.Now(),
+	}, http.StatusOK)
+}
+
+// handleCreate handles POST /file-requests - Creates an upload-only link for a directory
+func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateRequest
+	if err := httputil.DecodeJSON(w, r, &req, 64*1024); err != nil {
+		httputil.SendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Path == "" {
+		httputil.SendError(w, "Directory path is required", http.StatusBadRequest)
+		return
+	}
+	if strings.Contains(req.Path, "..") {
+		httputil.SendError(w, "Invalid directory path provided", http.StatusBadRequest)
+		return
+	}
+	cleanPath := path.Clean("/" + req.Path)
+
+	expiresAt, err := httputil.ResolveExpiry(req.ExpiresIn, req.ExpiresAt)
+	if err != nil {
+		httputil.SendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.MaxBytes < 0 {
+		httputil.SendError(w, "maxBytes must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	// Uploads land in an existing directory inside the sandbox
+	details, err := h.svc.GetFileDetails(ctx, cleanPath)
+	if err != nil || !details.IsDir {
+		httputil.SendError(w, "Directory not found", http.StatusNotFound)
+		return
+	}
+
+	created, err := h.store.Create(FileRequest{
+		Path:              cleanPath,
+		Title:             req.Title,
+		CreatedBy:         auth.FromContext(ctx).Username,
+		ExpiresAt:         expiresAt,
+		MaxBytes:          req.MaxBytes,
+		AllowedExtensions: normalizeExtensions(req.AllowedExtensions),
+	})
+	h.audit.Record(ctx, audit.NewEntry(ctx, audit.OpFileRequestCreate, cleanPath, "", 0, err))
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create file request", "path", cleanPath, "error", err)
+		httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	logging.FromContext(ctx).Info("created file request", "path", cleanPath, "token", created.Token)
+	httputil.SendJSON(w, FileRequestResponse{Success: true, FileRequest: toInfo(created)}, http.StatusCreated)
+}
+
+// handleRevoke handles DELETE /file-requests?token= - Revokes a link, keeping received files
+func (h *Handler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		httputil.SendError(w, "File request token is required", http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.store.Get(token)
+	if err != nil {
+		httputil.SendError(w, "File request not found", http.StatusNotFound)
+		return
+	}
+
+	identity := auth.FromContext(ctx)
+	if request.CreatedBy != identity.Username && !identity.IsAdmin() {
+		httputil.SendError(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	err = h.store.Revoke(token)
+	h.audit.Record(ctx, audit.NewEntry(ctx, audit.OpFileRequestRevoke, request.Path, "", 0, err))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			httputil.SendError(w, "File request not found", http.StatusNotFound)
+			return
+		}
+		logging.FromContext(ctx).Error("failed to revoke file request", "token", token, "error", err)
+		httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	httputil.SendJSON(w, map[string]interface{}{
+		"success": true,
+		"message": "File request revoked successfully",
+		"token":   token,
+	}, http.StatusOK)
+}
+
+// normalizeExtensions lower-cases extensions and ensures a leading dot
+func normalizeExtensions(extensions []string) []string {
+	var result []string
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		result = append(result, ext)
+	}
+	return result
+}
+
+// toInfo converts a stored file request into its API representation
+func toInfo(request *FileRequest) FileRequestInfo {
+	return FileRequestInfo{
+		FileRequest: *request,
+		URL:         "/u/" + request.Token,
+		Active:      !request.Expired(time.Now()) && request.RemainingBytes() != 0,
+	}
+}
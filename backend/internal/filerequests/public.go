@@ -0,0 +1,301 @@
+package filerequests
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/httputil"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// defaultMaxUploadMB caps a single upload when the link has no quota and
+// FILE_REQUEST_MAX_UPLOAD_MB is unset
+const defaultMaxUploadMB = 4096
+
+// UploadResult reports what happened to one uploaded file; it never names
+// the stored file so senders learn nothing about the target directory
+type UploadResult struct {
+	FileName string `json:"fileName"`
+	Size     int64  `json:"size"`
+	Error    string `json:"error,omitempty"`
+}
+
+// UploadResponse is returned by POST /u/{token}
+type UploadResponse struct {
+	Success bool           `json:"success"`
+	Files   []UploadResult `json:"files"`
+}
+
+// PublicHandler serves upload-only pages to anonymous senders; it never lists the target
+type PublicHandler struct {
+	store         *Store
+	svc           files.FileServiceInterface
+	maxUploadSize int64
+}
+
+// NewPublicHandler creates the handler mounted at /u/
+//
+//	GET  /u/{token}   HTML upload form
+//	POST /u/{token}   multipart upload with one or more "file" parts and an optional "name" field
+func NewPublicHandler(store *Store, svc files.FileServiceInterface) http.Handler {
+	maxUploadMB := int64(config.EnvInt("FILE_REQUEST_MAX_UPLOAD_MB", defaultMaxUploadMB, 1))
+
+	return &PublicHandler{
+		store:         store,
+		svc:           svc,
+		maxUploadSize: maxUploadMB * 1024 * 1024,
+	}
+}
+
+// ServeHTTP resolves the link token and dispatches on the HTTP method
+func (h *PublicHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.Trim(strings.TrimPrefix(r.URL.Path, "/u/"), "/")
+	if token == "" || strings.Contains(token, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	request, err := h.store.Active(token)
+	if err != nil {
+		h.sendRequestError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.handleForm(w, r, request)
+	case http.MethodPost:
+		h.handleUpload(w, r, request)
+	default:
+		httputil.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleForm handles GET /u/{token} - Renders the upload form
+func (h *PublicHandler) handleForm(w http.ResponseWriter, r *http.Request, request *FileRequest) {
+	data := formData{
+		Title:             request.Title,
+		AllowedExtensions: strings.Join(request.AllowedExtensions, ", "),
+		ExpiresAt:         request.ExpiresAt,
+	}
+	if remaining := request.RemainingBytes(); remaining >= 0 {
+		data.RemainingMB = strconv.FormatFloat(float64(remaining)/(1024*1024), 'f', 1, 64)
+	}
+	if data.Title == "" {
+		data.Title = "Send files"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := formTemplate.Execute(w, data); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render upload form", "error", err)
+	}
+}
+
+// handleUpload handles POST /u/{token} - Streams multipart files into the target directory
+func (h *PublicHandler) handleUpload(w http.ResponseWriter, r *http.Request, request *FileRequest) {
+	clientIP := auth.RequestClientIP(r)
+	ctx := auth.WithClientIP(r.Context(), clientIP)
+	ctx = auth.WithIdentity(ctx, auth.Identity{Username: "file-request:" + request.Token})
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		httputil.SendError(w, "Expected multipart/form-data upload", http.StatusBadRequest)
+		return
+	}
+
+	var uploader string
+	var results []UploadResult
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			httputil.SendError(w, "Malformed upload", http.StatusBadRequest)
+			return
+		}
+
+		// The optional sender name precedes the files in the form
+		if part.FormName() == "name" {
+			value, _ := io.ReadAll(io.LimitReader(part, 256))
+			uploader = strings.TrimSpace(string(value))
+			part.Close()
+			continue
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		result := UploadResult{FileName: path.Base(strings.ReplaceAll(part.FileName(), `\`, "/"))}
+		if !request.AllowsFile(result.FileName) {
+			result.Error = "file type not allowed"
+			results = append(results, result)
+			part.Close()
+			continue
+		}
+
+		// Each file may use whatever quota is left at the time it starts
+		current, err := h.store.Active(request.Token)
+		if err != nil {
+			h.sendRequestError(w, err)
+			return
+		}
+		limit := h.maxUploadSize
+		if remaining := current.RemainingBytes(); remaining >= 0 && remaining < limit {
+			limit = remaining
+		}
+		if limit == 0 {
+			result.Error = "upload quota exhausted"
+			results = append(results, result)
+			part.Close()
+			continue
+		}
+
+		details, err := h.svc.UploadFile(ctx, request.Path, result.FileName, part, files.UploadOptions{
+			AutoRename: true,
+			MaxBytes:   limit,
+		})
+		part.Close()
+		if err != nil {
+			logging.FromContext(ctx).Warn("file request upload failed", "token", request.Token, "file", result.FileName, "error", err)
+			result.Error = uploadErrorMessage(err)
+			results = append(results, result)
+			continue
+		}
+
+		result.Size = details.Size
+
+		err = h.store.RecordUpload(request.Token, Upload{
+			FileName:   result.FileName,
+			StoredPath: details.Path,
+			Size:       details.Size,
+			Uploader:   uploader,
+			ClientIP:   clientIP,
+			UploadedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			// A concurrent upload used up the quota; do not keep the file
+			if deleteErr := h.svc.DeleteFile(ctx, details.Path); deleteErr != nil {
+				logging.FromContext(ctx).Error("failed to remove rejected upload", "path", details.Path, "error", deleteErr)
+			}
+			result.Size = 0
+			result.Error = uploadErrorMessage(err)
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		httputil.SendError(w, "No files uploaded", http.StatusBadRequest)
+		return
+	}
+
+	response := UploadResponse{Success: true, Files: results}
+	for _, result := range results {
+		if result.Error != "" {
+			response.Success = false
+		}
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := resultTemplate.Execute(w, response); err != nil {
+			logging.FromContext(ctx).Error("failed to render upload result", "error", err)
+		}
+		return
+	}
+	httputil.SendJSON(w, response, http.StatusOK)
+}
+
+// sendRequestError maps store errors to responses
+func (h *PublicHandler) sendRequestError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		httputil.SendError(w, "File request not found", http.StatusNotFound)
+	case errors.Is(err, ErrExpired):
+		httputil.SendError(w, "File request is no longer available", http.StatusGone)
+	default:
+		httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// uploadErrorMessage turns an upload error into a message safe to show to senders
+func uploadErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrQuotaExceeded):
+		return "upload quota exceeded"
+	case strings.Contains(err.Error(), "too large"):
+		return "file too large"
+	case strings.Contains(err.Error(), "invalid"):
+		return "invalid file name"
+	default:
+		return "upload failed"
+	}
+}
+
+// formData feeds the upload form template
+type formData struct {
+	Title             string
+	AllowedExtensions string
+	RemainingMB       string
+	ExpiresAt         *time.Time
+}
+
+var formTemplate = template.Must(template.New("form").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Title}}</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: Arial, sans-serif; max-width: 600px; margin: 50px auto; padding: 20px; }
+        label { display: block; margin: 12px 0 4px; }
+        .hint { color: #666; font-size: 0.9em; }
+        button { margin-top: 16px; padding: 8px 16px; }
+    </style>
+</head>
+<body>
+    <h1>{{.Title}}</h1>
+    <form method="post" enctype="multipart/form-data">
+        <label for="name">Your name (optional)</label>
+        <input id="name" name="name" type="text" maxlength="100">
+        <label for="file">Files</label>
+        <input id="file" name="file" type="file" multiple required>
+        {{if .AllowedExtensions}}<p class="hint">Allowed types: {{.AllowedExtensions}}</p>{{end}}
+        {{if .RemainingMB}}<p class="hint">Space left: {{.RemainingMB}} MB</p>{{end}}
+        {{if .ExpiresAt}}<p class="hint">This link expires {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}</p>{{end}}
+        <button type="submit">Upload</button>
+    </form>
+</body>
+</html>
+`))
+
+var resultTemplate = template.Must(template.New("result").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>Upload complete</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: Arial, sans-serif; max-width: 600px; margin: 50px auto; padding: 20px; }
+        .error { color: #c00; }
+    </style>
+</head>
+<body>
+    <h1>{{if .Success}}Thank you!{{else}}Some files could not be uploaded{{end}}</h1>
+    <ul>
+    {{range .Files}}
+        <li>{{.FileName}}{{if .Error}} <span class="error">({{.Error}})</span>{{else}} ({{.Size}} bytes){{end}}</li>
+    {{end}}
+    </ul>
+    <p><a href="">Send more files</a></p>
+</body>
+</html>
+`))
@@ -0,0 +1,140 @@
+package filerequests
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/filestest"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+)
+
+// newTestRequest stores request in a fresh store over a temporary tree with an
+// /inbox directory and returns the public handler, the store, the token and the tree
+func newTestRequest(t *testing.T, request FileRequest) (http.Handler, *Store, string, string) {
+	t.Helper()
+	root := filestest.Tree(t, map[string]string{"/inbox/photo.jpg": "existing"})
+	store, err := NewStore(filepath.Join(t.TempDir(), "file-requests.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Path = "/inbox"
+	created, err := store.Create(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := files.NewFileServiceWithBackend(fs.NewLocalFS(root))
+	return NewPublicHandler(store, svc), store, created.Token, root
+}
+
+// upload posts the files, keyed by name, with an optional sender name
+func upload(t *testing.T, h http.Handler, token, sender string, names []string, contents map[string]string) (*httptest.ResponseRecorder, UploadResponse) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if sender != "" {
+		form.WriteField("name", sender)
+	}
+	for _, name := range names {
+		part, err := form.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(contents[name]))
+	}
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/u/"+token, &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var response UploadResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+		}
+	}
+	return w, response
+}
+
+func TestUploadKeepsStoredNamesPrivate(t *testing.T) {
+	h, store, token, root := newTestRequest(t, FileRequest{})
+
+	w, response := upload(t, h, token, "Bob", []string{"photo.jpg"}, map[string]string{"photo.jpg": "new photo"})
+	if w.Code != http.StatusOK || !response.Success || len(response.Files) != 1 {
+		t.Fatalf("upload = %d, %+v, want one stored file", w.Code, response)
+	}
+	if result := response.Files[0]; result.FileName != "photo.jpg" || result.Size != 9 {
+		t.Fatalf("result = %+v, want photo.jpg with 9 bytes", result)
+	}
+
+	// The clash was renamed, which only the owner gets to see
+	if strings.Contains(w.Body.String(), "photo (1)") {
+		t.Fatalf("response %s reveals the stored name", w.Body.String())
+	}
+	content, err := os.ReadFile(filepath.Join(root, "inbox", "photo (1).jpg"))
+	if err != nil || string(content) != "new photo" {
+		t.Fatalf("stored file = %q, %v, want the upload next to the existing file", content, err)
+	}
+	request, err := store.Get(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(request.Uploads) != 1 || request.Uploads[0].StoredPath != "/inbox/photo (1).jpg" || request.Uploads[0].Uploader != "Bob" {
+		t.Fatalf("uploads = %+v, want the renamed file from Bob", request.Uploads)
+	}
+}
+
+func TestUploadRejectsDisallowedFiles(t *testing.T) {
+	h, store, token, root := newTestRequest(t, FileRequest{AllowedExtensions: []string{".jpg"}})
+
+	_, response := upload(t, h, token, "", []string{"a.JPG", "setup.exe"}, map[string]string{"a.JPG": "a", "setup.exe": "x"})
+	if response.Success || len(response.Files) != 2 {
+		t.Fatalf("upload = %+v, want one accepted and one rejected file", response)
+	}
+	if response.Files[0].Error != "" || response.Files[1].Error != "file type not allowed" {
+		t.Fatalf("results = %+v, want setup.exe rejected", response.Files)
+	}
+	if _, err := os.Stat(filepath.Join(root, "inbox", "setup.exe")); !os.IsNotExist(err) {
+		t.Fatalf("rejected file was stored: %v", err)
+	}
+	if request, _ := store.Get(token); len(request.Uploads) != 1 {
+		t.Fatalf("uploads = %+v, want only a.JPG", request.Uploads)
+	}
+}
+
+func TestUploadQuota(t *testing.T) {
+	h, store, token, root := newTestRequest(t, FileRequest{MaxBytes: 10})
+
+	_, response := upload(t, h, token, "", []string{"a.txt", "b.txt"}, map[string]string{"a.txt": "123456", "b.txt": "123456"})
+	if response.Success || response.Files[0].Error != "" || response.Files[1].Error != "file too large" || response.Files[1].Size != 0 {
+		t.Fatalf("results = %+v, want b.txt over the quota", response.Files)
+	}
+	if _, err := os.Stat(filepath.Join(root, "inbox", "b.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file over the quota was stored: %v", err)
+	}
+	if request, _ := store.Get(token); request.UsedBytes != 6 {
+		t.Fatalf("used bytes = %d, want 6", request.UsedBytes)
+	}
+
+	w, _ := upload(t, h, token, "", nil, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("empty upload status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUploadToUnknownRequest(t *testing.T) {
+	h, _, _, _ := newTestRequest(t, FileRequest{})
+	w, _ := upload(t, h, "missing", "", []string{"a.txt"}, map[string]string{"a.txt": "a"})
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
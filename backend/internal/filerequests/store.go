@@ -0,0 +1,227 @@
+package filerequests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/statefile"
+)
+
+// Errors returned by the file request store
+var (
+	ErrNotFound      = errors.New("file request not found")
+	ErrExpired       = errors.New("file request expired")
+	ErrQuotaExceeded = errors.New("file request quota exceeded")
+)
+
+// Upload records one file received through a file request link
+type Upload struct {
+	FileName   string    `json:"fileName"`
+	StoredPath string    `json:"storedPath"`
+	Size       int64     `json:"size"`
+	Uploader   string    `json:"uploader,omitempty"`
+	ClientIP   string    `json:"clientIp,omitempty"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+// FileRequest is an upload-only link into one target directory
+type FileRequest struct {
+	Token             string     `json:"token"`
+	Path              string     `json:"path"`
+	Title             string     `json:"title,omitempty"`
+	CreatedBy         string     `json:"createdBy"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	MaxBytes          int64      `json:"maxBytes,omitempty"`
+	UsedBytes         int64      `json:"usedBytes"`
+	AllowedExtensions []string   `json:"allowedExtensions,omitempty"`
+	Uploads           []Upload   `json:"uploads"`
+}
+
+// Expired reports whether the link is past its expiry time
+func (f *FileRequest) Expired(now time.Time) bool {
+	return f.ExpiresAt != nil && now.After(*f.ExpiresAt)
+}
+
+// RemainingBytes returns the quota left, or -1 when the link has no quota
+func (f *FileRequest) RemainingBytes() int64 {
+	if f.MaxBytes <= 0 {
+		return -1
+	}
+	if f.UsedBytes >= f.MaxBytes {
+		return 0
+	}
+	return f.MaxBytes - f.UsedBytes
+}
+
+// AllowsFile reports whether the file name has one of the allowed extensions
+func (f *FileRequest) AllowsFile(name string) bool {
+	if len(f.AllowedExtensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, allowed := range f.AllowedExtensions {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Store keeps file requests in memory and persists them to a JSON file
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	requests map[string]*FileRequest
+}
+
+// NewStore loads the file request store from path
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:     path,
+		requests: make(map[string]*FileRequest),
+	}
+
+	var requests []*FileRequest
+	if _, err := statefile.Load(path, &requests); err != nil {
+		return nil, fmt.Errorf("failed to load file request store: %w", err)
+	}
+	for _, request := range requests {
+		s.requests[request.Token] = request
+	}
+
+	return s, nil
+}
+
+// Create stores a new file request under a fresh random token
+func (s *Store) Create(request FileRequest) (*FileRequest, error) {
+	token, err := auth.RandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	request.Token = token
+	request.CreatedAt = time.Now().UTC()
+	request.UsedBytes = 0
+	request.Uploads = []Upload{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[token] = &request
+	if err := s.save(); err != nil {
+		delete(s.requests, token)
+		return nil, err
+	}
+
+	return request.clone(), nil
+}
+
+// Get returns a copy of the file request with the given token
+func (s *Store) Get(token string) (*FileRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, exists := s.requests[token]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return request.clone(), nil
+}
+
+// Active returns the file request if it exists and has not expired
+func (s *Store) Active(token string) (*FileRequest, error) {
+	request, err := s.Get(token)
+	if err != nil {
+		return nil, err
+	}
+	if request.Expired(time.Now()) {
+		return nil, ErrExpired
+	}
+	return request, nil
+}
+
+// List returns file requests created by owner, or all of them when owner is empty
+func (s *Store) List(owner string) []*FileRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []*FileRequest{}
+	for _, request := range s.requests {
+		if owner == "" || request.CreatedBy == owner {
+			result = append(result, request.clone())
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Revoke deletes a file request; files already uploaded are kept
+func (s *Store) Revoke(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, exists := s.requests[token]
+	if !exists {
+		return ErrNotFound
+	}
+
+	delete(s.requests, token)
+	if err := s.save(); err != nil {
+		s.requests[token] = request
+		return err
+	}
+	return nil
+}
+
+// RecordUpload adds an upload to the link, failing if it would exceed the quota
+func (s *Store) RecordUpload(token string, upload Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, exists := s.requests[token]
+	if !exists {
+		return ErrNotFound
+	}
+	if request.MaxBytes > 0 && request.UsedBytes+upload.Size > request.MaxBytes {
+		return ErrQuotaExceeded
+	}
+
+	request.UsedBytes += upload.Size
+	request.Uploads = append(request.Uploads, upload)
+	if err := s.save(); err != nil {
+		request.UsedBytes -= upload.Size
+		request.Uploads = request.Uploads[:len(request.Uploads)-1]
+		return err
+	}
+	return nil
+}
+
+// save writes the store atomically; the caller must hold the lock
+func (s *Store) save() error {
+	requests := make([]*FileRequest, 0, len(s.requests))
+	for _, request := range s.requests {
+		requests = append(requests, request)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+	})
+
+	return statefile.Save(s.path, requests)
+}
+
+// clone returns a deep copy safe to hand out without the lock
+func (f *FileRequest) clone() *FileRequest {
+	copied := *f
+	copied.AllowedExtensions = append([]string(nil), f.AllowedExtensions...)
+	copied.Uploads = append([]Upload{}, f.Uploads...)
+	return &copied
+}
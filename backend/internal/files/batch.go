@@ -0,0 +1,338 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// Batch operations
+const (
+	BatchDelete = "delete"
+	BatchMove   = "move"
+	BatchCopy   = "copy"
+	BatchMkdir  = "mkdir"
+	BatchChmod  = "chmod"
+)
+
+// Statuses of batch items
+const (
+	BatchItemDone    = "done"
+	BatchItemFailed  = "failed"
+	BatchItemSkipped = "skipped"
+	// BatchItemPlanned is reported by dry runs for items that would be attempted
+	BatchItemPlanned = "planned"
+)
+
+// maxBatchOperations bounds the number of operations in one batch
+const maxBatchOperations = 10000
+
+// BatchOperation is one step of a batch
+type BatchOperation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	// Target is the destination of move and copy
+	Target string `json:"target,omitempty"`
+	// Mode is the octal mode of chmod, such as 0644
+	Mode string `json:"mode,omitempty"`
+}
+
+// BatchOptions controls how a batch runs
+type BatchOptions struct {
+	// ContinueOnError keeps going after a failed operation; by default the
+	// remaining operations are skipped
+	ContinueOnError bool
+	// DryRun checks every operation without changing anything
+	DryRun bool
+}
+
+// ValidateBatch checks that a batch is well formed before it is started
+func ValidateBatch(ops []BatchOperation) error {
+	if len(ops) == 0 {
+		return fmt.Errorf("no operations given")
+	}
+	if len(ops) > maxBatchOperations {
+		return fmt.Errorf("too many operations: %d (max: %d)", len(ops), maxBatchOperations)
+	}
+
+	for i, op := range ops {
+		if op.Path == "" {
+			return fmt.Errorf("operation %d: path is required", i)
+		}
+		switch op.Op {
+		case BatchDelete, BatchMkdir:
+		case BatchMove, BatchCopy:
+			if op.Target == "" {
+				return fmt.Errorf("operation %d: target is required for %s", i, op.Op)
+			}
+		case BatchChmod:
+			if _, err := ParseMode(op.Mode); err != nil {
+				return fmt.Errorf("operation %d: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("operation %d: unsupported operation %q", i, op.Op)
+		}
+	}
+	return nil
+}
+
+// RunBatch runs a list of operations in order as one background job
+func (s *FileService) RunBatch(ctx context.Context, ops []BatchOperation, opts BatchOptions) (*jobs.Job, error) {
+	if err := ValidateBatch(ops); err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	// Refuse the whole batch rather than fail its chmods one by one
+	for _, op := range ops {
+		if op.Op == BatchChmod {
+			if err := checkAttributeAccess(ctx, "change mode"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	job, err := jobs.Default.Start(ctx, "batch", fmt.Sprintf("%d operations", len(ops)), func(ctx context.Context, report jobs.ReportFunc) (interface{}, error) {
+		return s.runBatch(ctx, ops, opts, report), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// runBatch performs or checks each operation and collects the results
+func (s *FileService) runBatch(ctx context.Context, ops []BatchOperation, opts BatchOptions, report jobs.ReportFunc) *BatchResponse {
+	result := &BatchResponse{
+		Success: true,
+		DryRun:  opts.DryRun,
+		Results: make([]BatchItemResult, 0, len(ops)),
+	}
+	plan := newBatchPlan(s.backend)
+
+	for i, op := range ops {
+		item := BatchItemResult{Index: i, Op: op.Op, Path: op.Path, Target: op.Target}
+
+		var err error
+		switch {
+		case result.Stopped:
+			item.Status = BatchItemSkipped
+		case ctx.Err() != nil:
+			item.Status = BatchItemSkipped
+			item.Error = ctx.Err().Error()
+		case opts.DryRun:
+			err = s.checkBatchOperation(plan, op)
+			item.Status = BatchItemPlanned
+		default:
+			err = s.runBatchOperation(ctx, op)
+			item.Status = BatchItemDone
+		}
+
+		if err != nil {
+			item.Status = BatchItemFailed
+			item.Error = err.Error()
+			result.Stopped = !opts.ContinueOnError
+		}
+		switch item.Status {
+		case BatchItemDone, BatchItemPlanned:
+			result.Succeeded++
+		case BatchItemFailed:
+			result.Failed++
+		case BatchItemSkipped:
+			result.Skipped++
+		}
+		result.Results = append(result.Results, item)
+		report(int64(i+1), int64(len(ops)))
+	}
+
+	logging.FromContext(ctx).Info("ran batch", "operations", len(ops), "dry_run", opts.DryRun,
+		"succeeded", result.Succeeded, "failed", result.Failed, "skipped", result.Skipped)
+	result.RequestTime = time.Now()
+	return result
+}
+
+// runBatchOperation performs one operation through the regular service
+// methods, so each is validated, audited and journaled on its own
+func (s *FileService) runBatchOperation(ctx context.Context, op BatchOperation) error {
+	switch op.Op {
+	case BatchDelete:
+		return s.DeleteFile(ctx, op.Path)
+	case BatchMove:
+		return s.MoveFile(ctx, op.Path, op.Target)
+	case BatchCopy:
+		return s.CopyFile(ctx, op.Path, op.Target)
+	case BatchMkdir:
+		return s.CreateDirectory(ctx, op.Path)
+	case BatchChmod:
+		mode, err := ParseMode(op.Mode)
+		if err != nil {
+			return err
+		}
+		_, _, err = s.SetPermissions(ctx, op.Path, mode, false)
+		return err
+	}
+	return fmt.Errorf("unsupported operation %q", op.Op)
+}
+
+// checkBatchOperation validates the paths of an operation for a dry run and
+// checks it against the plan
+func (s *FileService) checkBatchOperation(plan *batchPlan, op BatchOperation) error {
+	source, err := s.validatePath(op.Path)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+	target := ""
+	if op.Op == BatchMove || op.Op == BatchCopy {
+		if target, err = s.validatePath(op.Target); err != nil {
+			return fmt.Errorf("path validation failed: %w", err)
+		}
+	}
+	return plan.check(op, source, target)
+}
+
+// batchPlan checks the operations of a dry run against the current tree and
+// the effects of the operations planned before them. It tracks paths, not
+// content: below a planned copy or move it looks at the source on disk.
+type batchPlan struct {
+	backend fs.FileSystemInterface
+	// exists overrides whether a path exists, and isDir whether it is a directory
+	exists map[string]bool
+	isDir  map[string]bool
+	// origin maps planned copy and move targets to their sources
+	origin map[string]string
+}
+
+func newBatchPlan(backend fs.FileSystemInterface) *batchPlan {
+	return &batchPlan{
+		backend: backend,
+		exists:  make(map[string]bool),
+		isDir:   make(map[string]bool),
+		origin:  make(map[string]string),
+	}
+}
+
+// check reports the error an operation on the clean paths source and target
+// would fail with, and records its effect
+func (p *batchPlan) check(op BatchOperation, source, target string) error {
+	switch op.Op {
+	case BatchDelete:
+		if !p.pathExists(source) {
+			return fmt.Errorf("file or directory not found: %s", op.Path)
+		}
+		p.set(source, false, false)
+
+	case BatchMove, BatchCopy:
+		if target == "/" || (op.Op == BatchMove && source == "/") {
+			return fmt.Errorf("invalid path: cannot %s the root directory", op.Op)
+		}
+		if !p.pathExists(source) {
+			return fmt.Errorf("file or directory not found: %s", op.Path)
+		}
+		if p.pathExists(target) {
+			return fmt.Errorf("target already exists: %s", op.Target)
+		}
+		if !p.dirExists(path.Dir(target)) {
+			return fmt.Errorf("target directory not found: %s", path.Dir(target))
+		}
+		if source == "/" || strings.HasPrefix(target, source+"/") {
+			return fmt.Errorf("invalid path: cannot %s a directory into itself", op.Op)
+		}
+		sourceIsDir := p.dirExists(source)
+		origin := p.resolve(source)
+		if op.Op == BatchMove {
+			p.set(source, false, false)
+		}
+		p.set(target, true, sourceIsDir)
+		p.origin[target] = origin
+
+	case BatchMkdir:
+		if source == "/" || p.pathExists(source) {
+			return fmt.Errorf("directory already exists: %s", op.Path)
+		}
+		if !p.dirExists(path.Dir(source)) {
+			return fmt.Errorf("failed to create directory: parent not found: %s", path.Dir(source))
+		}
+		p.set(source, true, true)
+
+	case BatchChmod:
+		if !p.pathExists(source) {
+			return fmt.Errorf("file or directory not found: %s", op.Path)
+		}
+		if _, ok := p.backend.(fs.Chmoder); !ok {
+			return fmt.Errorf("failed to change mode: %w", fs.ErrChmodUnsupported)
+		}
+
+	default:
+		return fmt.Errorf("unsupported operation %q", op.Op)
+	}
+	return nil
+}
+
+// set records that a planned operation created or removed a path
+func (p *batchPlan) set(name string, exists, isDir bool) {
+	// Earlier plans below a replaced path no longer apply
+	for planned := range p.exists {
+		if strings.HasPrefix(planned, name+"/") {
+			delete(p.exists, planned)
+			delete(p.isDir, planned)
+			delete(p.origin, planned)
+		}
+	}
+	p.exists[name] = exists
+	p.isDir[name] = isDir
+	delete(p.origin, name)
+}
+
+// resolve maps a path below planned copies and moves to where its content is on disk
+func (p *batchPlan) resolve(name string) string {
+	for dir := name; ; dir = path.Dir(dir) {
+		if origin, planned := p.origin[dir]; planned {
+			return origin + strings.TrimPrefix(name, dir)
+		}
+		if dir == "/" {
+			return name
+		}
+	}
+}
+
+// lookup returns the planned state of name or of a directory above it that
+// was removed; known is false when the tree on disk decides
+func (p *batchPlan) lookup(name string) (exists, isDir, known bool) {
+	if exists, planned := p.exists[name]; planned {
+		return exists, p.isDir[name], true
+	}
+	for dir := path.Dir(name); ; dir = path.Dir(dir) {
+		if exists, planned := p.exists[dir]; planned {
+			origin, copied := p.origin[dir]
+			if !exists || !copied {
+				// Removed, or created empty by mkdir
+				return false, false, true
+			}
+			info, err := p.backend.Stat(origin + strings.TrimPrefix(name, dir))
+			return err == nil, err == nil && info.IsDir(), true
+		}
+		if dir == "/" {
+			return false, false, false
+		}
+	}
+}
+
+// pathExists reports whether name would exist at this point of the batch
+func (p *batchPlan) pathExists(name string) bool {
+	if exists, _, known := p.lookup(name); known {
+		return exists
+	}
+	return fs.Exists(p.backend, name)
+}
+
+// dirExists reports whether name would be a directory at this point of the batch
+func (p *batchPlan) dirExists(name string) bool {
+	if exists, isDir, known := p.lookup(name); known {
+		return exists && isDir
+	}
+	return fs.IsDirectory(p.backend, name)
+}
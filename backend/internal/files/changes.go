@@ -0,0 +1,56 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+)
+
+// ListChanges returns the changes after cursor, optionally only those at or
+// below path; an empty cursor returns the current cursor to start from
+func (s *FileService) ListChanges(ctx context.Context, cursor, path string, limit int) (*ChangesResponse, error) {
+	prefix := ""
+	if path != "" {
+		cleanPath, err := s.validatePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("path validation failed: %w", err)
+		}
+		prefix = cleanPath
+	}
+
+	page, err := s.changes.Since(cursor, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changes: %w", err)
+	}
+
+	return &ChangesResponse{
+		Success:     true,
+		Events:      page.Events,
+		Cursor:      page.Cursor,
+		HasMore:     page.HasMore,
+		Resync:      page.Resync,
+		RequestTime: time.Now(),
+	}, nil
+}
+
+// recordChange adds a change made through the service to the change journal,
+// describing the entry as it is now
+func (s *FileService) recordChange(ctx context.Context, changeType, cleanPath, from string) {
+	event := changes.Event{Type: changeType, Path: cleanPath, From: from}
+	if info, err := s.backend.Stat(cleanPath); err == nil {
+		event.IsDir = info.IsDir()
+		if !info.IsDir() {
+			event.Size = info.Size()
+		}
+		modTime := info.ModTime().UTC()
+		event.ModTime = &modTime
+	}
+	s.changes.Record(ctx, event)
+}
+
+// recordDelete adds the deletion of a file or directory to the change journal
+func (s *FileService) recordDelete(ctx context.Context, cleanPath string, isDir bool) {
+	s.changes.Record(ctx, changes.Event{Type: changes.TypeDelete, Path: cleanPath, IsDir: isDir})
+}
@@ -0,0 +1,298 @@
+package files
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Defaults for checksum computation
+const (
+	defaultChecksumAlgorithm = "sha256"
+	// defaultChecksumJobThresholdMB is used when CHECKSUM_JOB_THRESHOLD_MB is unset
+	defaultChecksumJobThresholdMB = 64
+	// maxChecksumCacheEntries bounds the checksum cache
+	maxChecksumCacheEntries = 10000
+	// progressInterval is how many bytes are hashed between progress reports
+	progressInterval = 4 << 20
+)
+
+// checksumAlgorithms maps algorithm names to hash constructors
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+	"blake2b": func() hash.Hash {
+		h, _ := blake2b.New512(nil)
+		return h
+	},
+}
+
+// ParseChecksumAlgorithms turns repeated or comma separated algo parameters
+// into a sorted list of known algorithms, defaulting to sha256
+func ParseChecksumAlgorithms(values []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var algos []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" || seen[name] {
+				continue
+			}
+			if _, ok := checksumAlgorithms[name]; !ok {
+				return nil, fmt.Errorf("unsupported checksum algorithm %q", name)
+			}
+			seen[name] = true
+			algos = append(algos, name)
+		}
+	}
+	if len(algos) == 0 {
+		algos = []string{defaultChecksumAlgorithm}
+	}
+	sort.Strings(algos)
+	return algos, nil
+}
+
+// checksumJobThreshold reads CHECKSUM_JOB_THRESHOLD_MB, the size above which
+// checksums are computed as a background job
+func checksumJobThreshold() int64 {
+	thresholdMB := config.EnvInt("CHECKSUM_JOB_THRESHOLD_MB", defaultChecksumJobThresholdMB, 0)
+	return int64(thresholdMB) * 1024 * 1024
+}
+
+// ChecksumFile hashes a file with all requested algorithms in one pass.
+// Sums cached for the file's current size and modification time are reused.
+// Files over the job threshold are hashed in a background job, which is
+// returned instead of the result.
+func (s *FileService) ChecksumFile(ctx context.Context, filePath string, algos []string) (*FileChecksumResponse, *jobs.Job, error) {
+	// Validate the path
+	targetPath, err := s.validatePath(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	info, err := s.backend.Stat(targetPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if info.IsDir() {
+		return nil, nil, fmt.Errorf("invalid path: cannot checksum a directory: %s", filePath)
+	}
+
+	// Small files, and files whose sums are all cached, are answered directly
+	if info.Size() <= s.checksumJobThreshold || len(s.missingChecksums(targetPath, info, algos)) == 0 {
+		sums, cached, err := s.sumFile(ctx, targetPath, info, algos, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return newChecksumResponse(filePath, info, sums, cached), nil, nil
+	}
+
+	job, err := jobs.Default.Start(ctx, "checksum", filePath, func(ctx context.Context, report jobs.ReportFunc) (interface{}, error) {
+		sums, cached, err := s.sumFile(ctx, targetPath, info, algos, report)
+		if err != nil {
+			return nil, err
+		}
+		return newChecksumResponse(filePath, info, sums, cached), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, &job, nil
+}
+
+// missingChecksums returns the algorithms without a cached sum for the file described by info
+func (s *FileService) missingChecksums(cleanPath string, info os.FileInfo, algos []string) []string {
+	cached := s.checksums.get(cleanPath, info)
+	var missing []string
+	for _, algo := range algos {
+		if _, ok := cached[algo]; !ok {
+			missing = append(missing, algo)
+		}
+	}
+	return missing
+}
+
+// sumFile returns the requested sums of a file, reading it in one pass for
+// the algorithms that are not cached. The new sums are cached when the file
+// did not change while it was read; cached reports whether nothing was read.
+func (s *FileService) sumFile(ctx context.Context, cleanPath string, info os.FileInfo, algos []string, report jobs.ReportFunc) (map[string]string, bool, error) {
+	sums := s.checksums.get(cleanPath, info)
+	missing := s.missingChecksums(cleanPath, info, algos)
+	if len(missing) == 0 {
+		return pickChecksums(sums, algos), true, nil
+	}
+
+	file, err := s.backend.OpenFile(cleanPath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	hashes := make(map[string]hash.Hash, len(missing))
+	writers := make([]io.Writer, 0, len(missing))
+	for _, algo := range missing {
+		hashes[algo] = checksumAlgorithms[algo]()
+		writers = append(writers, hashes[algo])
+	}
+
+	reader := &progressReader{ctx: ctx, reader: file, total: info.Size(), report: report}
+	if _, err := io.Copy(io.MultiWriter(writers...), reader); err != nil {
+		return nil, false, fmt.Errorf("failed to read file content: %w", err)
+	}
+
+	if sums == nil {
+		sums = make(map[string]string, len(hashes))
+	}
+	for algo, h := range hashes {
+		sums[algo] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	// Only cache sums of content that stayed the same while it was read
+	if after, err := s.backend.Stat(cleanPath); err == nil && after.Size() == info.Size() && after.ModTime().Equal(info.ModTime()) {
+		s.checksums.put(cleanPath, info, sums)
+	}
+
+	return pickChecksums(sums, algos), false, nil
+}
+
+// pickChecksums returns the sums of the requested algorithms
+func pickChecksums(sums map[string]string, algos []string) map[string]string {
+	picked := make(map[string]string, len(algos))
+	for _, algo := range algos {
+		picked[algo] = sums[algo]
+	}
+	return picked
+}
+
+// newChecksumResponse builds the response for the sums of a file
+func newChecksumResponse(filePath string, info os.FileInfo, checksums map[string]string, cached bool) *FileChecksumResponse {
+	return &FileChecksumResponse{
+		Success:     true,
+		Path:        filePath,
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		Checksums:   checksums,
+		Cached:      cached,
+		RequestTime: time.Now(),
+	}
+}
+
+// progressReader reports read progress and stops when its context is canceled
+type progressReader struct {
+	ctx      context.Context
+	reader   io.Reader
+	total    int64
+	done     int64
+	reported int64
+	report   jobs.ReportFunc
+}
+
+// Read reads from the underlying reader, reporting every progressInterval bytes
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := r.reader.Read(p)
+	r.done += int64(n)
+	if r.report != nil && (r.done-r.reported >= progressInterval || err == io.EOF) {
+		r.report(r.done, r.total)
+		r.reported = r.done
+	}
+	return n, err
+}
+
+// checksumCacheEntries reports the size of the checksum cache
+var checksumCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace:   metrics.Namespace,
+	Name:        "cache_entries",
+	Help:        "Number of entries held by an in-memory cache.",
+	ConstLabels: prometheus.Labels{"cache": "checksums"},
+})
+
+func init() {
+	metrics.Registry.MustRegister(checksumCacheEntries)
+}
+
+// checksumCache remembers sums per path, valid while size and modification time match
+type checksumCache struct {
+	mu      sync.Mutex
+	entries map[string]checksumEntry
+}
+
+// checksumEntry holds the sums of one version of a file
+type checksumEntry struct {
+	size    int64
+	modTime time.Time
+	sums    map[string]string
+}
+
+// newChecksumCache creates an empty cache
+func newChecksumCache() *checksumCache {
+	return &checksumCache{entries: make(map[string]checksumEntry)}
+}
+
+// get returns a copy of the cached sums for the file described by info
+func (c *checksumCache) get(cleanPath string, info os.FileInfo) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[cleanPath]
+	if !ok || entry.size != info.Size() || !entry.modTime.Equal(info.ModTime()) {
+		return nil
+	}
+	sums := make(map[string]string, len(entry.sums))
+	for algo, sum := range entry.sums {
+		sums[algo] = sum
+	}
+	return sums
+}
+
+// put caches sums for the file described by info, evicting an arbitrary entry when full
+func (c *checksumCache) put(cleanPath string, info os.FileInfo, sums map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[cleanPath]; !exists && len(c.entries) >= maxChecksumCacheEntries {
+		for key := range c.entries {
+			delete(c.entries, key)
+			break
+		}
+	}
+	c.entries[cleanPath] = checksumEntry{size: info.Size(), modTime: info.ModTime(), sums: sums}
+	checksumCacheEntries.Set(float64(len(c.entries)))
+}
+
+// invalidate drops the entries of a path and everything below it
+func (c *checksumCache) invalidate(cleanPath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if key == cleanPath || strings.HasPrefix(key, strings.TrimSuffix(cleanPath, "/")+"/") {
+			delete(c.entries, key)
+		}
+	}
+	checksumCacheEntries.Set(float64(len(c.entries)))
+}
@@ -0,0 +1,139 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/versions"
+)
+
+// ErrContentChanged is returned when a file was modified after the client loaded it
+var ErrContentChanged = errors.New("file changed since it was loaded")
+
+// maxEditableSize matches the size limit of OpenFile
+const maxEditableSize = 10 * 1024 * 1024
+
+// Line ending styles accepted by SaveOptions.LineEnding
+const (
+	LineEndingLF   = "lf"
+	LineEndingCRLF = "crlf"
+)
+
+// SaveOptions carries the version a client loaded and any explicit formatting changes
+type SaveOptions struct {
+	// ETag and ModTime identify the version the client edited; at least one is required
+	ETag    string
+	ModTime *time.Time
+	// LineEnding converts the content to "lf" or "crlf"; empty keeps the file's current style
+	LineEnding string
+	// TrailingNewline forces a final newline on or off; nil keeps the file's current choice
+	TrailingNewline *bool
+}
+
+// SaveFileContent replaces the text content of an existing file, failing with
+// ErrContentChanged when the file no longer matches the version in opts
+func (s *FileService) SaveFileContent(ctx context.Context, filePath, content string, opts SaveOptions) (result *FileDetailsResponse, err error) {
+	var written int64
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpWrite, filePath, "", written, err))
+	}()
+
+	// Validate the path
+	targetPath, err := s.validatePath(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	if opts.ETag == "" && opts.ModTime == nil {
+		return nil, fmt.Errorf("etag or modTime of the loaded file is required")
+	}
+	if opts.LineEnding != "" && opts.LineEnding != LineEndingLF && opts.LineEnding != LineEndingCRLF {
+		return nil, fmt.Errorf("unknown line ending %q", opts.LineEnding)
+	}
+	if len(content) > maxEditableSize {
+		return nil, fmt.Errorf("file too large to save: %d bytes (max: %d bytes)", len(content), maxEditableSize)
+	}
+
+	// Check and replace under one lock so concurrent saves cannot both pass the check
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	info, err := s.backend.Stat(targetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("cannot save directory as file: %s", filePath)
+	}
+	if (opts.ETag != "" && opts.ETag != ETag(info.ModTime(), info.Size())) ||
+		(opts.ModTime != nil && !opts.ModTime.Equal(info.ModTime())) {
+		return nil, ErrContentChanged
+	}
+	if info.Size() > maxEditableSize {
+		return nil, fmt.Errorf("file too large to save: %d bytes (max: %d bytes)", info.Size(), maxEditableSize)
+	}
+
+	// The current content decides which line endings and final newline to keep
+	current, err := fs.ReadFileContent(s.backend, targetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %w", err)
+	}
+	data := formatContent(content, current, opts)
+
+	// Keep what is about to be replaced in the version history
+	if s.versions.Accepts(info.Size()) {
+		if err := s.versions.Snapshot(ctx, targetPath, strings.NewReader(current), info, versions.ReasonEdit); err != nil {
+			return nil, fmt.Errorf("failed to keep previous version: %w", err)
+		}
+	}
+
+	if _, err := fs.WriteAtomic(s.backend, targetPath, strings.NewReader(data), nil); err != nil {
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+	written = int64(len(data))
+	s.recordChange(ctx, changes.TypeModify, targetPath, "")
+
+	logging.FromContext(ctx).Info("saved file content", "path", filePath, "bytes", written)
+	return s.GetFileDetails(ctx, filePath)
+}
+
+// formatContent applies the line ending style and final newline of current to
+// content, unless opts explicitly asks for something else
+func formatContent(content, current string, opts SaveOptions) string {
+	lineEnding := opts.LineEnding
+	if lineEnding == "" {
+		lineEnding = detectLineEnding(current)
+	}
+	trailingNewline := strings.HasSuffix(current, "\n")
+	if opts.TrailingNewline != nil {
+		trailingNewline = *opts.TrailingNewline
+	}
+
+	// Work on LF text, then convert
+	text := strings.ReplaceAll(content, "\r\n", "\n")
+	if trailingNewline && text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	} else if !trailingNewline {
+		text = strings.TrimSuffix(text, "\n")
+	}
+
+	if lineEnding == LineEndingCRLF {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	return text
+}
+
+// detectLineEnding returns the dominant line ending style of text, LF when it has no line breaks
+func detectLineEnding(text string) string {
+	crlf := strings.Count(text, "\r\n")
+	if crlf > 0 && crlf >= strings.Count(text, "\n")-crlf {
+		return LineEndingCRLF
+	}
+	return LineEndingLF
+}
@@ -0,0 +1,128 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// saveContent sends body as PUT /content through the handler
+func saveContent(t *testing.T, s *FileService, body map[string]string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPut, "/content", bytes.NewReader(data))
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	NewHandler(s).ServeHTTP(w, r)
+	return w
+}
+
+// currentETag returns the ETag the service reports for filePath
+func currentETag(t *testing.T, s *FileService, filePath string) string {
+	t.Helper()
+	details, err := s.GetFileDetails(context.Background(), filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return details.ETag
+}
+
+func TestSaveContentConflict(t *testing.T) {
+	s, root := newTestService(t, map[string]string{"/notes.txt": "first\n"})
+	loaded := currentETag(t, s, "/notes.txt")
+
+	w := saveContent(t, s, map[string]string{"path": "/notes.txt", "content": "second\n", "etag": loaded}, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("save status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	// A second editor still holding the first version must reload
+	w = saveContent(t, s, map[string]string{"path": "/notes.txt", "content": "third\n"}, map[string]string{"If-Match": loaded})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("stale save status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "notes.txt")); string(content) != "second\n" {
+		t.Fatalf("content = %q, want the first save kept", content)
+	}
+
+	w = saveContent(t, s, map[string]string{"path": "/notes.txt", "content": "third\n"}, map[string]string{"If-Match": currentETag(t, s, "/notes.txt")})
+	if w.Code != http.StatusOK {
+		t.Fatalf("save with the current If-Match status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	if w := saveContent(t, s, map[string]string{"path": "/notes.txt", "content": "x"}, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("save without a version status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSaveContentFormatting(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name    string
+		current string
+		content string
+		opts    SaveOptions
+		want    string
+	}{
+		{name: "keeps CRLF", current: "a\r\nb\r\n", content: "a\nb\nc\n", want: "a\r\nb\r\nc\r\n"},
+		{name: "keeps LF", current: "a\nb\n", content: "a\r\nc\r\n", want: "a\nc\n"},
+		{name: "keeps the final newline", current: "a\n", content: "b", want: "b\n"},
+		{name: "keeps no final newline", current: "a", content: "b\n", want: "b"},
+		{name: "converts to CRLF", current: "a\n", content: "a\nb\n", opts: SaveOptions{LineEnding: LineEndingCRLF}, want: "a\r\nb\r\n"},
+		{name: "converts to LF", current: "a\r\n", content: "a\r\nb\r\n", opts: SaveOptions{LineEnding: LineEndingLF}, want: "a\nb\n"},
+		{name: "adds a final newline", current: "a", content: "b", opts: SaveOptions{TrailingNewline: &yes}, want: "b\n"},
+		{name: "drops the final newline", current: "a\r\n", content: "b\r\n", opts: SaveOptions{TrailingNewline: &no}, want: "b"},
+		{name: "empty file stays empty", current: "a\n", content: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, root := newTestService(t, map[string]string{"/file.txt": tt.current})
+			tt.opts.ETag = currentETag(t, s, "/file.txt")
+
+			if _, err := s.SaveFileContent(context.Background(), "/file.txt", tt.content, tt.opts); err != nil {
+				t.Fatalf("SaveFileContent() error = %v", err)
+			}
+			if content, _ := os.ReadFile(filepath.Join(root, "file.txt")); string(content) != tt.want {
+				t.Fatalf("content = %q, want %q", content, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveContentThroughSymlink(t *testing.T) {
+	s, root := newTestService(t, map[string]string{"/real.txt": "old\n"})
+	if err := os.Symlink("real.txt", filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.SaveFileContent(context.Background(), "/link.txt", "new\n", SaveOptions{ETag: currentETag(t, s, "/link.txt")}); err != nil {
+		t.Fatalf("SaveFileContent() error = %v", err)
+	}
+	if info, err := os.Lstat(filepath.Join(root, "link.txt")); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link.txt = %v, %v, want it to stay a link", info, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "real.txt")); string(content) != "new\n" {
+		t.Fatalf("content = %q, want the link target replaced", content)
+	}
+}
+
+func TestSaveContentTooLarge(t *testing.T) {
+	s, _ := newTestService(t, map[string]string{"/big.log": strings.Repeat("x", maxEditableSize+1)})
+
+	_, err := s.SaveFileContent(context.Background(), "/big.log", "small", SaveOptions{ETag: currentETag(t, s, "/big.log")})
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("SaveFileContent() error = %v, want too large", err)
+	}
+}
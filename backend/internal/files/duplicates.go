@@ -0,0 +1,466 @@
+package files
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/duplicates"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// Limits of the duplicate finder
+const (
+	// partialHashSize is how much of each candidate is hashed before hashing it in full
+	partialHashSize = 16 * 1024
+	// defaultDuplicatesLimit and maxDuplicatesLimit bound a page of groups
+	defaultDuplicatesLimit = 50
+	maxDuplicatesLimit     = 500
+)
+
+// Actions for resolving a duplicate group
+const (
+	DuplicateActionDelete   = "delete"
+	DuplicateActionHardlink = "hardlink"
+)
+
+// Outcomes for the files of a resolved duplicate group
+const (
+	DuplicateDeleted = "deleted"
+	DuplicateLinked  = "linked"
+	DuplicateMissing = "missing"
+	DuplicateChanged = "changed"
+	DuplicateFailed  = "failed"
+)
+
+// duplicateCandidate is a file considered by a duplicate scan
+type duplicateCandidate struct {
+	path string
+	info os.FileInfo
+}
+
+// hashGroup is a set of candidates sharing a hash
+type hashGroup struct {
+	sum   string
+	files []duplicateCandidate
+}
+
+// ScanDuplicates starts a job finding files with identical content below a
+// directory. The result replaces any earlier scan of the same directory.
+func (s *FileService) ScanDuplicates(ctx context.Context, dirPath string, minSize int64) (*jobs.Job, error) {
+	// Validate the path
+	cleanDir, err := s.validatePath(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	if !fs.IsDirectory(s.backend, cleanDir) {
+		return nil, fmt.Errorf("path is not a directory or does not exist: %s", dirPath)
+	}
+
+	job, err := jobs.Default.Start(ctx, "duplicate-scan", dirPath, func(ctx context.Context, report jobs.ReportFunc) (interface{}, error) {
+		return s.scanDuplicates(ctx, cleanDir, minSize, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// scanDuplicates groups the files below dir by size, then by a hash of their
+// first bytes, then by a hash of their full content. Progress counts the
+// candidates sharing a size with another file.
+func (s *FileService) scanDuplicates(ctx context.Context, dir string, minSize int64, report jobs.ReportFunc) (*DuplicatesResponse, error) {
+	scan := duplicates.Scan{
+		Path:      dir,
+		StartedAt: time.Now().UTC(),
+		StartedBy: auth.FromContext(ctx).Username,
+		Groups:    []duplicates.Group{},
+	}
+
+	files, err := s.collectDuplicateCandidates(ctx, dir, minSize)
+	if err != nil {
+		return nil, err
+	}
+
+	bySize := make(map[int64][]duplicateCandidate)
+	for _, file := range files {
+		scan.FilesScanned++
+		scan.BytesScanned += file.info.Size()
+		bySize[file.info.Size()] = append(bySize[file.info.Size()], file)
+	}
+
+	var sizes []int64
+	var total, done int64
+	for size, candidates := range bySize {
+		if len(candidates) > 1 {
+			sizes = append(sizes, size)
+			total += int64(len(candidates))
+		}
+	}
+	report(0, total)
+
+	for _, size := range sizes {
+		candidates := bySize[size]
+
+		// Only large files are worth a partial pass before the full hash
+		subsets := []hashGroup{{files: candidates}}
+		if size > partialHashSize {
+			subsets = s.groupByHash(ctx, candidates, s.partialHash)
+		}
+
+		for _, subset := range subsets {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			for _, same := range s.groupByHash(ctx, subset.files, s.fullHash) {
+				group := duplicates.Group{ID: same.sum, Size: size, WastedBytes: wastedBytes(size, same.files)}
+				for _, file := range same.files {
+					group.Files = append(group.Files, file.path)
+				}
+				sort.Strings(group.Files)
+				if group.WastedBytes > 0 {
+					scan.Groups = append(scan.Groups, group)
+					scan.WastedBytes += group.WastedBytes
+				}
+			}
+			done += int64(len(subset.files))
+			report(done, total)
+		}
+		// Candidates dropped by the partial pass are settled too
+		done += int64(len(candidates)) - countCandidates(subsets)
+		report(done, total)
+	}
+
+	sort.Slice(scan.Groups, func(i, j int) bool {
+		if scan.Groups[i].WastedBytes != scan.Groups[j].WastedBytes {
+			return scan.Groups[i].WastedBytes > scan.Groups[j].WastedBytes
+		}
+		return scan.Groups[i].ID < scan.Groups[j].ID
+	})
+	scan.FinishedAt = time.Now().UTC()
+
+	if err := s.duplicates.Put(scan); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("scanned for duplicates", "path", dir, "files", scan.FilesScanned,
+		"groups", len(scan.Groups), "wasted_bytes", scan.WastedBytes)
+	return newDuplicatesResponse(scan, 0, defaultDuplicatesLimit), nil
+}
+
+// collectDuplicateCandidates lists the regular files below dir of at least
+// minSize bytes; symbolic links are not followed
+func (s *FileService) collectDuplicateCandidates(ctx context.Context, dir string, minSize int64) ([]duplicateCandidate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.backend.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var files []duplicateCandidate
+	for _, entry := range entries {
+		entryPath := path.Join(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			children, err := s.collectDuplicateCandidates(ctx, entryPath, minSize)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, children...)
+		case entry.Mode().IsRegular() && entry.Size() >= minSize:
+			files = append(files, duplicateCandidate{path: entryPath, info: entry})
+		}
+	}
+	return files, nil
+}
+
+// groupByHash splits candidates by the given hash, keeping groups of at least
+// two files; files that cannot be read are left out
+func (s *FileService) groupByHash(ctx context.Context, candidates []duplicateCandidate, hash func(context.Context, duplicateCandidate) (string, error)) []hashGroup {
+	byHash := make(map[string][]duplicateCandidate)
+	var order []string
+	for _, candidate := range candidates {
+		sum, err := hash(ctx, candidate)
+		if err != nil {
+			logging.FromContext(ctx).Debug("skipping unreadable file in duplicate scan", "path", candidate.path, "error", err)
+			continue
+		}
+		if _, seen := byHash[sum]; !seen {
+			order = append(order, sum)
+		}
+		byHash[sum] = append(byHash[sum], candidate)
+	}
+
+	var groups []hashGroup
+	for _, sum := range order {
+		if len(byHash[sum]) > 1 {
+			groups = append(groups, hashGroup{sum: sum, files: byHash[sum]})
+		}
+	}
+	return groups
+}
+
+// partialHash hashes the first partialHashSize bytes of a file
+func (s *FileService) partialHash(ctx context.Context, file duplicateCandidate) (string, error) {
+	f, err := s.backend.OpenFile(file.path, os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, partialHashSize); err != nil && err != io.EOF {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fullHash returns the SHA-256 of a file, reusing the checksum cache
+func (s *FileService) fullHash(ctx context.Context, file duplicateCandidate) (string, error) {
+	sums, _, err := s.sumFile(ctx, file.path, file.info, []string{"sha256"}, nil)
+	if err != nil {
+		return "", err
+	}
+	return sums["sha256"], nil
+}
+
+// countCandidates counts the files in groups of candidates
+func countCandidates(groups []hashGroup) int64 {
+	var count int64
+	for _, group := range groups {
+		count += int64(len(group.files))
+	}
+	return count
+}
+
+// wastedBytes is the space taken by all but one copy of a file; hard links of
+// the same file share their space and count once
+func wastedBytes(size int64, files []duplicateCandidate) int64 {
+	unique := 0
+	seen := make(map[[2]uint64]bool)
+	for _, file := range files {
+		dev, ino, ok := fs.FileID(file.info)
+		if ok {
+			if seen[[2]uint64{dev, ino}] {
+				continue
+			}
+			seen[[2]uint64{dev, ino}] = true
+		}
+		unique++
+	}
+	return size * int64(unique-1)
+}
+
+// ListDuplicates returns a page of the groups found by the latest scan of a directory
+func (s *FileService) ListDuplicates(ctx context.Context, dirPath string, offset, limit int) (*DuplicatesResponse, error) {
+	// Validate the path
+	cleanDir, err := s.validatePath(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	scan, err := s.duplicates.Get(cleanDir)
+	if err != nil {
+		return nil, err
+	}
+	return newDuplicatesResponse(scan, offset, limit), nil
+}
+
+// newDuplicatesResponse builds a page of a scan's groups
+func newDuplicatesResponse(scan duplicates.Scan, offset, limit int) *DuplicatesResponse {
+	if limit <= 0 {
+		limit = defaultDuplicatesLimit
+	}
+	if limit > maxDuplicatesLimit {
+		limit = maxDuplicatesLimit
+	}
+	if offset > len(scan.Groups) {
+		offset = len(scan.Groups)
+	}
+	end := offset + limit
+	if end > len(scan.Groups) {
+		end = len(scan.Groups)
+	}
+
+	return &DuplicatesResponse{
+		Success:      true,
+		Path:         scan.Path,
+		StartedAt:    scan.StartedAt,
+		FinishedAt:   scan.FinishedAt,
+		StartedBy:    scan.StartedBy,
+		FilesScanned: scan.FilesScanned,
+		BytesScanned: scan.BytesScanned,
+		WastedBytes:  scan.WastedBytes,
+		TotalGroups:  len(scan.Groups),
+		Offset:       offset,
+		Limit:        limit,
+		Groups:       scan.Groups[offset:end],
+		RequestTime:  time.Now(),
+	}
+}
+
+// ResolveDuplicates removes the copies in a group of the latest scan of a
+// directory, keeping one original: copies are deleted, or replaced with hard
+// links to the original. Only copies whose content still matches the original
+// are touched. files limits the copies handled; by default all are.
+func (s *FileService) ResolveDuplicates(ctx context.Context, dirPath, groupID, keep, action string, files []string) (*DuplicatesResolveResponse, error) {
+	// Validate the paths
+	cleanDir, err := s.validatePath(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	cleanKeep, err := s.validatePath(keep)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	if action != DuplicateActionDelete && action != DuplicateActionHardlink {
+		return nil, fmt.Errorf("unsupported duplicate action %q", action)
+	}
+
+	group, err := s.duplicates.Group(cleanDir, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	inGroup := make(map[string]bool, len(group.Files))
+	for _, file := range group.Files {
+		inGroup[file] = true
+	}
+	if !inGroup[cleanKeep] {
+		return nil, fmt.Errorf("invalid path: %s is not part of the group", keep)
+	}
+
+	targets := make(map[string]bool)
+	for _, file := range files {
+		cleanFile, err := s.validatePath(file)
+		if err != nil {
+			return nil, fmt.Errorf("path validation failed: %w", err)
+		}
+		if !inGroup[cleanFile] || cleanFile == cleanKeep {
+			return nil, fmt.Errorf("invalid path: %s is not a copy in the group", file)
+		}
+		targets[cleanFile] = true
+	}
+	if len(files) == 0 {
+		for _, file := range group.Files {
+			if file != cleanKeep {
+				targets[file] = true
+			}
+		}
+	}
+
+	// The original must still hold the content the group was found with
+	keepInfo, err := s.backend.Stat(cleanKeep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if !s.matchesGroup(ctx, cleanKeep, keepInfo, group) {
+		return nil, fmt.Errorf("%w: %s", ErrContentChanged, keep)
+	}
+
+	result := &DuplicatesResolveResponse{
+		Success: true,
+		Path:    cleanDir,
+		Action:  action,
+		Kept:    cleanKeep,
+		Results: []DuplicateResult{},
+	}
+
+	remaining := []duplicateCandidate{{path: cleanKeep, info: keepInfo}}
+	for _, file := range group.Files {
+		if file == cleanKeep {
+			continue
+		}
+		info, statErr := s.backend.Stat(file)
+		if !targets[file] {
+			if statErr == nil {
+				remaining = append(remaining, duplicateCandidate{path: file, info: info})
+			}
+			continue
+		}
+
+		outcome := DuplicateResult{Path: file}
+		switch {
+		case statErr != nil:
+			outcome.Status = DuplicateMissing
+		case !s.matchesGroup(ctx, file, info, group):
+			outcome.Status = DuplicateChanged
+		case action == DuplicateActionHardlink && os.SameFile(info, keepInfo):
+			// Already a hard link of the original
+			outcome.Status = DuplicateLinked
+		default:
+			freed := group.Size
+			if os.SameFile(info, keepInfo) {
+				freed = 0
+			}
+			if err := s.resolveDuplicate(ctx, cleanKeep, file, action); err != nil {
+				outcome.Status = DuplicateFailed
+				outcome.Error = err.Error()
+				remaining = append(remaining, duplicateCandidate{path: file, info: info})
+				break
+			}
+			outcome.Status = DuplicateDeleted
+			if action == DuplicateActionHardlink {
+				outcome.Status = DuplicateLinked
+			}
+			result.FreedBytes += freed
+		}
+		result.Results = append(result.Results, outcome)
+	}
+
+	// Keep what is left of the group for the next round
+	group.Files = nil
+	for _, file := range remaining {
+		group.Files = append(group.Files, file.path)
+	}
+	sort.Strings(group.Files)
+	group.WastedBytes = wastedBytes(group.Size, remaining)
+	if err := s.duplicates.UpdateGroup(cleanDir, group); err != nil {
+		return nil, err
+	}
+	result.Group = group
+
+	logging.FromContext(ctx).Info("resolved duplicates", "path", cleanDir, "group", groupID,
+		"action", action, "freed_bytes", result.FreedBytes)
+	result.RequestTime = time.Now()
+	return result, nil
+}
+
+// resolveDuplicate deletes a copy or replaces it with a hard link to the original
+func (s *FileService) resolveDuplicate(ctx context.Context, original, copyPath, action string) (err error) {
+	if action == DuplicateActionDelete {
+		return s.DeleteFile(ctx, copyPath)
+	}
+
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpLink, copyPath, original, 0, err))
+	}()
+	if err := fs.ReplaceWithLink(s.backend, original, copyPath); err != nil {
+		return fmt.Errorf("failed to link: %w", err)
+	}
+	s.recordChange(ctx, changes.TypeModify, copyPath, "")
+	return nil
+}
+
+// matchesGroup reports whether a file still has the size and content of a duplicate group
+func (s *FileService) matchesGroup(ctx context.Context, cleanPath string, info os.FileInfo, group duplicates.Group) bool {
+	if !info.Mode().IsRegular() || info.Size() != group.Size {
+		return false
+	}
+	sum, err := s.fullHash(ctx, duplicateCandidate{path: cleanPath, info: info})
+	return err == nil && sum == group.ID
+}
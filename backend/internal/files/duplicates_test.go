@@ -0,0 +1,112 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/duplicates"
+)
+
+// scanTestDuplicates gives s a duplicate store of its own and scans dir
+func scanTestDuplicates(t *testing.T, s *FileService, dir string, minSize int64) *DuplicatesResponse {
+	t.Helper()
+	store, err := duplicates.NewStore(filepath.Join(t.TempDir(), "duplicates.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.duplicates = store
+
+	result, err := s.scanDuplicates(context.Background(), dir, minSize, func(done, total int64) {})
+	if err != nil {
+		t.Fatalf("scanDuplicates() error = %v", err)
+	}
+	return result
+}
+
+func TestScanDuplicates(t *testing.T) {
+	large := strings.Repeat("x", partialHashSize+10)
+	s, root := newTestService(t, map[string]string{
+		"/a.txt":          "same content",
+		"/sub/b.txt":      "same content",
+		"/sub/deep/c.txt": "same content",
+		"/d.txt":          "other conten",
+		"/big1.bin":       large + "1",
+		"/big2.bin":       large + "2",
+		"/big3.bin":       large + "1",
+		"/tiny1":          "t",
+		"/tiny2":          "t",
+	})
+	if err := os.Link(filepath.Join(root, "a.txt"), filepath.Join(root, "sub", "a-link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	result := scanTestDuplicates(t, s, "/", 2)
+	if result.FilesScanned != 8 || result.TotalGroups != 2 {
+		t.Fatalf("scan = %d files in %d groups, want 8 files in 2 groups: %+v", result.FilesScanned, result.TotalGroups, result.Groups)
+	}
+
+	// Groups are sorted by wasted space, and the hard link shares its space
+	bigGroup, textGroup := result.Groups[0], result.Groups[1]
+	if got := strings.Join(bigGroup.Files, ","); got != "/big1.bin,/big3.bin" || bigGroup.WastedBytes != int64(len(large)+1) {
+		t.Fatalf("first group = %+v, want big1 and big3", bigGroup)
+	}
+	if got := strings.Join(textGroup.Files, ","); got != "/a.txt,/sub/a-link.txt,/sub/b.txt,/sub/deep/c.txt" || textGroup.WastedBytes != 2*12 {
+		t.Fatalf("second group = %+v, want the four text files wasting two copies", textGroup)
+	}
+
+	listed, err := s.ListDuplicates(context.Background(), "/", 1, 1)
+	if err != nil || len(listed.Groups) != 1 || listed.Groups[0].ID != textGroup.ID {
+		t.Fatalf("ListDuplicates() page = %+v, %v, want the second group", listed, err)
+	}
+}
+
+func TestResolveDuplicates(t *testing.T) {
+	s, root := newTestService(t, map[string]string{
+		"/keep.txt":    "same content",
+		"/copy1.txt":   "same content",
+		"/copy2.txt":   "same content",
+		"/changed.txt": "same content",
+	})
+	ctx := context.Background()
+	group := scanTestDuplicates(t, s, "/", 0).Groups[0]
+
+	// A copy edited since the scan is left alone
+	if err := os.WriteFile(filepath.Join(root, "changed.txt"), []byte("edited since"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.ResolveDuplicates(ctx, "/", group.ID, "/keep.txt", DuplicateActionHardlink, []string{"/copy1.txt", "/changed.txt"})
+	if err != nil {
+		t.Fatalf("ResolveDuplicates() error = %v", err)
+	}
+	statuses := map[string]string{}
+	for _, outcome := range result.Results {
+		statuses[outcome.Path] = outcome.Status
+	}
+	if statuses["/copy1.txt"] != DuplicateLinked || statuses["/changed.txt"] != DuplicateChanged || result.FreedBytes != 12 {
+		t.Fatalf("results = %+v, freed %d, want copy1 linked and changed.txt skipped", result.Results, result.FreedBytes)
+	}
+	keepInfo, _ := os.Stat(filepath.Join(root, "keep.txt"))
+	copyInfo, _ := os.Stat(filepath.Join(root, "copy1.txt"))
+	if !os.SameFile(keepInfo, copyInfo) {
+		t.Fatal("copy1.txt is not a hard link of keep.txt")
+	}
+
+	result, err = s.ResolveDuplicates(ctx, "/", group.ID, "/keep.txt", DuplicateActionDelete, nil)
+	if err != nil {
+		t.Fatalf("ResolveDuplicates() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "copy2.txt")); !os.IsNotExist(err) {
+		t.Fatalf("copy2.txt was not deleted: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "keep.txt")); string(content) != "same content" {
+		t.Fatalf("kept file = %q, want it untouched", content)
+	}
+
+	if _, err := s.ResolveDuplicates(ctx, "/", group.ID, "/other.txt", DuplicateActionDelete, nil); err == nil {
+		t.Fatal("ResolveDuplicates() kept a file outside the group")
+	}
+}
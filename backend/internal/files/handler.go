@@ -1,12 +1,23 @@
 package files
 
 import (
+	"encoding/base64"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/httputil"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/metrics"
 )
 
 // FileHandler handles HTTP requests for file operations
@@ -15,23 +26,62 @@ type FileHandler struct {
 }
 
 // NewHandler creates a new file handler with proper routing
-func NewHandler() http.Handler {
+func NewHandler(svc FileServiceInterface) http.Handler {
 	mux := http.NewServeMux()
 	handler := &FileHandler{
-		svc: NewFileService(),
+		svc: svc,
 	}
 
 	// File management endpoints
-	mux.HandleFunc("/list", handler.handleListFiles)
-	mux.HandleFunc("/open", handler.handleOpenFile)
-	mux.HandleFunc("/details", handler.handleGetFileDetails)
-	mux.HandleFunc("/delete", handler.handleDeleteFile)
-	mux.HandleFunc("/raw", handler.handleRawFile)
+	handler.handle(mux, "/list", handler.handleListFiles)
+	handler.handle(mux, "/watch", handler.handleWatch)
+	handler.handle(mux, "/changes", handler.handleChanges)
+	handler.handle(mux, "/open", handler.handleOpenFile)
+	handler.handle(mux, "/details", handler.handleGetFileDetails)
+	handler.handle(mux, "/delete", handler.handleDeleteFile)
+	handler.handle(mux, "/raw", handler.handleRawFile)
+	handler.handle(mux, "/content", handler.handleSaveContent)
+	handler.handle(mux, "/checksum", handler.handleChecksum)
+	handler.handle(mux, "/checksum/verify", handler.handleVerifyManifest)
+	handler.handle(mux, "/checksum/manifest", handler.handleCreateManifest)
+
+	// Duplicate finder endpoints
+	handler.handle(mux, "/duplicates", handler.handleListDuplicates)
+	handler.handle(mux, "/duplicates/scan", handler.handleScanDuplicates)
+	handler.handle(mux, "/duplicates/resolve", handler.handleResolveDuplicates)
+
+	// Batch operations
+	handler.handle(mux, "/batch", handler.handleBatch)
+
+	// Permissions and ownership, for admins only
+	handler.handle(mux, "/chmod", handler.handleChmod)
+	handler.handle(mux, "/chown", handler.handleChown)
+
+	// Extended attributes; namespaces other than user. are checked by the service
+	handler.handle(mux, "/xattrs", handler.handleListXattrs)
+	handler.handle(mux, "/xattrs/get", handler.handleGetXattr)
+	handler.handle(mux, "/xattrs/set", handler.handleSetXattr)
+	handler.handle(mux, "/xattrs/remove", handler.handleRemoveXattr)
+
+	// Symbolic and hard links
+	handler.handle(mux, "/link", handler.handleCreateLink)
+
+	// Version history endpoints
+	handler.handle(mux, "/versions", handler.handleListVersions)
+	handler.handle(mux, "/versions/open", handler.handleOpenVersion)
+	handler.handle(mux, "/versions/raw", handler.handleRawVersion)
+	handler.handle(mux, "/versions/diff", handler.handleDiffVersion)
+	handler.handle(mux, "/versions/restore", handler.handleRestoreVersion)
 
 	// Add middleware for logging and CORS
 	return handler.withMiddleware(mux)
 }
 
+// handle registers a route on the mux with per-route metrics
+func (h *FileHandler) handle(mux *http.ServeMux, pattern string, fn http.HandlerFunc) {
+	mux.Handle(pattern, metrics.InstrumentRoute("/file"+pattern, fn))
+}
+
 // handleListFiles handles GET /file/list - Lists files and directories
 func (h *FileHandler) handleListFiles(w http.ResponseWriter, r *http.Request) {
 	// Check HTTP method
@@ -49,18 +99,156 @@ func (h *FileHandler) handleListFiles(w http.ResponseWriter, r *http.Request) {
 	// Clean and validate path
 	cleanPath := filepath.Clean(path)
 
-	// Debug logging
-	log.Printf("Received path: %q, cleaned path: %q", path, cleanPath)
+	logging.FromContext(r.Context()).Debug("listing path", "path", path, "clean_path", cleanPath)
+
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid path provided", http.StatusBadRequest)
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.ListFiles(r.Context(), cleanPath)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to list files", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// Timing of /file/watch streams: the reconnect delay suggested to clients, and
+// the interval of comments that keep idle connections open through proxies
+const (
+	watchRetry     = 3 * time.Second
+	watchHeartbeat = 30 * time.Second
+)
+
+// handleWatch handles GET /file/watch - Streams changes of a directory as
+// server-sent events; reconnecting clients resume with Last-Event-ID
+func (h *FileHandler) handleWatch(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract and validate path parameter
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		path = "/"
+	}
 
+	// Clean and validate path
+	cleanPath := filepath.Clean(path)
 	if !isValidPath(cleanPath) {
 		h.sendErrorResponse(w, "Invalid path provided", http.StatusBadRequest)
 		return
 	}
 
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.sendErrorResponse(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// EventSource sends Last-Event-ID itself; the query parameter is for clients that cannot set headers
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	// Call service layer
+	sub, err := h.svc.WatchDirectory(r.Context(), cleanPath, lastEventID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to watch directory", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", watchRetry.Milliseconds())
+	if sub.Resync {
+		// The events since Last-Event-ID are gone; the client has to reload the listing
+		fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(watchHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-sub.C:
+			if !ok {
+				// The directory went away, the server is shutting down or the client fell behind
+				if sub.Resync {
+					fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+					flusher.Flush()
+				}
+				return
+			}
+			data, err := json.Marshal(newWatchEvent(cleanPath, event))
+			if err != nil {
+				slog.Error("failed to encode watch event", "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleChanges handles GET /file/changes - Returns the changes after a cursor
+// so sync clients can catch up without listing everything again
+func (h *FileHandler) handleChanges(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	cursor := query.Get("cursor")
+	if cursor != "" && !changes.ValidCursor(cursor) {
+		h.sendErrorResponse(w, "Invalid cursor parameter", http.StatusBadRequest)
+		return
+	}
+
+	// The path is optional and limits the changes to a subtree
+	cleanPath := ""
+	if dirPath := query.Get("path"); dirPath != "" {
+		cleanPath = filepath.Clean(dirPath)
+		if !isValidPath(cleanPath) {
+			h.sendErrorResponse(w, "Invalid path provided", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var limit int
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			h.sendErrorResponse(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Call service layer
-	result, err := h.svc.ListFiles(cleanPath)
+	result, err := h.svc.ListChanges(r.Context(), cursor, cleanPath, limit)
 	if err != nil {
-		log.Printf("Error listing files for path %s: %v", cleanPath, err)
+		logging.FromContext(r.Context()).Error("failed to list changes", "cursor", cursor, "error", err)
 		h.handleServiceError(w, err)
 		return
 	}
@@ -92,9 +280,9 @@ func (h *FileHandler) handleOpenFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service layer
-	result, err := h.svc.OpenFile(cleanPath)
+	result, err := h.svc.OpenFile(r.Context(), cleanPath)
 	if err != nil {
-		log.Printf("Error opening file %s: %v", cleanPath, err)
+		logging.FromContext(r.Context()).Error("failed to open file", "path", cleanPath, "error", err)
 		h.handleServiceError(w, err)
 		return
 	}
@@ -126,9 +314,9 @@ func (h *FileHandler) handleGetFileDetails(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Call service layer
-	result, err := h.svc.GetFileDetails(cleanPath)
+	result, err := h.svc.GetFileDetails(r.Context(), cleanPath)
 	if err != nil {
-		log.Printf("Error getting file details for %s: %v", cleanPath, err)
+		logging.FromContext(r.Context()).Error("failed to get file details", "path", cleanPath, "error", err)
 		h.handleServiceError(w, err)
 		return
 	}
@@ -160,9 +348,9 @@ func (h *FileHandler) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service layer
-	err := h.svc.DeleteFile(cleanPath)
+	err := h.svc.DeleteFile(r.Context(), cleanPath)
 	if err != nil {
-		log.Printf("Error deleting file %s: %v", cleanPath, err)
+		logging.FromContext(r.Context()).Error("failed to delete file", "path", cleanPath, "error", err)
 		h.handleServiceError(w, err)
 		return
 	}
@@ -199,72 +387,1016 @@ func (h *FileHandler) handleRawFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service layer to serve raw file
-	err := h.svc.ServeRawFile(w, cleanPath)
+	rw := logging.NewResponseWriter(w)
+	err := h.svc.ServeRawFile(r.Context(), rw, r, cleanPath)
+	metrics.RawBytesServed.WithLabelValues("raw").Add(float64(rw.BytesWritten()))
 	if err != nil {
-		log.Printf("Error serving raw file %s: %v", cleanPath, err)
+		logging.FromContext(r.Context()).Error("failed to serve raw file", "path", cleanPath, "error", err)
 		h.handleServiceError(w, err)
 		return
 	}
 }
 
-// Helper methods for the handler
+// saveContentRequest is the body of PUT /file/content
+type saveContentRequest struct {
+	Path            string     `json:"path"`
+	Content         *string    `json:"content"`
+	ETag            string     `json:"etag"`
+	ModTime         *time.Time `json:"modTime"`
+	LineEnding      string     `json:"lineEnding"`
+	TrailingNewline *bool      `json:"trailingNewline"`
+}
 
-// sendJSONResponse sends a JSON response with proper headers
-func (h *FileHandler) sendJSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+// maxSaveRequestSize allows for JSON escaping of the largest editable file
+const maxSaveRequestSize = 2*maxEditableSize + 64*1024
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// handleSaveContent handles PUT /file/content - Writes edited text back to a file
+func (h *FileHandler) handleSaveContent(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPut {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req saveContentRequest
+	if err := httputil.DecodeJSON(w, r, &req, maxSaveRequestSize); err != nil {
+		h.sendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// The ETag may also arrive as a standard If-Match header
+	if req.ETag == "" {
+		req.ETag = r.Header.Get("If-Match")
+	}
+
+	if req.Path == "" {
+		h.sendErrorResponse(w, "File path is required", http.StatusBadRequest)
+		return
+	}
+	if req.Content == nil {
+		h.sendErrorResponse(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+	if req.ETag == "" && req.ModTime == nil {
+		h.sendErrorResponse(w, "etag or modTime of the loaded file is required", http.StatusBadRequest)
+		return
+	}
+	if req.LineEnding != "" && req.LineEnding != LineEndingLF && req.LineEnding != LineEndingCRLF {
+		h.sendErrorResponse(w, "lineEnding must be \"lf\" or \"crlf\"", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(req.Path)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.SaveFileContent(r.Context(), cleanPath, *req.Content, SaveOptions{
+		ETag:            req.ETag,
+		ModTime:         req.ModTime,
+		LineEnding:      req.LineEnding,
+		TrailingNewline: req.TrailingNewline,
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to save file content", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
 	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
 }
 
-// sendErrorResponse sends an error response with proper format
-func (h *FileHandler) sendErrorResponse(w http.ResponseWriter, message string, statusCode int) {
-	errorResponse := map[string]interface{}{
-		"success": false,
-		"error":   message,
-		"code":    statusCode,
+// handleChecksum handles GET /file/checksum - Computes checksums of a file;
+// large files are hashed in a background job that is returned with 202 Accepted
+func (h *FileHandler) handleChecksum(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	h.sendJSONResponse(w, errorResponse, statusCode)
+
+	// Extract and validate file path
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		h.sendErrorResponse(w, "File path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// Accept algo=sha256,md5 as well as repeated algo parameters
+	algos, err := ParseChecksumAlgorithms(r.URL.Query()["algo"])
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Call service layer
+	result, job, err := h.svc.ChecksumFile(r.Context(), cleanPath, algos)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to compute checksum", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	if job != nil {
+		h.sendJobAccepted(w, job)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
 }
 
-// handleServiceError handles errors from the service layer
-func (h *FileHandler) handleServiceError(w http.ResponseWriter, err error) {
-	if strings.Contains(err.Error(), "no such file") || strings.Contains(err.Error(), "not found") {
-		h.sendErrorResponse(w, "File or directory not found", http.StatusNotFound)
-	} else if strings.Contains(err.Error(), "access denied") || strings.Contains(err.Error(), "permission denied") {
-		h.sendErrorResponse(w, "Access denied", http.StatusForbidden)
-	} else if strings.Contains(err.Error(), "invalid path") {
-		h.sendErrorResponse(w, "Invalid path provided", http.StatusBadRequest)
-	} else {
-		h.sendErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+// handleVerifyManifest handles POST /file/checksum/verify - Verifies the files
+// listed in a checksum manifest in a background job
+func (h *FileHandler) handleVerifyManifest(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract and validate manifest path
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		h.sendErrorResponse(w, "Manifest path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// The algorithm is optional; GNU format lines are recognised by digest length
+	algo := strings.ToLower(r.URL.Query().Get("algo"))
+	if algo != "" {
+		if _, ok := checksumAlgorithms[algo]; !ok {
+			h.sendErrorResponse(w, "Unsupported checksum algorithm", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Call service layer
+	job, err := h.svc.VerifyManifest(r.Context(), cleanPath, algo)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to verify manifest", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
 	}
+
+	h.sendJobAccepted(w, job)
 }
 
-// withMiddleware adds middleware to the handler
-func (h *FileHandler) withMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Add CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+// handleCreateManifest handles POST /file/checksum/manifest - Writes a checksum
+// manifest for a directory in a background job
+func (h *FileHandler) handleCreateManifest(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+	// Extract and validate directory path
+	query := r.URL.Query()
+	dirPath := query.Get("path")
+	if dirPath == "" {
+		h.sendErrorResponse(w, "Directory path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(dirPath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	opts := ManifestOptions{
+		Algo:   strings.ToLower(query.Get("algo")),
+		Name:   query.Get("name"),
+		Format: strings.ToLower(query.Get("format")),
+	}
+	if opts.Algo != "" {
+		if _, ok := checksumAlgorithms[opts.Algo]; !ok {
+			h.sendErrorResponse(w, "Unsupported checksum algorithm", http.StatusBadRequest)
+			return
+		}
+	}
+	if opts.Format != "" && opts.Format != ManifestFormatGNU && opts.Format != ManifestFormatBSD {
+		h.sendErrorResponse(w, "Format must be gnu or bsd", http.StatusBadRequest)
+		return
+	}
+	for name, flag := range map[string]*bool{"recursive": &opts.Recursive, "overwrite": &opts.Overwrite} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				h.sendErrorResponse(w, "Invalid value for "+name, http.StatusBadRequest)
+				return
+			}
+			*flag = parsed
+		}
+	}
+
+	// Call service layer
+	job, err := h.svc.CreateManifest(r.Context(), cleanPath, opts)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to create manifest", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.sendJobAccepted(w, job)
+}
+
+// handleScanDuplicates handles POST /file/duplicates/scan - Finds duplicate
+// files below a directory in a background job
+func (h *FileHandler) handleScanDuplicates(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract and validate directory path
+	dirPath := r.URL.Query().Get("path")
+	if dirPath == "" {
+		h.sendErrorResponse(w, "Directory path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(dirPath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// Empty files are all identical, so they are skipped by default
+	minSize := int64(1)
+	if value := r.URL.Query().Get("minSize"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			h.sendErrorResponse(w, "Invalid minSize parameter", http.StatusBadRequest)
 			return
 		}
+		minSize = parsed
+	}
 
-		// Log request
-		start := time.Now()
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
+	// Call service layer
+	job, err := h.svc.ScanDuplicates(r.Context(), cleanPath, minSize)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to scan for duplicates", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
 
-		// Call next handler
-		next.ServeHTTP(w, r)
+	h.sendJobAccepted(w, job)
+}
 
-		// Log completion
-		log.Printf("Completed %s %s in %v", r.Method, r.URL.Path, time.Since(start))
+// handleListDuplicates handles GET /file/duplicates - Pages through the
+// groups found by the latest scan of a directory
+func (h *FileHandler) handleListDuplicates(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract and validate directory path
+	query := r.URL.Query()
+	dirPath := query.Get("path")
+	if dirPath == "" {
+		h.sendErrorResponse(w, "Directory path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(dirPath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	var offset, limit int
+	var err error
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			h.sendErrorResponse(w, "Invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			h.sendErrorResponse(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Call service layer
+	result, err := h.svc.ListDuplicates(r.Context(), cleanPath, offset, limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to list duplicates", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// resolveDuplicatesRequest is the body of POST /file/duplicates/resolve
+type resolveDuplicatesRequest struct {
+	Path   string   `json:"path"`
+	Group  string   `json:"group"`
+	Keep   string   `json:"keep"`
+	Action string   `json:"action"`
+	Files  []string `json:"files"`
+}
+
+// maxResolveRequestSize bounds the body of POST /file/duplicates/resolve
+const maxResolveRequestSize = 1 << 20
+
+// handleResolveDuplicates handles POST /file/duplicates/resolve - Deletes or
+// hard links the copies in a duplicate group, keeping one original
+func (h *FileHandler) handleResolveDuplicates(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req resolveDuplicatesRequest
+	if err := httputil.DecodeJSON(w, r, &req, maxResolveRequestSize); err != nil {
+		h.sendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Path == "" || req.Group == "" || req.Keep == "" {
+		h.sendErrorResponse(w, "Path, group and keep are required", http.StatusBadRequest)
+		return
+	}
+	if req.Action != DuplicateActionDelete && req.Action != DuplicateActionHardlink {
+		h.sendErrorResponse(w, "action must be \"delete\" or \"hardlink\"", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate paths
+	cleanPath := filepath.Clean(req.Path)
+	cleanKeep := filepath.Clean(req.Keep)
+	if !isValidPath(cleanPath) || !isValidPath(cleanKeep) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+	for i, file := range req.Files {
+		req.Files[i] = filepath.Clean(file)
+		if !isValidPath(req.Files[i]) {
+			h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Call service layer
+	result, err := h.svc.ResolveDuplicates(r.Context(), cleanPath, req.Group, cleanKeep, req.Action, req.Files)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to resolve duplicates", "path", cleanPath, "group", req.Group, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// batchRequest is the body of POST /file/batch
+type batchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+	// OnError is "stop" (default) or "continue"
+	OnError string `json:"onError"`
+	DryRun  bool   `json:"dryRun"`
+}
+
+// maxBatchRequestSize bounds the body of POST /file/batch
+const maxBatchRequestSize = 4 << 20
+
+// handleBatch handles POST /file/batch - Runs a list of delete, move, copy,
+// mkdir and chmod operations as one background job
+func (h *FileHandler) handleBatch(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req batchRequest
+	if err := httputil.DecodeJSON(w, r, &req, maxBatchRequestSize); err != nil {
+		h.sendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.OnError != "" && req.OnError != "stop" && req.OnError != "continue" {
+		h.sendErrorResponse(w, "onError must be \"stop\" or \"continue\"", http.StatusBadRequest)
+		return
+	}
+	if err := ValidateBatch(req.Operations); err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate paths
+	for i := range req.Operations {
+		op := &req.Operations[i]
+		op.Path = filepath.Clean(op.Path)
+		if !isValidPath(op.Path) {
+			h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+			return
+		}
+		if op.Target != "" {
+			op.Target = filepath.Clean(op.Target)
+			if !isValidPath(op.Target) {
+				h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	// Call service layer
+	job, err := h.svc.RunBatch(r.Context(), req.Operations, BatchOptions{
+		ContinueOnError: req.OnError == "continue",
+		DryRun:          req.DryRun,
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to start batch", "operations", len(req.Operations), "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.sendJobAccepted(w, job)
+}
+
+// handleChmod handles POST /file/chmod - Sets the permissions of a file or
+// directory; recursive changes run in a background job
+func (h *FileHandler) handleChmod(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract and validate file path
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		h.sendErrorResponse(w, "File path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	mode, err := ParseMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recursive := r.URL.Query().Get("recursive") == "true"
+
+	// Call service layer
+	result, job, err := h.svc.SetPermissions(r.Context(), cleanPath, mode, recursive)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to change mode", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	if job != nil {
+		h.sendJobAccepted(w, job)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// handleChown handles POST /file/chown - Sets the owner and group of a file
+// or directory; recursive changes run in a background job
+func (h *FileHandler) handleChown(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract and validate file path
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		h.sendErrorResponse(w, "File path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// Owner and group are names or numeric IDs; at least one is required
+	userName, groupName := r.URL.Query().Get("user"), r.URL.Query().Get("group")
+	if userName == "" && groupName == "" {
+		h.sendErrorResponse(w, "User or group is required", http.StatusBadRequest)
+		return
+	}
+	uid, gid, err := ParseOwner(userName, groupName)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recursive := r.URL.Query().Get("recursive") == "true"
+
+	// Call service layer
+	result, job, err := h.svc.SetOwner(r.Context(), cleanPath, uid, gid, recursive)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to change owner", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	if job != nil {
+		h.sendJobAccepted(w, job)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// setXattrRequest is the body of POST /file/xattrs/set
+type setXattrRequest struct {
+	// Value is base64 encoded
+	Value string `json:"value"`
+}
+
+// maxSetXattrRequestSize bounds the body of POST /file/xattrs/set, room for
+// the largest value in base64
+const maxSetXattrRequestSize = 128 << 10
+
+// handleListXattrs handles GET /file/xattrs - Lists the extended attributes of a file
+func (h *FileHandler) handleListXattrs(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract and validate file path
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		h.sendErrorResponse(w, "File path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.ListXattrs(r.Context(), cleanPath)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to list extended attributes", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// handleGetXattr handles GET /file/xattrs/get - Returns one extended attribute
+func (h *FileHandler) handleGetXattr(w http.ResponseWriter, r *http.Request) {
+	cleanPath, name, ok := h.xattrParams(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.GetXattr(r.Context(), cleanPath, name)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to get extended attribute", "path", cleanPath, "name", name, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// handleSetXattr handles POST /file/xattrs/set - Creates or replaces an extended attribute
+func (h *FileHandler) handleSetXattr(w http.ResponseWriter, r *http.Request) {
+	cleanPath, name, ok := h.xattrParams(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+
+	var req setXattrRequest
+	if err := httputil.DecodeJSON(w, r, &req, maxSetXattrRequestSize); err != nil {
+		h.sendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	value, err := base64.StdEncoding.DecodeString(req.Value)
+	if err != nil {
+		h.sendErrorResponse(w, "Value must be base64 encoded", http.StatusBadRequest)
+		return
+	}
+
+	// Call service layer
+	if err := h.svc.SetXattr(r.Context(), cleanPath, name, value); err != nil {
+		logging.FromContext(r.Context()).Error("failed to set extended attribute", "path", cleanPath, "name", name, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, XattrResponse{
+		Success:     true,
+		Path:        cleanPath,
+		Name:        name,
+		Value:       req.Value,
+		RequestTime: time.Now(),
+	}, http.StatusOK)
+}
+
+// handleRemoveXattr handles DELETE /file/xattrs/remove - Removes an extended attribute
+func (h *FileHandler) handleRemoveXattr(w http.ResponseWriter, r *http.Request) {
+	cleanPath, name, ok := h.xattrParams(w, r, http.MethodDelete)
+	if !ok {
+		return
+	}
+
+	// Call service layer
+	if err := h.svc.RemoveXattr(r.Context(), cleanPath, name); err != nil {
+		logging.FromContext(r.Context()).Error("failed to remove extended attribute", "path", cleanPath, "name", name, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, map[string]interface{}{
+		"success":     true,
+		"message":     "Extended attribute removed successfully",
+		"path":        cleanPath,
+		"name":        name,
+		"requestTime": time.Now(),
+	}, http.StatusOK)
+}
+
+// handleCreateLink handles POST /file/link - Creates a symbolic or hard link
+// at path pointing to target
+func (h *FileHandler) handleCreateLink(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	linkType := r.URL.Query().Get("type")
+	if linkType != LinkTypeSymlink && linkType != LinkTypeHard {
+		h.sendErrorResponse(w, "Link type must be symlink or hard", http.StatusBadRequest)
+		return
+	}
+
+	// Extract and validate the link path
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		h.sendErrorResponse(w, "File path is required", http.StatusBadRequest)
+		return
+	}
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// The target may be relative to the link, so the service checks where it resolves
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		h.sendErrorResponse(w, "Link target is required", http.StatusBadRequest)
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.CreateLink(r.Context(), linkType, target, cleanPath)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to create link", "path", cleanPath, "target", target, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// xattrParams checks the method and extracts the path and name parameters of
+// the extended attribute endpoints, sending an error response when they are invalid
+func (h *FileHandler) xattrParams(w http.ResponseWriter, r *http.Request, method string) (string, string, bool) {
+	// Check HTTP method
+	if r.Method != method {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return "", "", false
+	}
+
+	// Extract and validate parameters
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		h.sendErrorResponse(w, "File path is required", http.StatusBadRequest)
+		return "", "", false
+	}
+	name := r.URL.Query().Get("name")
+	if err := ValidateXattrName(name); err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return "", "", false
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return "", "", false
+	}
+	return cleanPath, name, true
+}
+
+// handleListVersions handles GET /file/versions - Lists earlier versions of a file
+func (h *FileHandler) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	// Check HTTP method
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract and validate file path
+	filePath := r.URL.Query().Get("path")
+	if filePath == "" {
+		h.sendErrorResponse(w, "File path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.ListVersions(r.Context(), cleanPath)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to list versions", "path", cleanPath, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// handleOpenVersion handles GET /file/versions/open - Reads an earlier version of a file
+func (h *FileHandler) handleOpenVersion(w http.ResponseWriter, r *http.Request) {
+	cleanPath, id, ok := h.versionParams(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.OpenVersion(r.Context(), cleanPath, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to open version", "path", cleanPath, "version", id, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// handleRawVersion handles GET /file/versions/raw - Downloads an earlier version of a file
+func (h *FileHandler) handleRawVersion(w http.ResponseWriter, r *http.Request) {
+	cleanPath, id, ok := h.versionParams(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+
+	// Call service layer to serve the stored content
+	if err := h.svc.ServeVersion(r.Context(), w, r, cleanPath, id); err != nil {
+		logging.FromContext(r.Context()).Error("failed to serve version", "path", cleanPath, "version", id, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+}
+
+// handleDiffVersion handles GET /file/versions/diff - Diffs an earlier version against the current file
+func (h *FileHandler) handleDiffVersion(w http.ResponseWriter, r *http.Request) {
+	cleanPath, id, ok := h.versionParams(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.DiffVersion(r.Context(), cleanPath, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to diff version", "path", cleanPath, "version", id, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// handleRestoreVersion handles POST /file/versions/restore - Restores an earlier version of a file
+func (h *FileHandler) handleRestoreVersion(w http.ResponseWriter, r *http.Request) {
+	cleanPath, id, ok := h.versionParams(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+
+	// Call service layer
+	result, err := h.svc.RestoreVersion(r.Context(), cleanPath, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to restore version", "path", cleanPath, "version", id, "error", err)
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Send successful response
+	h.sendJSONResponse(w, result, http.StatusOK)
+}
+
+// versionParams checks the method and extracts the path and id parameters of
+// the version endpoints, sending an error response when they are invalid
+func (h *FileHandler) versionParams(w http.ResponseWriter, r *http.Request, method string) (string, string, bool) {
+	// Check HTTP method
+	if r.Method != method {
+		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return "", "", false
+	}
+
+	// Extract and validate parameters
+	filePath := r.URL.Query().Get("path")
+	id := r.URL.Query().Get("id")
+	if filePath == "" || id == "" {
+		h.sendErrorResponse(w, "File path and version id are required", http.StatusBadRequest)
+		return "", "", false
+	}
+
+	// Clean and validate path
+	cleanPath := filepath.Clean(filePath)
+	if !isValidPath(cleanPath) {
+		h.sendErrorResponse(w, "Invalid file path provided", http.StatusBadRequest)
+		return "", "", false
+	}
+	return cleanPath, id, true
+}
+
+// Helper methods for the handler
+
+// sendJobAccepted answers 202 Accepted with a background job and where to poll it
+func (h *FileHandler) sendJobAccepted(w http.ResponseWriter, job *jobs.Job) {
+	w.Header().Set("Location", "/file/jobs?id="+job.ID)
+	h.sendJSONResponse(w, jobs.JobResponse{
+		Success:     true,
+		Job:         *job,
+		RequestTime: time.Now(),
+	}, http.StatusAccepted)
+}
+
+// sendJSONResponse sends a JSON response with proper headers
+func (h *FileHandler) sendJSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// sendErrorResponse sends an error response with proper format
+func (h *FileHandler) sendErrorResponse(w http.ResponseWriter, message string, statusCode int) {
+	errorResponse := map[string]interface{}{
+		"success": false,
+		"error":   message,
+		"code":    statusCode,
+	}
+	h.sendJSONResponse(w, errorResponse, statusCode)
+}
+
+// handleServiceError handles errors from the service layer
+func (h *FileHandler) handleServiceError(w http.ResponseWriter, err error) {
+	class := classifyServiceError(err)
+	metrics.ServiceErrors.WithLabelValues(class).Inc()
+
+	// Edits of a file that changed underneath get their own message so clients can reload
+	if errors.Is(err, ErrContentChanged) {
+		h.sendErrorResponse(w, "File was modified since it was loaded", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, fs.ErrNoXattr) {
+		h.sendErrorResponse(w, "Extended attribute not found", http.StatusNotFound)
+		return
+	}
+
+	switch class {
+	case errorClassNotFound:
+		h.sendErrorResponse(w, "File or directory not found", http.StatusNotFound)
+	case errorClassAccessDenied:
+		h.sendErrorResponse(w, "Access denied", http.StatusForbidden)
+	case errorClassInvalidPath:
+		h.sendErrorResponse(w, "Invalid path provided", http.StatusBadRequest)
+	case errorClassConflict:
+		h.sendErrorResponse(w, "File already exists", http.StatusConflict)
+	case errorClassTooLarge:
+		h.sendErrorResponse(w, "File too large", http.StatusRequestEntityTooLarge)
+	case errorClassUnsupported:
+		h.sendErrorResponse(w, "Not supported on this storage", http.StatusBadRequest)
+	case errorClassBusy:
+		h.sendErrorResponse(w, "Too many background jobs, try again later", http.StatusServiceUnavailable)
+	default:
+		h.sendErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// Service error classifications, also used as metric labels
+const (
+	errorClassNotFound     = "not_found"
+	errorClassAccessDenied = "access_denied"
+	errorClassInvalidPath  = "invalid_path"
+	errorClassConflict     = "conflict"
+	errorClassTooLarge     = "too_large"
+	errorClassUnsupported  = "unsupported"
+	errorClassBusy         = "busy"
+	errorClassInternal     = "internal"
+)
+
+// classifyServiceError maps a service error to one of the error classifications
+func classifyServiceError(err error) string {
+	if errors.Is(err, ErrContentChanged) {
+		return errorClassConflict
+	} else if errors.Is(err, jobs.ErrQueueFull) || errors.Is(err, jobs.ErrClosed) {
+		return errorClassBusy
+	} else if errors.Is(err, fs.ErrXattrUnsupported) || errors.Is(err, fs.ErrChmodUnsupported) ||
+		errors.Is(err, fs.ErrChownUnsupported) || errors.Is(err, fs.ErrSymlinkUnsupported) ||
+		errors.Is(err, fs.ErrHardLinkUnsupported) {
+		return errorClassUnsupported
+	} else if strings.Contains(err.Error(), "no such file") || strings.Contains(err.Error(), "not found") {
+		return errorClassNotFound
+	} else if strings.Contains(err.Error(), "access denied") || strings.Contains(err.Error(), "permission denied") {
+		return errorClassAccessDenied
+	} else if strings.Contains(err.Error(), "invalid path") {
+		return errorClassInvalidPath
+	} else if strings.Contains(err.Error(), "already exists") {
+		return errorClassConflict
+	} else if strings.Contains(err.Error(), "too large") {
+		return errorClassTooLarge
+	}
+	return errorClassInternal
+}
+
+// withMiddleware adds CORS handling to the handler; request IDs and access
+// logging are applied by logging.Middleware at the router level
+func (h *FileHandler) withMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Add CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+
+		// Handle preflight requests
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// Call next handler
+		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,322 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/versions"
+)
+
+// CreateDirectory creates a new directory; its parent must already exist
+func (s *FileService) CreateDirectory(ctx context.Context, dirPath string) (err error) {
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpMkdir, dirPath, "", 0, err))
+	}()
+
+	// Validate the path
+	cleanPath, err := s.validatePath(dirPath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+	if cleanPath == "/" {
+		return fmt.Errorf("directory already exists: %s", dirPath)
+	}
+
+	if err := s.backend.Mkdir(cleanPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	s.recordChange(ctx, changes.TypeCreate, cleanPath, "")
+
+	logging.FromContext(ctx).Info("created directory", "path", dirPath)
+	return nil
+}
+
+// MoveFile renames or moves a file or directory; the target must not exist
+func (s *FileService) MoveFile(ctx context.Context, sourcePath, targetPath string) (err error) {
+	var bytes int64
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpMove, sourcePath, targetPath, bytes, err))
+	}()
+
+	// Validate both paths
+	cleanSource, err := s.validatePath(sourcePath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+	cleanTarget, err := s.validatePath(targetPath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+
+	if cleanSource == "/" || cleanTarget == "/" {
+		return fmt.Errorf("invalid path: cannot move the root directory")
+	}
+
+	// Check source exists and target is free
+	if !fs.Exists(s.backend, cleanSource) {
+		return fmt.Errorf("file or directory not found: %s", sourcePath)
+	}
+	if fs.Exists(s.backend, cleanTarget) {
+		return fmt.Errorf("target already exists: %s", targetPath)
+	}
+
+	// A directory cannot be moved into itself
+	if strings.HasPrefix(cleanTarget, cleanSource+"/") {
+		return fmt.Errorf("invalid path: cannot move a directory into itself")
+	}
+
+	bytes, _ = fs.DiskUsage(s.backend, cleanSource)
+
+	if err := s.backend.Rename(cleanSource, cleanTarget); err != nil {
+		return fmt.Errorf("failed to rename: %w", err)
+	}
+	s.checksums.invalidate(cleanSource)
+	s.checksums.invalidate(cleanTarget)
+	s.recordChange(ctx, changes.TypeRename, cleanTarget, cleanSource)
+
+	logging.FromContext(ctx).Info("moved path", "source", sourcePath, "target", targetPath)
+	return nil
+}
+
+// CopyFile copies a file or directory tree; the target must not exist
+func (s *FileService) CopyFile(ctx context.Context, sourcePath, targetPath string) (err error) {
+	var bytes int64
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpCopy, sourcePath, targetPath, bytes, err))
+	}()
+
+	// Validate both paths
+	cleanSource, err := s.validatePath(sourcePath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+	cleanTarget, err := s.validatePath(targetPath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+
+	if cleanTarget == "/" {
+		return fmt.Errorf("invalid path: cannot copy onto the root directory")
+	}
+
+	// Check source exists and target is free
+	if !fs.Exists(s.backend, cleanSource) {
+		return fmt.Errorf("file or directory not found: %s", sourcePath)
+	}
+	if fs.Exists(s.backend, cleanTarget) {
+		return fmt.Errorf("target already exists: %s", targetPath)
+	}
+
+	// A directory cannot be copied into itself
+	if cleanSource == "/" || strings.HasPrefix(cleanTarget, cleanSource+"/") {
+		return fmt.Errorf("invalid path: cannot copy a directory into itself")
+	}
+
+	bytes, err = fs.Copy(ctx, s.backend, cleanSource, cleanTarget)
+	if err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+	s.recordChange(ctx, changes.TypeCreate, cleanTarget, "")
+
+	logging.FromContext(ctx).Info("copied path", "source", sourcePath, "target", targetPath, "bytes", bytes)
+	return nil
+}
+
+// ParseMode parses an octal permission string such as 755 or 0644, including
+// the setuid, setgid and sticky bits
+func ParseMode(value string) (os.FileMode, error) {
+	bits, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || bits > 07777 {
+		return 0, fmt.Errorf("invalid mode %q, expected octal permissions such as 0644", value)
+	}
+
+	mode := os.FileMode(bits) & os.ModePerm
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+// Stat returns file information for a path inside the base path
+func (s *FileService) Stat(ctx context.Context, filePath string) (os.FileInfo, error) {
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	info, err := s.backend.Stat(cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	return info, nil
+}
+
+// OpenHandle opens a file inside the base path; handles opened for writing
+// are recorded in the audit log when closed
+func (s *FileService) OpenHandle(ctx context.Context, filePath string, flag int, perm os.FileMode) (File, error) {
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+	if writable && cleanPath == "/" {
+		return nil, fmt.Errorf("invalid path: cannot write the root directory")
+	}
+
+	// Truncating an existing file overwrites it; keep its content first
+	if flag&os.O_TRUNC != 0 {
+		if err := s.keepVersion(ctx, cleanPath, versions.ReasonOverwrite); err != nil {
+			s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpWrite, filePath, "", 0, err))
+			return nil, err
+		}
+	}
+
+	changeType := changes.TypeModify
+	if writable && !fs.Exists(s.backend, cleanPath) {
+		changeType = changes.TypeCreate
+	}
+
+	file, err := s.backend.OpenFile(cleanPath, flag, perm)
+	if err != nil {
+		err = fmt.Errorf("failed to open file: %w", err)
+	}
+	if writable {
+		if err != nil {
+			s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpWrite, filePath, "", 0, err))
+			return nil, err
+		}
+		return &auditedFile{file: file, ctx: ctx, path: filePath, recorder: s.audit, changed: func() {
+			s.recordChange(ctx, changeType, cleanPath, "")
+		}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// WriteFile streams content into a file, creating or replacing it. Nothing
+// is changed until the content is complete and verify, when given, accepts
+// it, so a failed or rejected write keeps the existing file intact.
+func (s *FileService) WriteFile(ctx context.Context, filePath string, content io.Reader, verify func() error) (err error) {
+	var written int64
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpWrite, filePath, "", written, err))
+	}()
+
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+	if cleanPath == "/" {
+		return fmt.Errorf("invalid path: cannot write the root directory")
+	}
+
+	changeType := changes.TypeModify
+	if !fs.Exists(s.backend, cleanPath) {
+		changeType = changes.TypeCreate
+	}
+
+	// The previous content is only kept once the new one is accepted. The
+	// content streams without the save lock, which is held from then on until
+	// the rename, so an editor save cannot pass its check and be overwritten.
+	locked := false
+	written, err = fs.WriteAtomic(s.backend, cleanPath, content, func() error {
+		if verify != nil {
+			if err := verify(); err != nil {
+				return err
+			}
+		}
+		s.saveMu.Lock()
+		locked = true
+		return s.keepVersion(ctx, cleanPath, versions.ReasonOverwrite)
+	})
+	if locked {
+		s.saveMu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	s.checksums.invalidate(cleanPath)
+	s.recordChange(ctx, changeType, cleanPath, "")
+
+	logging.FromContext(ctx).Info("wrote file", "path", filePath, "bytes", written)
+	return nil
+}
+
+// auditedFile records the bytes written through a handle when it is closed.
+// It wraps rather than embeds the backend file so WriteString and ReadFrom
+// cannot bypass the byte count.
+type auditedFile struct {
+	file     fs.File
+	ctx      context.Context
+	path     string
+	recorder audit.Recorder
+	written  int64
+	writeErr error
+	// changed journals the change after a successful close
+	changed func()
+}
+
+// Read reads from the underlying file
+func (f *auditedFile) Read(p []byte) (int, error) {
+	return f.file.Read(p)
+}
+
+// Seek seeks the underlying file
+func (f *auditedFile) Seek(offset int64, whence int) (int64, error) {
+	return f.file.Seek(offset, whence)
+}
+
+// Readdir lists the underlying directory
+func (f *auditedFile) Readdir(count int) ([]os.FileInfo, error) {
+	return f.file.Readdir(count)
+}
+
+// Stat returns file information for the underlying file
+func (f *auditedFile) Stat() (os.FileInfo, error) {
+	return f.file.Stat()
+}
+
+// Write counts bytes written through the handle
+func (f *auditedFile) Write(p []byte) (int, error) {
+	n, err := f.file.Write(p)
+	f.written += int64(n)
+	if err != nil && f.writeErr == nil {
+		f.writeErr = err
+	}
+	return n, err
+}
+
+// Close closes the handle and records the write
+func (f *auditedFile) Close() error {
+	err := f.file.Close()
+
+	recordErr := f.writeErr
+	if recordErr == nil {
+		recordErr = err
+	}
+	f.recorder.Record(f.ctx, audit.NewEntry(f.ctx, audit.OpWrite, f.path, "", f.written, recordErr))
+	if recordErr == nil && f.changed != nil {
+		f.changed()
+	}
+	return err
+}
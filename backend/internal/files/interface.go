@@ -1,12 +1,64 @@
 package files
 
-import "net/http"
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/watch"
+)
 
 // FileServiceInterface defines the contract for file service operations
 type FileServiceInterface interface {
-	ListFiles(path string) (*FileListResponse, error)
-	GetFileDetails(path string) (*FileDetailsResponse, error)
-	DeleteFile(path string) error
-	OpenFile(path string) (*FileContentResponse, error)
-	ServeRawFile(w http.ResponseWriter, path string) error
-}
\ No newline at end of file
+	ListFiles(ctx context.Context, path string) (*FileListResponse, error)
+	GetFileDetails(ctx context.Context, path string) (*FileDetailsResponse, error)
+	RealPath(ctx context.Context, path string) (string, error)
+	DeleteFile(ctx context.Context, path string) error
+	OpenFile(ctx context.Context, path string) (*FileContentResponse, error)
+	ServeRawFile(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) error
+	UploadFile(ctx context.Context, dirPath, name string, content io.Reader, opts UploadOptions) (*FileDetailsResponse, error)
+	CreateDirectory(ctx context.Context, path string) error
+	MoveFile(ctx context.Context, sourcePath, targetPath string) error
+	SaveFileContent(ctx context.Context, path, content string, opts SaveOptions) (*FileDetailsResponse, error)
+	CopyFile(ctx context.Context, sourcePath, targetPath string) error
+	RunBatch(ctx context.Context, ops []BatchOperation, opts BatchOptions) (*jobs.Job, error)
+	SetPermissions(ctx context.Context, path string, mode os.FileMode, recursive bool) (*FileDetailsResponse, *jobs.Job, error)
+	SetOwner(ctx context.Context, path string, uid, gid int, recursive bool) (*FileDetailsResponse, *jobs.Job, error)
+	ListXattrs(ctx context.Context, path string) (*XattrsResponse, error)
+	GetXattr(ctx context.Context, path, name string) (*XattrResponse, error)
+	SetXattr(ctx context.Context, path, name string, value []byte) error
+	RemoveXattr(ctx context.Context, path, name string) error
+	CreateLink(ctx context.Context, linkType, target, path string) (*FileDetailsResponse, error)
+
+	// Version history of overwritten files
+	ListVersions(ctx context.Context, path string) (*FileVersionsResponse, error)
+	OpenVersion(ctx context.Context, path, id string) (*FileContentResponse, error)
+	ServeVersion(ctx context.Context, w http.ResponseWriter, r *http.Request, path, id string) error
+	DiffVersion(ctx context.Context, path, id string) (*FileVersionDiffResponse, error)
+	RestoreVersion(ctx context.Context, path, id string) (*FileDetailsResponse, error)
+
+	// Checksums, computed in a background job for large files
+	ChecksumFile(ctx context.Context, path string, algos []string) (*FileChecksumResponse, *jobs.Job, error)
+	VerifyManifest(ctx context.Context, path, algo string) (*jobs.Job, error)
+	CreateManifest(ctx context.Context, dirPath string, opts ManifestOptions) (*jobs.Job, error)
+
+	// Duplicate files
+	ScanDuplicates(ctx context.Context, dirPath string, minSize int64) (*jobs.Job, error)
+	ListDuplicates(ctx context.Context, dirPath string, offset, limit int) (*DuplicatesResponse, error)
+	ResolveDuplicates(ctx context.Context, dirPath, groupID, keep, action string, files []string) (*DuplicatesResolveResponse, error)
+
+	// Live changes of a directory, and the journal of all changes
+	WatchDirectory(ctx context.Context, path, lastEventID string) (*watch.Subscription, error)
+	ListChanges(ctx context.Context, cursor, path string, limit int) (*ChangesResponse, error)
+
+	// Low-level access for protocol frontends (WebDAV, ...)
+	Stat(ctx context.Context, path string) (os.FileInfo, error)
+	OpenHandle(ctx context.Context, path string, flag int, perm os.FileMode) (File, error)
+	WriteFile(ctx context.Context, path string, content io.Reader, verify func() error) error
+}
+
+// File is an open file handle on the service's storage backend
+type File = fs.File
@@ -0,0 +1,114 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// Link types accepted by CreateLink
+const (
+	LinkTypeSymlink = "symlink"
+	LinkTypeHard    = "hard"
+)
+
+// CreateLink creates linkPath as a symbolic or hard link to targetPath. A
+// relative target is resolved against the directory of the link, and both
+// must stay inside the base directory. Hard links can only point to regular
+// files.
+func (s *FileService) CreateLink(ctx context.Context, linkType, targetPath, linkPath string) (result *FileDetailsResponse, err error) {
+	op := audit.OpLink
+	if linkType == LinkTypeSymlink {
+		op = audit.OpSymlink
+	}
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, op, linkPath, targetPath, 0, err))
+	}()
+
+	if linkType != LinkTypeSymlink && linkType != LinkTypeHard {
+		return nil, fmt.Errorf("invalid link type: %s", linkType)
+	}
+
+	// Validate both paths
+	cleanLink, err := s.validatePath(linkPath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	if cleanLink == "/" {
+		return nil, fmt.Errorf("invalid path: cannot replace the root directory")
+	}
+	resolved, ok := resolveLinkTarget(cleanLink, targetPath)
+	if !ok {
+		return nil, fmt.Errorf("invalid path: access denied - link target escapes base directory")
+	}
+	cleanTarget, err := s.validatePath(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	// Check the target exists and the link location is free
+	targetInfo, err := fs.Lstat(s.backend, cleanTarget)
+	if err != nil {
+		return nil, fmt.Errorf("link target not found: %s", targetPath)
+	}
+	if _, err := fs.Lstat(s.backend, cleanLink); err == nil {
+		return nil, fmt.Errorf("link path already exists: %s", linkPath)
+	}
+	if !fs.IsDirectory(s.backend, path.Dir(cleanLink)) {
+		return nil, fmt.Errorf("directory not found: %s", path.Dir(cleanLink))
+	}
+
+	if linkType == LinkTypeHard {
+		// Another link to a symbolic link could point elsewhere from its new directory
+		if !targetInfo.Mode().IsRegular() {
+			return nil, fmt.Errorf("invalid path: only regular files can be hard linked")
+		}
+		err = fs.Link(s.backend, cleanTarget, cleanLink)
+	} else {
+		err = fs.Symlink(s.backend, cleanTarget, cleanLink)
+	}
+	if errors.Is(err, syscall.EXDEV) {
+		return nil, fmt.Errorf("invalid path: cannot link across mounts or file systems")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create link: %w", err)
+	}
+	s.recordChange(ctx, changes.TypeCreate, cleanLink, "")
+
+	logging.FromContext(ctx).Info("created link", "type", linkType, "path", linkPath, "target", cleanTarget)
+	return s.GetFileDetails(ctx, cleanLink)
+}
+
+// resolveLinkTarget returns the path a link at cleanLink to target points to;
+// ok is false when a relative target climbs above the base directory
+func resolveLinkTarget(cleanLink, target string) (resolved string, ok bool) {
+	if path.IsAbs(target) {
+		return target, true
+	}
+
+	dir := path.Dir(cleanLink)
+	depth := 0
+	if dir != "/" {
+		depth = strings.Count(dir, "/")
+	}
+	for _, segment := range strings.Split(target, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if depth--; depth < 0 {
+				return "", false
+			}
+		default:
+			depth++
+		}
+	}
+	return path.Join(dir, target), true
+}
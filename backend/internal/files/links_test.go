@@ -0,0 +1,35 @@
+package files
+
+import "testing"
+
+func TestResolveLinkTarget(t *testing.T) {
+	tests := []struct {
+		name   string
+		link   string
+		target string
+		want   string
+		wantOK bool
+	}{
+		{name: "sibling", link: "/docs/link", target: "report.pdf", want: "/docs/report.pdf", wantOK: true},
+		{name: "dot segments", link: "/docs/link", target: "./sub/../report.pdf", want: "/docs/report.pdf", wantOK: true},
+		{name: "up to the root", link: "/docs/link", target: "../report.pdf", want: "/report.pdf", wantOK: true},
+		{name: "up from a deep directory", link: "/a/b/c/link", target: "../../../file", want: "/file", wantOK: true},
+		{name: "down then up", link: "/a/link", target: "b/../../file", want: "/file", wantOK: true},
+		{name: "repeated slashes", link: "/a/link", target: "b//file", want: "/a/b/file", wantOK: true},
+		{name: "absolute", link: "/a/link", target: "/b/file", want: "/b/file", wantOK: true},
+		{name: "above the root from the root", link: "/link", target: "../file", wantOK: false},
+		{name: "above the root from a directory", link: "/docs/link", target: "../../etc/passwd", wantOK: false},
+		{name: "above the root from a deep directory", link: "/a/b/c/link", target: "../../../../file", wantOK: false},
+		{name: "above the root and back down", link: "/docs/link", target: "../../docs/file", wantOK: false},
+		{name: "above the root after going down", link: "/a/link", target: "b/../../../file", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := resolveLinkTarget(tt.link, tt.target)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Fatalf("resolveLinkTarget(%q, %q) = %q, %v, want %q, %v", tt.link, tt.target, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,514 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/versions"
+)
+
+// Manifest formats
+const (
+	// ManifestFormatGNU is the "<hex>  <name>" format of sha256sum and friends
+	ManifestFormatGNU = "gnu"
+	// ManifestFormatBSD is the "SHA256 (<name>) = <hex>" format of --tag and BSD tools
+	ManifestFormatBSD = "bsd"
+)
+
+// Verification results of a manifest entry
+const (
+	ManifestEntryOK       = "ok"
+	ManifestEntryMismatch = "mismatch"
+	ManifestEntryMissing  = "missing"
+	ManifestEntryError    = "error"
+)
+
+// bsdTags maps algorithms to the tags of the BSD format
+var bsdTags = map[string]string{
+	"md5":     "MD5",
+	"sha1":    "SHA1",
+	"sha256":  "SHA256",
+	"sha512":  "SHA512",
+	"blake2b": "BLAKE2b",
+	"crc32":   "CRC32",
+}
+
+// hexLengthAlgorithms identifies the algorithm of a GNU format line by its digest length
+var hexLengthAlgorithms = map[int]string{
+	8:  "crc32",
+	32: "md5",
+	40: "sha1",
+	64: "sha256",
+}
+
+// ambiguousAlgorithms are the algorithms sharing a digest length; a GNU line
+// with such a digest is told apart by the manifest name, or else by trying each
+var ambiguousAlgorithms = map[int][]string{
+	128: {"sha512", "blake2b"},
+}
+
+// ManifestOptions control how a checksum manifest is created
+type ManifestOptions struct {
+	Algo      string
+	Name      string
+	Format    string
+	Recursive bool
+	Overwrite bool
+}
+
+// manifestEntry is one parsed line of a manifest; candidates holds the
+// possible algorithms when the digest length alone does not tell
+type manifestEntry struct {
+	name       string
+	algo       string
+	candidates []string
+	expected   string
+}
+
+// VerifyManifest starts a job checking every file listed in a checksum
+// manifest, relative to the manifest's directory. algo overrides the
+// algorithm of GNU format lines, which is otherwise told by digest length and,
+// for 128 digit SHA-512 or BLAKE2b digests, by the manifest name.
+func (s *FileService) VerifyManifest(ctx context.Context, manifestPath, algo string) (*jobs.Job, error) {
+	// Validate the path
+	cleanPath, err := s.validatePath(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	info, err := s.backend.Stat(cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("invalid path: manifest is a directory: %s", manifestPath)
+	}
+	if info.Size() > maxEditableSize {
+		return nil, fmt.Errorf("manifest too large: %d bytes (max: %d bytes)", info.Size(), maxEditableSize)
+	}
+
+	entries, malformed, err := s.readManifest(cleanPath, algo)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := jobs.Default.Start(ctx, "verify-manifest", manifestPath, func(ctx context.Context, report jobs.ReportFunc) (interface{}, error) {
+		return s.verifyManifest(ctx, manifestPath, path.Dir(cleanPath), entries, malformed, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// verifyManifest hashes each manifest entry and compares it with the expected digest
+func (s *FileService) verifyManifest(ctx context.Context, manifestPath, dir string, entries []manifestEntry, malformed int, report jobs.ReportFunc) (*ManifestVerifyResponse, error) {
+	result := &ManifestVerifyResponse{
+		Success:   true,
+		Manifest:  manifestPath,
+		Entries:   make([]ManifestEntryResult, 0, len(entries)),
+		Total:     len(entries),
+		Malformed: malformed,
+	}
+
+	for i, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entryResult := s.verifyManifestEntry(ctx, dir, entry)
+		switch entryResult.Status {
+		case ManifestEntryOK:
+			result.OK++
+		case ManifestEntryMismatch:
+			result.Mismatch++
+		case ManifestEntryMissing:
+			result.Missing++
+		default:
+			result.Failed++
+		}
+		result.Entries = append(result.Entries, entryResult)
+		report(int64(i+1), int64(len(entries)))
+	}
+
+	logging.FromContext(ctx).Info("verified checksum manifest", "path", manifestPath,
+		"ok", result.OK, "mismatch", result.Mismatch, "missing", result.Missing, "failed", result.Failed)
+	result.RequestTime = time.Now()
+	return result, nil
+}
+
+// verifyManifestEntry checks a single manifest entry
+func (s *FileService) verifyManifestEntry(ctx context.Context, dir string, entry manifestEntry) ManifestEntryResult {
+	result := ManifestEntryResult{
+		Name:     entry.name,
+		Path:     path.Join(dir, entry.name),
+		Algo:     entry.algo,
+		Expected: entry.expected,
+	}
+
+	algos := entry.candidates
+	if entry.algo != "" {
+		algos = []string{entry.algo}
+	}
+	for _, algo := range algos {
+		if _, ok := checksumAlgorithms[algo]; !ok {
+			result.Status = ManifestEntryError
+			result.Error = fmt.Sprintf("unsupported checksum algorithm %q", algo)
+			return result
+		}
+	}
+
+	cleanPath, err := s.validatePath(result.Path)
+	if err != nil {
+		result.Status = ManifestEntryError
+		result.Error = "invalid path"
+		return result
+	}
+
+	info, err := s.backend.Stat(cleanPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result.Status = ManifestEntryMissing
+		} else {
+			result.Status = ManifestEntryError
+			result.Error = err.Error()
+		}
+		return result
+	}
+	if info.IsDir() {
+		result.Status = ManifestEntryError
+		result.Error = "is a directory"
+		return result
+	}
+
+	sums, _, err := s.sumFile(ctx, cleanPath, info, algos, nil)
+	if err != nil {
+		result.Status = ManifestEntryError
+		result.Error = err.Error()
+		return result
+	}
+
+	// An ambiguous digest is reported under the first candidate unless another matches
+	result.Algo, result.Actual, result.Status = algos[0], sums[algos[0]], ManifestEntryMismatch
+	for _, algo := range algos {
+		if sums[algo] == entry.expected {
+			result.Algo, result.Actual, result.Status = algo, sums[algo], ManifestEntryOK
+			break
+		}
+	}
+	return result
+}
+
+// readManifest parses a manifest in GNU or BSD format, skipping blank and
+// comment lines and counting lines in neither format
+func (s *FileService) readManifest(cleanPath, algo string) ([]manifestEntry, int, error) {
+	file, err := s.backend.OpenFile(cleanPath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer file.Close()
+
+	var entries []manifestEntry
+	malformed := 0
+	nameAlgo := manifestNameAlgorithm(path.Base(cleanPath))
+	scanner := bufio.NewScanner(io.LimitReader(file, maxEditableSize))
+	scanner.Buffer(make([]byte, 64*1024), maxEditableSize)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, ok := parseManifestLine(line, algo, nameAlgo)
+		if !ok {
+			malformed++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, 0, fmt.Errorf("invalid path: no checksum lines found in %s", cleanPath)
+	}
+	return entries, malformed, nil
+}
+
+// parseManifestLine parses one line in BSD or GNU format. A leading
+// backslash marks a name with escaped backslashes and newlines. nameAlgo,
+// guessed from the manifest name, settles digests of an ambiguous length.
+func parseManifestLine(line, algo, nameAlgo string) (manifestEntry, bool) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+
+	var entry manifestEntry
+	if hexPart, rest, ok := strings.Cut(line, " "); ok && isHex(hexPart) {
+		// GNU: hex, a space, then a space (text) or '*' (binary) before the name
+		if rest != "" && (rest[0] == ' ' || rest[0] == '*') {
+			rest = rest[1:]
+		}
+		entry.name, entry.expected = rest, hexPart
+		entry.algo = algo
+		if entry.algo == "" {
+			entry.algo = hexLengthAlgorithms[len(hexPart)]
+		}
+		if candidates := ambiguousAlgorithms[len(hexPart)]; entry.algo == "" && candidates != nil {
+			entry.candidates = candidates
+			for _, candidate := range candidates {
+				if candidate == nameAlgo {
+					entry.algo = nameAlgo
+				}
+			}
+		}
+	} else if tag, rest, ok := strings.Cut(line, " ("); ok {
+		// BSD: TAG (name) = hex
+		sep := strings.LastIndex(rest, ") = ")
+		if sep < 0 {
+			return entry, false
+		}
+		entry.name, entry.expected = rest[:sep], rest[sep+len(") = "):]
+		entry.algo = strings.ToLower(tag)
+		for name, bsdTag := range bsdTags {
+			if strings.EqualFold(tag, bsdTag) {
+				entry.algo = name
+			}
+		}
+	} else {
+		return entry, false
+	}
+
+	entry.expected = strings.ToLower(entry.expected)
+	if entry.name == "" || (entry.algo == "" && entry.candidates == nil) || !isHex(entry.expected) {
+		return entry, false
+	}
+	if escaped {
+		entry.name = unescapeManifestName(entry.name)
+	}
+	return entry, true
+}
+
+// CreateManifest starts a job writing a checksum manifest for the files of a
+// directory into that directory
+func (s *FileService) CreateManifest(ctx context.Context, dirPath string, opts ManifestOptions) (*jobs.Job, error) {
+	// Validate the path
+	cleanDir, err := s.validatePath(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	if !fs.IsDirectory(s.backend, cleanDir) {
+		return nil, fmt.Errorf("path is not a directory or does not exist: %s", dirPath)
+	}
+
+	if opts.Algo == "" {
+		opts.Algo = defaultChecksumAlgorithm
+	}
+	if _, ok := checksumAlgorithms[opts.Algo]; !ok {
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", opts.Algo)
+	}
+	if opts.Format == "" {
+		opts.Format = ManifestFormatGNU
+	}
+	if opts.Name == "" {
+		opts.Name = defaultManifestName(opts.Algo)
+	}
+	if opts.Format != ManifestFormatGNU && opts.Format != ManifestFormatBSD {
+		return nil, fmt.Errorf("unsupported manifest format %q", opts.Format)
+	}
+	name, err := sanitizeFileName(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	manifestPath := path.Join(cleanDir, name)
+	if fs.Exists(s.backend, manifestPath) && !opts.Overwrite {
+		return nil, fmt.Errorf("manifest already exists: %s", manifestPath)
+	}
+
+	job, err := jobs.Default.Start(ctx, "create-manifest", dirPath, func(ctx context.Context, report jobs.ReportFunc) (interface{}, error) {
+		return s.createManifest(ctx, cleanDir, manifestPath, opts, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// createManifest hashes the files below dir and writes the manifest
+func (s *FileService) createManifest(ctx context.Context, dir, manifestPath string, opts ManifestOptions, report jobs.ReportFunc) (result *ManifestCreateResponse, err error) {
+	var written int64
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpWrite, manifestPath, "", written, err))
+	}()
+
+	names, err := s.manifestFiles(dir, "", manifestPath, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest strings.Builder
+	for i, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		cleanPath := path.Join(dir, name)
+		info, err := s.backend.Stat(cleanPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file info: %w", err)
+		}
+		sums, _, err := s.sumFile(ctx, cleanPath, info, []string{opts.Algo}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum %s: %w", cleanPath, err)
+		}
+		manifest.WriteString(formatManifestLine(opts.Format, opts.Algo, name, sums[opts.Algo]))
+		report(int64(i+1), int64(len(names)))
+	}
+
+	content := []byte(manifest.String())
+	changeType := changes.TypeCreate
+	if fs.Exists(s.backend, manifestPath) {
+		changeType = changes.TypeModify
+		if !opts.Overwrite {
+			return nil, fmt.Errorf("manifest already exists: %s", manifestPath)
+		}
+		if err := s.keepVersion(ctx, manifestPath, versions.ReasonOverwrite); err != nil {
+			return nil, err
+		}
+		if _, err := fs.WriteAtomic(s.backend, manifestPath, bytes.NewReader(content), nil); err != nil {
+			return nil, fmt.Errorf("failed to write manifest: %w", err)
+		}
+	} else {
+		file, err := fs.CreateFile(s.backend, manifestPath, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write manifest: %w", err)
+		}
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+	written = int64(len(content))
+	s.recordChange(ctx, changeType, manifestPath, "")
+
+	logging.FromContext(ctx).Info("created checksum manifest", "path", manifestPath, "files", len(names))
+	return &ManifestCreateResponse{
+		Success:     true,
+		Manifest:    manifestPath,
+		Algo:        opts.Algo,
+		Format:      opts.Format,
+		Files:       len(names),
+		RequestTime: time.Now(),
+	}, nil
+}
+
+// manifestFiles lists the regular files below dir as names relative to the
+// manifest directory, sorted, leaving out the manifest itself
+func (s *FileService) manifestFiles(root, rel, manifestPath string, recursive bool) ([]string, error) {
+	entries, err := s.backend.ReadDir(path.Join(root, rel))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var names []string
+	for _, entry := range entries {
+		name := path.Join(rel, entry.Name())
+		switch {
+		case entry.IsDir() && recursive:
+			children, err := s.manifestFiles(root, name, manifestPath, recursive)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, children...)
+		case entry.Mode().IsRegular() && path.Join(root, name) != manifestPath:
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// defaultManifestName names manifests the way coreutils users do: SHA256SUMS, MD5SUMS, B2SUMS, ...
+func defaultManifestName(algo string) string {
+	if algo == "blake2b" {
+		return "B2SUMS"
+	}
+	return strings.ToUpper(algo) + "SUMS"
+}
+
+// manifestNameAlgorithm guesses the algorithm of a manifest from its name,
+// such as SHA512SUMS, B2SUMS or image.sha512; it returns "" when unsure
+func manifestNameAlgorithm(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "sha512"):
+		return "sha512"
+	case strings.HasPrefix(name, "b2"), strings.Contains(name, "blake2"), strings.HasSuffix(name, ".b2"):
+		return "blake2b"
+	}
+	return ""
+}
+
+// formatManifestLine formats one manifest line, escaping names the way GNU tools do
+func formatManifestLine(format, algo, name, sum string) string {
+	prefix := ""
+	if strings.ContainsAny(name, "\\\n\r") {
+		prefix = "\\"
+		name = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(name)
+	}
+	if format == ManifestFormatBSD {
+		return fmt.Sprintf("%s%s (%s) = %s\n", prefix, bsdTags[algo], name, sum)
+	}
+	return fmt.Sprintf("%s%s  %s\n", prefix, sum, name)
+}
+
+// unescapeManifestName reverses the escaping of formatManifestLine
+func unescapeManifestName(name string) string {
+	var out strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '\\' || i+1 == len(name) {
+			out.WriteByte(name[i])
+			continue
+		}
+		i++
+		switch name[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		default:
+			out.WriteByte(name[i])
+		}
+	}
+	return out.String()
+}
+
+// isHex reports whether s is a non-empty hex string
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
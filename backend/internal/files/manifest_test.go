@@ -0,0 +1,73 @@
+package files
+
+import (
+	"context"
+	"testing"
+)
+
+// Digests of "hello world\n" as printed by sha512sum and b2sum
+const (
+	helloSHA512  = "db3974a97f2407b7cae1ae637c0030687a11913274d578492558e39c16c017de84eacdc8c62fe34ee4e12b4b1428817f09b6a2760c3f8a664ceae94d2434a593"
+	helloBLAKE2b = "fec91c70284c72d0d4e3684788a90de9338a5b2f47f01fedbe203cafd68708718ae5672d10eca804a8121904047d40d1d6cf11e7a76419357a9469af41f22d01"
+)
+
+func TestVerifyManifest128DigitAlgorithms(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		content  string
+		algo     string
+		wantAlgo string
+		want     string
+	}{
+		{name: "sha512sum manifest", manifest: "SHA512SUMS", content: helloSHA512 + "  h.txt\n", wantAlgo: "sha512", want: ManifestEntryOK},
+		{name: "b2sum manifest", manifest: "B2SUMS", content: helloBLAKE2b + "  h.txt\n", wantAlgo: "blake2b", want: ManifestEntryOK},
+		{name: "sha512 digest in a BLAKE2b manifest", manifest: "B2SUMS", content: helloSHA512 + "  h.txt\n", wantAlgo: "blake2b", want: ManifestEntryMismatch},
+		{name: "unnamed sha512 manifest", manifest: "CHECKSUMS", content: helloSHA512 + " *h.txt\n", wantAlgo: "sha512", want: ManifestEntryOK},
+		{name: "unnamed blake2b manifest", manifest: "CHECKSUMS", content: helloBLAKE2b + "  h.txt\n", wantAlgo: "blake2b", want: ManifestEntryOK},
+		{name: "algo parameter wins", manifest: "SHA512SUMS", content: helloBLAKE2b + "  h.txt\n", algo: "blake2b", wantAlgo: "blake2b", want: ManifestEntryOK},
+		{name: "bsd tag", manifest: "CHECKSUMS", content: "SHA512 (h.txt) = " + helloSHA512 + "\n", wantAlgo: "sha512", want: ManifestEntryOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t, map[string]string{"/data/h.txt": "hello world\n", "/data/" + tt.manifest: tt.content})
+			manifestPath := "/data/" + tt.manifest
+
+			entries, malformed, err := s.readManifest(manifestPath, tt.algo)
+			if err != nil {
+				t.Fatalf("readManifest() error = %v", err)
+			}
+			if malformed != 0 || len(entries) != 1 {
+				t.Fatalf("readManifest() = %d entries, %d malformed, want 1 entry", len(entries), malformed)
+			}
+
+			result, err := s.verifyManifest(context.Background(), manifestPath, "/data", entries, malformed, func(done, total int64) {})
+			if err != nil {
+				t.Fatalf("verifyManifest() error = %v", err)
+			}
+			entry := result.Entries[0]
+			if entry.Status != tt.want || entry.Algo != tt.wantAlgo {
+				t.Fatalf("entry = %s with %s (%s), want %s with %s", entry.Status, entry.Algo, entry.Error, tt.want, tt.wantAlgo)
+			}
+		})
+	}
+}
+
+func TestManifestNameAlgorithm(t *testing.T) {
+	tests := map[string]string{
+		"SHA512SUMS":       "sha512",
+		"debian.sha512":    "sha512",
+		"B2SUMS":           "blake2b",
+		"release.b2":       "blake2b",
+		"BLAKE2SUMS":       "blake2b",
+		"SHA256SUMS":       "",
+		"checksums.txt":    "",
+		"sha512sum.txt.gz": "sha512",
+	}
+	for name, want := range tests {
+		if got := manifestNameAlgorithm(name); got != want {
+			t.Errorf("manifestNameAlgorithm(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
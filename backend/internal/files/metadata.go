@@ -0,0 +1,158 @@
+package files
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+)
+
+// File types reported in FileItem.FileType
+const (
+	FileTypeFile    = "file"
+	FileTypeDir     = "dir"
+	FileTypeSymlink = "symlink"
+	FileTypeSocket  = "socket"
+	FileTypeFIFO    = "fifo"
+	FileTypeDevice  = "device"
+)
+
+// fileType classifies a file by its mode, without following symbolic links
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return FileTypeSymlink
+	case mode.IsDir():
+		return FileTypeDir
+	case mode&os.ModeSocket != 0:
+		return FileTypeSocket
+	case mode&os.ModeNamedPipe != 0:
+		return FileTypeFIFO
+	case mode&os.ModeDevice != 0:
+		return FileTypeDevice
+	}
+	return FileTypeFile
+}
+
+// FileMetadata is the Unix metadata shared by listings and details. Fields
+// are left out where the storage or platform does not record them.
+type FileMetadata struct {
+	UID        *uint32    `json:"uid,omitempty"`
+	GID        *uint32    `json:"gid,omitempty"`
+	Owner      string     `json:"owner,omitempty"`
+	Group      string     `json:"group,omitempty"`
+	Inode      uint64     `json:"inode,omitempty"`
+	Links      uint64     `json:"links,omitempty"`
+	Device     uint64     `json:"device,omitempty"`
+	AccessTime *time.Time `json:"accessTime,omitempty"`
+	ChangeTime *time.Time `json:"changeTime,omitempty"`
+	BirthTime  *time.Time `json:"birthTime,omitempty"`
+	// LinkTarget is where a symbolic link points: a relative target as stored
+	// in the link, or an absolute one as an API path. LinkOutside is set
+	// instead for links pointing outside the base directory.
+	LinkTarget  string `json:"linkTarget,omitempty"`
+	LinkOutside bool   `json:"linkOutside,omitempty"`
+	BrokenLink  bool   `json:"brokenLink,omitempty"`
+}
+
+// newFileMetadata returns the metadata recorded in info
+func newFileMetadata(info os.FileInfo) FileMetadata {
+	var result FileMetadata
+	meta, ok := fs.MetadataOf(info)
+	if !ok {
+		return result
+	}
+
+	uid, gid := meta.UID, meta.GID
+	result.UID = &uid
+	result.GID = &gid
+	result.Owner = owners.userName(uid)
+	result.Group = owners.groupName(gid)
+	result.Inode = meta.Inode
+	result.Links = meta.Links
+	result.Device = meta.Device
+	result.AccessTime = timeOrNil(meta.AccessTime)
+	result.ChangeTime = timeOrNil(meta.ChangeTime)
+	result.BirthTime = timeOrNil(meta.BirthTime)
+	return result
+}
+
+// fileMetadata describes the file at cleanPath, adding what info does not
+// record: the birth time on Linux and where a symbolic link points. info must
+// not follow a final symbolic link so links are reported as such.
+func (s *FileService) fileMetadata(cleanPath string, info os.FileInfo) FileMetadata {
+	result := newFileMetadata(info)
+	if result.UID != nil && result.BirthTime == nil {
+		result.BirthTime = timeOrNil(fs.BirthTime(s.backend, cleanPath))
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, inside, err := fs.LinkTarget(s.backend, cleanPath)
+		if err == nil {
+			result.LinkTarget, result.LinkOutside = target, !inside
+		}
+		_, err = s.backend.Stat(cleanPath)
+		result.BrokenLink = errors.Is(err, os.ErrNotExist)
+	}
+	return result
+}
+
+// timeOrNil returns nil for zero times so they are left out
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// owners resolves the user and group names of listed files
+var owners = newOwnerNames()
+
+// ownerNames caches user and group names by ID, since listings look up the
+// same few IDs for every entry
+type ownerNames struct {
+	mu     sync.Mutex
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+func newOwnerNames() *ownerNames {
+	return &ownerNames{
+		users:  make(map[uint32]string),
+		groups: make(map[uint32]string),
+	}
+}
+
+// userName returns the name of a user ID, or "" when it has none
+func (o *ownerNames) userName(uid uint32) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	name, cached := o.users[uid]
+	if !cached {
+		if found, err := user.LookupId(strconv.FormatUint(uint64(uid), 10)); err == nil {
+			name = found.Username
+		}
+		o.users[uid] = name
+	}
+	return name
+}
+
+// groupName returns the name of a group ID, or "" when it has none
+func (o *ownerNames) groupName(gid uint32) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	name, cached := o.groups[gid]
+	if !cached {
+		if found, err := user.LookupGroupId(strconv.FormatUint(uint64(gid), 10)); err == nil {
+			name = found.Name
+		}
+		o.groups[gid] = name
+	}
+	return name
+}
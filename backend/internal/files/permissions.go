@@ -0,0 +1,186 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// attributeChange describes a chmod or chown applied to a path or tree
+type attributeChange struct {
+	// op is the audit operation, also used as the job kind
+	op string
+	// target is recorded in the audit log: the mode or the new owner
+	target string
+	// description is used in errors and logs, such as "change mode"
+	description string
+	apply       fs.WalkFunc
+}
+
+// SetPermissions sets the mode of a file or directory and, with recursive,
+// of everything below it. Symbolic links below the path are not followed.
+// Recursive changes run in a background job, which is returned instead of
+// the updated details.
+func (s *FileService) SetPermissions(ctx context.Context, filePath string, mode os.FileMode, recursive bool) (*FileDetailsResponse, *jobs.Job, error) {
+	return s.changeAttributes(ctx, filePath, recursive, attributeChange{
+		op:          audit.OpChmod,
+		target:      mode.String(),
+		description: "change mode",
+		apply: func(name string, info os.FileInfo) error {
+			// Links have no permissions of their own
+			if info.Mode()&os.ModeSymlink != 0 {
+				return nil
+			}
+			return fs.Chmod(s.backend, name, mode)
+		},
+	})
+}
+
+// SetOwner sets the owner and group of a file or directory and, with
+// recursive, of everything below it; -1 keeps the current owner or group.
+// Symbolic links are changed themselves, not what they point to. Recursive
+// changes run in a background job, which is returned instead of the updated
+// details.
+func (s *FileService) SetOwner(ctx context.Context, filePath string, uid, gid int, recursive bool) (*FileDetailsResponse, *jobs.Job, error) {
+	return s.changeAttributes(ctx, filePath, recursive, attributeChange{
+		op:          audit.OpChown,
+		target:      fmt.Sprintf("%d:%d", uid, gid),
+		description: "change owner",
+		apply: func(name string, _ os.FileInfo) error {
+			return fs.Lchown(s.backend, name, uid, gid)
+		},
+	})
+}
+
+// checkAttributeAccess rejects permission and ownership changes by non-admins
+func checkAttributeAccess(ctx context.Context, description string) error {
+	if auth.FromContext(ctx).IsAdmin() {
+		return nil
+	}
+	return fmt.Errorf("access denied: only admins can %s", description)
+}
+
+// changeAttributes checks the caller and path and applies change directly or,
+// with recursive, in a background job
+func (s *FileService) changeAttributes(ctx context.Context, filePath string, recursive bool, change attributeChange) (*FileDetailsResponse, *jobs.Job, error) {
+	if err := checkAttributeAccess(ctx, change.description); err != nil {
+		s.audit.Record(ctx, audit.NewEntry(ctx, change.op, filePath, change.target, 0, err))
+		return nil, nil, err
+	}
+
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	if !fs.Exists(s.backend, cleanPath) {
+		return nil, nil, fmt.Errorf("file or directory not found: %s", filePath)
+	}
+
+	// Remote storage has no Unix permissions or owners
+	if _, ok := fs.LocalPath(s.backend, cleanPath); !ok {
+		return nil, nil, fmt.Errorf("invalid path: cannot %s on remote storage", change.description)
+	}
+
+	if !recursive {
+		if err := s.applyAttributes(ctx, filePath, cleanPath, false, change, nil); err != nil {
+			return nil, nil, err
+		}
+		details, err := s.GetFileDetails(ctx, filePath)
+		return details, nil, err
+	}
+
+	job, err := jobs.Default.Start(ctx, change.op, filePath, func(ctx context.Context, report jobs.ReportFunc) (interface{}, error) {
+		if err := s.applyAttributes(ctx, filePath, cleanPath, true, change, report); err != nil {
+			return nil, err
+		}
+		return s.GetFileDetails(ctx, filePath)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, &job, nil
+}
+
+// applyAttributes applies change to cleanPath and, with recursive, to
+// everything below it, stopping at the first failure. A recursive change
+// walks the tree twice, first only counting entries so progress has a total.
+// Like moves and copies, the change is audited and journaled once, as a
+// modify of cleanPath, rather than once per entry below it.
+func (s *FileService) applyAttributes(ctx context.Context, filePath, cleanPath string, recursive bool, change attributeChange, report jobs.ReportFunc) (err error) {
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, change.op, filePath, change.target, 0, err))
+	}()
+
+	if !recursive {
+		info, err := s.backend.Stat(cleanPath)
+		if err == nil {
+			err = change.apply(cleanPath, info)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", change.description, err)
+		}
+	} else {
+		var total, done int64
+		err := fs.Walk(ctx, s.backend, cleanPath, func(string, os.FileInfo) error {
+			total++
+			return nil
+		})
+		if err == nil {
+			err = fs.Walk(ctx, s.backend, cleanPath, func(name string, info os.FileInfo) error {
+				if err := change.apply(name, info); err != nil {
+					return err
+				}
+				done++
+				report(done, total)
+				return nil
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", change.description, err)
+		}
+	}
+	s.recordChange(ctx, changes.TypeModify, cleanPath, "")
+
+	logging.FromContext(ctx).Info("changed attributes", "operation", change.op, "path", filePath,
+		"value", change.target, "recursive", recursive)
+	return nil
+}
+
+// ParseOwner resolves a user and group given as names or numeric IDs on this
+// server; an empty value keeps the current one and resolves to -1
+func ParseOwner(userName, groupName string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if userName != "" {
+		if uid, err = strconv.Atoi(userName); err != nil {
+			found, lookupErr := user.Lookup(userName)
+			if lookupErr != nil {
+				return 0, 0, fmt.Errorf("unknown user %q", userName)
+			}
+			uid, _ = strconv.Atoi(found.Uid)
+		} else if uid < 0 {
+			return 0, 0, fmt.Errorf("invalid user id %d", uid)
+		}
+	}
+	if groupName != "" {
+		if gid, err = strconv.Atoi(groupName); err != nil {
+			found, lookupErr := user.LookupGroup(groupName)
+			if lookupErr != nil {
+				return 0, 0, fmt.Errorf("unknown group %q", groupName)
+			}
+			gid, _ = strconv.Atoi(found.Gid)
+		} else if gid < 0 {
+			return 0, 0, fmt.Errorf("invalid group id %d", gid)
+		}
+	}
+	return uid, gid, nil
+}
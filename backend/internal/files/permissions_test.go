@@ -0,0 +1,107 @@
+package files
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+)
+
+// fileMode returns the permission bits of a local file
+func fileMode(t *testing.T, localPath string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestChmodRequiresAdmin(t *testing.T) {
+	user := auth.Identity{Username: "bob", Role: auth.RoleUser}
+	tests := []struct {
+		name   string
+		target string
+		body   string
+	}{
+		{name: "chmod", target: "/chmod?path=/file.txt&mode=0600"},
+		{name: "chown", target: "/chown?path=/file.txt&user=0"},
+		{name: "batch chmod", target: "/batch", body: `{"operations":[{"op":"chmod","path":"/file.txt","mode":"0600"}]}`},
+		{name: "batch chmod after other operations", target: "/batch", body: `{"operations":[{"op":"mkdir","path":"/dir"},{"op":"chmod","path":"/file.txt","mode":"0600"}]}`},
+		{name: "batch chmod dry run", target: "/batch", body: `{"dryRun":true,"operations":[{"op":"chmod","path":"/file.txt","mode":"0600"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, root := newTestService(t, map[string]string{"/file.txt": "data"})
+			localPath := filepath.Join(root, "file.txt")
+
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			r = r.WithContext(auth.WithIdentity(r.Context(), user))
+			w := httptest.NewRecorder()
+			NewHandler(s).ServeHTTP(w, r)
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body.String())
+			}
+			if mode := fileMode(t, localPath); mode != 0644 {
+				t.Fatalf("mode = %o, want 644", mode)
+			}
+			if _, err := os.Stat(filepath.Join(root, "dir")); !os.IsNotExist(err) {
+				t.Fatalf("a refused batch ran its other operations: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetPermissionsChecksCaller(t *testing.T) {
+	s, root := newTestService(t, map[string]string{"/file.txt": "data"})
+	localPath := filepath.Join(root, "file.txt")
+
+	user := auth.WithIdentity(context.Background(), auth.Identity{Username: "bob", Role: auth.RoleUser})
+	if _, _, err := s.SetPermissions(user, "/file.txt", 0600, false); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Fatalf("SetPermissions() as a user error = %v, want access denied", err)
+	}
+	if _, _, err := s.SetOwner(user, "/file.txt", -1, -1, false); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Fatalf("SetOwner() as a user error = %v, want access denied", err)
+	}
+
+	admin := auth.WithIdentity(context.Background(), auth.Identity{Username: "alice", Role: auth.RoleAdmin})
+	details, _, err := s.SetPermissions(admin, "/file.txt", 0600, false)
+	if err != nil {
+		t.Fatalf("SetPermissions() as an admin error = %v", err)
+	}
+	if mode := fileMode(t, localPath); mode != 0600 || details == nil {
+		t.Fatalf("mode = %o, details = %v, want 600 and the updated details", mode, details)
+	}
+
+	// Batches run chmod through SetPermissions
+	if err := s.runBatchOperation(admin, BatchOperation{Op: BatchChmod, Path: "/file.txt", Mode: "0640"}); err != nil {
+		t.Fatalf("runBatchOperation() error = %v", err)
+	}
+	if mode := fileMode(t, localPath); mode != 0640 {
+		t.Fatalf("mode = %o, want 640", mode)
+	}
+}
+
+func TestChmodRejectsRemoteStorage(t *testing.T) {
+	backend := fs.NewMemFS()
+	file, err := backend.OpenFile("/file.txt", os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	s := NewFileServiceWithBackend(backend)
+
+	admin := auth.WithIdentity(context.Background(), auth.Identity{Username: "alice", Role: auth.RoleAdmin})
+	err = s.runBatchOperation(admin, BatchOperation{Op: BatchChmod, Path: "/file.txt", Mode: "0600"})
+	if err == nil || !strings.Contains(err.Error(), "remote storage") {
+		t.Fatalf("runBatchOperation() error = %v, want a remote storage error", err)
+	}
+}
@@ -1,52 +1,88 @@
 package files
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/duplicates"
 	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/versions"
+	"github.com/BomScoob12/homelab-file-manager/internal/watch"
 )
 
-// FileService implements file management operations
+// FileService implements file management operations on a storage backend
 type FileService struct {
-	basePath string
-	fsUtils  fs.FileSystemInterface
+	backend  fs.FileSystemInterface
+	audit    audit.Recorder
+	versions *versions.Store
+
+	// checksums caches file checksums; files larger than checksumJobThreshold
+	// are hashed in background jobs
+	checksums            *checksumCache
+	checksumJobThreshold int64
+
+	// duplicates keeps the latest duplicate scan of each directory
+	duplicates *duplicates.Store
+
+	// watcher delivers change events of watched directories
+	watcher *watch.Hub
+
+	// changes journals every change for sync clients
+	changes *changes.Journal
+
+	// saveMu serializes the version check of SaveFileContent with every
+	// replace of existing content: saves, restores and streamed writes
+	saveMu sync.Mutex
 }
 
-// NewFileService creates a new file service instance
+// NewFileService creates a new file service instance over the local base path
 func NewFileService() *FileService {
-	// Get base path from environment variable, with fallback
-	basePath := os.Getenv("FILE_MANAGER_BASE_PATH")
-	if basePath == "" {
-		// Default to Docker mount point
-		basePath = "/data"
-	}
+	return NewFileServiceWithBackend(fs.NewLocalFS(config.BasePath()))
+}
 
+// NewFileServiceWithBackend creates a file service over any storage backend
+func NewFileServiceWithBackend(backend fs.FileSystemInterface) *FileService {
 	return &FileService{
-		basePath: basePath,
-		fsUtils:  fs.NewFileSystemUtils(),
+		backend:  backend,
+		audit:    audit.Default,
+		versions: versions.Default,
+
+		checksums:            newChecksumCache(),
+		checksumJobThreshold: checksumJobThreshold(),
+
+		duplicates: duplicates.Default,
+		watcher:    watch.Default,
+		changes:    changes.Default,
 	}
 }
 
 // ListFiles lists all files and directories in the specified path
-func (s *FileService) ListFiles(path string) (*FileListResponse, error) {
-	// Validate and construct full path
-	fullPath, err := s.validateAndConstructPath(path)
+func (s *FileService) ListFiles(ctx context.Context, path string) (*FileListResponse, error) {
+	// Validate the path
+	dirPath, err := s.validatePath(path)
 	if err != nil {
 		return nil, fmt.Errorf("path validation failed: %w", err)
 	}
 
 	// Check if directory exists and is accessible
-	if !s.fsUtils.IsDirectory(fullPath) {
+	if !fs.IsDirectory(s.backend, dirPath) {
 		return nil, fmt.Errorf("path is not a directory or does not exist: %s", path)
 	}
 
 	// List directory contents
-	entries, err := s.fsUtils.ListDirectory(fullPath)
+	entries, err := s.backend.ReadDir(dirPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list directory: %w", err)
 	}
@@ -55,34 +91,9 @@ func (s *FileService) ListFiles(path string) (*FileListResponse, error) {
 	var fileItems []FileItem
 	var totalSize int64
 
-	for _, entry := range entries {
-		info, err := entry.Info()
-		if err != nil {
-			continue // Skip files we can't get info for
-		}
-
-		itemPath := filepath.Join(path, entry.Name())
-		fullItemPath := filepath.Join(fullPath, entry.Name())
-		
-		var mimeType string
-		if entry.IsDir() {
-			mimeType = "inode/directory"
-		} else {
-			mimeType = getMimeType(fullItemPath)
-		}
-		
-		fileItem := FileItem{
-			Name:        entry.Name(),
-			Path:        itemPath,
-			IsDir:       entry.IsDir(),
-			FileType:    entry.Type().String(),
-			Size:        info.Size(),
-			ModTime:     info.ModTime(),
-			Permissions: info.Mode().String(),
-			Extension:   filepath.Ext(entry.Name()),
-			MimeType:    mimeType,
-		}
-
+	for _, info := range entries {
+		fileItem := newFileItem(filepath.Join(path, info.Name()), info,
+			s.fileMetadata(filepath.Join(dirPath, info.Name()), info))
 		fileItems = append(fileItems, fileItem)
 		totalSize += info.Size()
 	}
@@ -97,45 +108,116 @@ func (s *FileService) ListFiles(path string) (*FileListResponse, error) {
 	}, nil
 }
 
+// newFileItem describes a directory entry at itemPath; info must not follow
+// symbolic links
+func newFileItem(itemPath string, info os.FileInfo, meta FileMetadata) FileItem {
+	var mimeType string
+	if info.IsDir() {
+		mimeType = "inode/directory"
+	} else {
+		mimeType = getMimeType(info.Name())
+	}
+
+	return FileItem{
+		Name:         info.Name(),
+		Path:         itemPath,
+		IsDir:        info.IsDir(),
+		FileType:     fileType(info.Mode()),
+		Size:         info.Size(),
+		ModTime:      info.ModTime(),
+		Permissions:  info.Mode().String(),
+		Extension:    filepath.Ext(info.Name()),
+		MimeType:     mimeType,
+		FileMetadata: meta,
+	}
+}
+
 // GetFileDetails gets detailed information about a specific file or directory
-func (s *FileService) GetFileDetails(filePath string) (*FileDetailsResponse, error) {
-	// Validate and construct full path
-	fullPath, err := s.validateAndConstructPath(filePath)
+func (s *FileService) GetFileDetails(ctx context.Context, filePath string) (*FileDetailsResponse, error) {
+	// Validate the path
+	targetPath, err := s.validatePath(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("path validation failed: %w", err)
 	}
 
-	// Get file information
-	info, err := s.fsUtils.GetFileInfo(fullPath)
+	// Get file information; symbolic links are followed, but broken ones are
+	// still described
+	linkInfo, linkErr := fs.Lstat(s.backend, targetPath)
+	info, err := s.backend.Stat(targetPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+		if linkErr != nil || linkInfo.Mode()&os.ModeSymlink == 0 {
+			return nil, fmt.Errorf("failed to get file info: %w", err)
+		}
+		info = linkInfo
+	}
+	if linkErr != nil {
+		linkInfo = info
+	}
+
+	// Files on remote backends have no location on disk
+	fullPath, ok := fs.LocalPath(s.backend, targetPath)
+	if !ok {
+		fullPath = targetPath
+	}
+
+	var checksums map[string]string
+	if !info.IsDir() {
+		checksums = s.checksums.get(targetPath, info)
+	}
+
+	// Storage without extended attributes simply has none to show
+	xattrs, _ := s.readXattrs(ctx, targetPath)
+	if len(xattrs) == 0 {
+		xattrs = nil
 	}
 
 	return &FileDetailsResponse{
-		Success:     true,
-		Name:        info.Name(),
-		Path:        filePath,
-		FullPath:    fullPath,
-		IsDir:       info.IsDir(),
-		Size:        info.Size(),
-		ModTime:     info.ModTime(),
-		MimeType:    getMimeType(fullPath),
-		Permissions: info.Mode().String(),
-		Extension:   filepath.Ext(info.Name()),
-		RequestTime: time.Now(),
+		Success:      true,
+		Name:         info.Name(),
+		Path:         filePath,
+		FullPath:     fullPath,
+		IsDir:        info.IsDir(),
+		FileType:     fileType(linkInfo.Mode()),
+		Size:         info.Size(),
+		ModTime:      info.ModTime(),
+		MimeType:     getMimeType(targetPath),
+		Permissions:  info.Mode().String(),
+		Extension:    filepath.Ext(info.Name()),
+		ETag:         ETag(info.ModTime(), info.Size()),
+		Checksums:    checksums,
+		Xattrs:       xattrs,
+		FileMetadata: s.fileMetadata(targetPath, linkInfo),
+		RequestTime:  time.Now(),
 	}, nil
 }
 
+// RealPath returns the path filePath resolves to once every symbolic link
+// along it is followed. Paths whose links lead out of the base directory
+// are refused.
+func (s *FileService) RealPath(ctx context.Context, filePath string) (string, error) {
+	// Validate the path
+	targetPath, err := s.validatePath(filePath)
+	if err != nil {
+		return "", fmt.Errorf("path validation failed: %w", err)
+	}
+
+	real, err := fs.EvalSymlinks(s.backend, targetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	return real, nil
+}
+
 // OpenFile opens and reads the content of a file
-func (s *FileService) OpenFile(filePath string) (*FileContentResponse, error) {
-	// Validate and construct full path
-	fullPath, err := s.validateAndConstructPath(filePath)
+func (s *FileService) OpenFile(ctx context.Context, filePath string) (*FileContentResponse, error) {
+	// Validate the path
+	targetPath, err := s.validatePath(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("path validation failed: %w", err)
 	}
 
 	// Check if it's a file (not directory)
-	info, err := s.fsUtils.GetFileInfo(fullPath)
+	info, err := s.backend.Stat(targetPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
@@ -151,12 +233,12 @@ func (s *FileService) OpenFile(filePath string) (*FileContentResponse, error) {
 	}
 
 	// Read file content
-	content, err := s.fsUtils.ReadFileContent(fullPath)
+	content, err := fs.ReadFileContent(s.backend, targetPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file content: %w", err)
 	}
 
-	mimeType := getMimeType(fullPath)
+	mimeType := getMimeType(targetPath)
 	encoding := "utf-8"
 
 	// Check if it's a binary file
@@ -172,6 +254,8 @@ func (s *FileService) OpenFile(filePath string) (*FileContentResponse, error) {
 		Path:        filePath,
 		Content:     content,
 		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		ETag:        ETag(info.ModTime(), info.Size()),
 		MimeType:    mimeType,
 		Encoding:    encoding,
 		RequestTime: time.Now(),
@@ -179,37 +263,54 @@ func (s *FileService) OpenFile(filePath string) (*FileContentResponse, error) {
 }
 
 // DeleteFile deletes a file or directory
-func (s *FileService) DeleteFile(targetPath string) error {
-	// Validate and construct full path
-	fullPath, err := s.validateAndConstructPath(targetPath)
+func (s *FileService) DeleteFile(ctx context.Context, targetPath string) (err error) {
+	var bytes int64
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpDelete, targetPath, "", bytes, err))
+	}()
+
+	// Validate the path
+	cleanPath, err := s.validatePath(targetPath)
 	if err != nil {
 		return fmt.Errorf("path validation failed: %w", err)
 	}
 
 	// Check if file exists
-	if !s.fsUtils.Exists(fullPath) {
+	if !fs.Exists(s.backend, cleanPath) {
 		return fmt.Errorf("file or directory not found: %s", targetPath)
 	}
 
+	// Record how much data is about to be removed
+	bytes, err = fs.DiskUsage(s.backend, cleanPath)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to compute size before delete", "path", targetPath, "error", err)
+	}
+
 	// Delete the file or directory
-	err = s.fsUtils.Delete(fullPath)
+	isDir := fs.IsDirectory(s.backend, cleanPath)
+	err = fs.Delete(s.backend, cleanPath)
 	if err != nil {
 		return fmt.Errorf("failed to delete: %w", err)
 	}
+	s.checksums.invalidate(cleanPath)
+	s.recordDelete(ctx, cleanPath, isDir)
 
+	logging.FromContext(ctx).Info("deleted path", "path", targetPath)
 	return nil
 }
 
-// ServeRawFile serves raw file content directly (for images, PDFs, etc.)
-func (s *FileService) ServeRawFile(w http.ResponseWriter, filePath string) error {
-	// Validate and construct full path
-	fullPath, err := s.validateAndConstructPath(filePath)
+// ServeRawFile serves raw file content directly (for images, PDFs, etc.),
+// honouring Range and conditional request headers. A Cache-Control header
+// already set by the caller is kept.
+func (s *FileService) ServeRawFile(ctx context.Context, w http.ResponseWriter, r *http.Request, filePath string) error {
+	// Validate the path
+	targetPath, err := s.validatePath(filePath)
 	if err != nil {
 		return fmt.Errorf("path validation failed: %w", err)
 	}
 
 	// Check if file exists and is not a directory
-	info, err := s.fsUtils.GetFileInfo(fullPath)
+	info, err := s.backend.Stat(targetPath)
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
@@ -219,31 +320,111 @@ func (s *FileService) ServeRawFile(w http.ResponseWriter, filePath string) error
 	}
 
 	// Open the file
-	file, err := os.Open(fullPath)
+	file, err := s.backend.OpenFile(targetPath, os.O_RDONLY, 0)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
 	// Set appropriate headers
-	mimeType := getMimeType(fullPath)
+	mimeType := getMimeType(targetPath)
 	w.Header().Set("Content-Type", mimeType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
-	
-	// Set cache headers for better performance
-	w.Header().Set("Cache-Control", "public, max-age=3600")
-	w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+
+	// Set cache headers for better performance, unless the caller chose its own
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
 
 	// For downloads, set Content-Disposition header
 	if !isInlineMimeType(mimeType) {
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", info.Name()))
 	}
 
-	// Copy file content to response
-	_, err = io.Copy(w, file)
+	// ServeContent handles Content-Length, Last-Modified, Range and If-* headers
+	rw := logging.NewResponseWriter(w)
+	http.ServeContent(rw, r, info.Name(), info.ModTime(), file)
+
+	logging.FromContext(ctx).Debug("served raw file", "path", filePath, "bytes", rw.BytesWritten(), "status", rw.Status())
+
+	return nil
+}
+
+// maxAutoRenameAttempts bounds the search for a free name when auto renaming
+const maxAutoRenameAttempts = 1000
+
+// UploadFile stores content as a new file named name inside dirPath
+func (s *FileService) UploadFile(ctx context.Context, dirPath, name string, content io.Reader, opts UploadOptions) (result *FileDetailsResponse, err error) {
+	var written int64
+	targetPath := filepath.Join(dirPath, name)
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpUpload, targetPath, "", written, err))
+	}()
+
+	// Validate the path
+	cleanDir, err := s.validatePath(dirPath)
 	if err != nil {
-		return fmt.Errorf("failed to serve file content: %w", err)
+		return nil, fmt.Errorf("path validation failed: %w", err)
 	}
 
-	return nil
+	// Check if target directory exists
+	if !fs.IsDirectory(s.backend, cleanDir) {
+		return nil, fmt.Errorf("target directory not found: %s", dirPath)
+	}
+
+	name, err = sanitizeFileName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the file, picking a free name when auto renaming
+	var file fs.File
+	finalName := name
+	for attempt := 1; ; attempt++ {
+		file, err = fs.CreateFile(s.backend, path.Join(cleanDir, finalName), true)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create file: %w", err)
+		}
+		if !opts.AutoRename {
+			return nil, fmt.Errorf("file already exists: %s", filepath.Join(dirPath, finalName))
+		}
+		if attempt > maxAutoRenameAttempts {
+			return nil, fmt.Errorf("no free file name for %s", name)
+		}
+		finalName = numberedFileName(name, attempt)
+	}
+
+	targetPath = filepath.Join(dirPath, finalName)
+	cleanPath := path.Join(cleanDir, finalName)
+
+	// Copy content, reading one extra byte to detect oversized uploads
+	reader := content
+	if opts.MaxBytes > 0 {
+		reader = io.LimitReader(content, opts.MaxBytes+1)
+	}
+	written, err = io.Copy(file, reader)
+	closeErr := file.Close()
+
+	if err == nil && opts.MaxBytes > 0 && written > opts.MaxBytes {
+		err = fmt.Errorf("file too large: more than %d bytes", opts.MaxBytes)
+	} else if err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write file: %w", closeErr)
+	} else if err != nil {
+		err = fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err != nil {
+		// Never leave partial uploads behind
+		if removeErr := fs.Delete(s.backend, cleanPath); removeErr != nil {
+			logging.FromContext(ctx).Error("failed to remove partial upload", "path", targetPath, "error", removeErr)
+		}
+		return nil, err
+	}
+
+	s.recordChange(ctx, changes.TypeCreate, cleanPath, "")
+
+	logging.FromContext(ctx).Info("uploaded file", "path", targetPath, "bytes", written)
+	return s.GetFileDetails(ctx, targetPath)
 }
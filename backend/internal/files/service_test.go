@@ -0,0 +1,16 @@
+package files
+
+import (
+	"testing"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/filestest"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+)
+
+// newTestService returns a service over a temporary directory laid out by
+// filestest.Tree, and the directory
+func newTestService(t *testing.T, tree map[string]string) (*FileService, string) {
+	t.Helper()
+	root := filestest.Tree(t, tree)
+	return NewFileServiceWithBackend(fs.NewLocalFS(root)), root
+}
@@ -1,6 +1,12 @@
 package files
 
-import "time"
+import (
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/duplicates"
+	"github.com/BomScoob12/homelab-file-manager/internal/versions"
+)
 
 // Response models
 type FileItem struct {
@@ -13,6 +19,7 @@ type FileItem struct {
 	Permissions string    `json:"permissions"`
 	Extension   string    `json:"extension,omitempty"`
 	MimeType    string    `json:"mimeType,omitempty"`
+	FileMetadata
 }
 
 type FileListResponse struct {
@@ -25,16 +32,22 @@ type FileListResponse struct {
 }
 
 type FileDetailsResponse struct {
-	Success     bool      `json:"success"`
-	Name        string    `json:"name"`
-	Path        string    `json:"path"`
-	FullPath    string    `json:"fullPath"`
-	IsDir       bool      `json:"isDir"`
-	Size        int64     `json:"size"`
-	ModTime     time.Time `json:"modTime"`
-	MimeType    string    `json:"mimeType"`
-	Permissions string    `json:"permissions"`
-	Extension   string    `json:"extension,omitempty"`
+	Success     bool              `json:"success"`
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	FullPath    string            `json:"fullPath"`
+	IsDir       bool              `json:"isDir"`
+	FileType    string            `json:"fileType"`
+	Size        int64             `json:"size"`
+	ModTime     time.Time         `json:"modTime"`
+	MimeType    string            `json:"mimeType"`
+	Permissions string            `json:"permissions"`
+	Extension   string            `json:"extension,omitempty"`
+	ETag        string            `json:"etag"`
+	Checksums   map[string]string `json:"checksums,omitempty"`
+	// Xattrs holds the extended attributes the caller may read, with base64 values
+	Xattrs map[string]string `json:"xattrs,omitempty"`
+	FileMetadata
 	RequestTime time.Time `json:"requestTime"`
 }
 
@@ -44,7 +57,173 @@ type FileContentResponse struct {
 	Path        string    `json:"path"`
 	Content     string    `json:"content"`
 	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"modTime"`
+	ETag        string    `json:"etag,omitempty"`
 	MimeType    string    `json:"mimeType"`
 	Encoding    string    `json:"encoding"`
 	RequestTime time.Time `json:"requestTime"`
-}
\ No newline at end of file
+}
+
+type FileVersionsResponse struct {
+	Success       bool               `json:"success"`
+	Path          string             `json:"path"`
+	Versions      []versions.Version `json:"versions"`
+	TotalVersions int                `json:"totalVersions"`
+	RequestTime   time.Time          `json:"requestTime"`
+}
+
+type FileVersionDiffResponse struct {
+	Success     bool      `json:"success"`
+	Path        string    `json:"path"`
+	VersionID   string    `json:"versionId"`
+	Identical   bool      `json:"identical"`
+	Binary      bool      `json:"binary,omitempty"`
+	Diff        string    `json:"diff"`
+	RequestTime time.Time `json:"requestTime"`
+}
+
+// UploadOptions controls how UploadFile stores new content
+type UploadOptions struct {
+	// AutoRename picks "name (1).ext", "name (2).ext", ... instead of failing when the name is taken
+	AutoRename bool
+	// MaxBytes rejects uploads larger than this many bytes; 0 means unlimited
+	MaxBytes int64
+}
+
+type FileChecksumResponse struct {
+	Success     bool              `json:"success"`
+	Path        string            `json:"path"`
+	Size        int64             `json:"size"`
+	ModTime     time.Time         `json:"modTime"`
+	Checksums   map[string]string `json:"checksums"`
+	Cached      bool              `json:"cached"`
+	RequestTime time.Time         `json:"requestTime"`
+}
+
+type ManifestEntryResult struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Algo     string `json:"algo"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ManifestVerifyResponse struct {
+	Success     bool                  `json:"success"`
+	Manifest    string                `json:"manifest"`
+	Entries     []ManifestEntryResult `json:"entries"`
+	Total       int                   `json:"total"`
+	OK          int                   `json:"ok"`
+	Mismatch    int                   `json:"mismatch"`
+	Missing     int                   `json:"missing"`
+	Failed      int                   `json:"failed"`
+	Malformed   int                   `json:"malformed"`
+	RequestTime time.Time             `json:"requestTime"`
+}
+
+type ManifestCreateResponse struct {
+	Success     bool      `json:"success"`
+	Manifest    string    `json:"manifest"`
+	Algo        string    `json:"algo"`
+	Format      string    `json:"format"`
+	Files       int       `json:"files"`
+	RequestTime time.Time `json:"requestTime"`
+}
+
+type DuplicatesResponse struct {
+	Success      bool               `json:"success"`
+	Path         string             `json:"path"`
+	StartedAt    time.Time          `json:"startedAt"`
+	FinishedAt   time.Time          `json:"finishedAt"`
+	StartedBy    string             `json:"startedBy"`
+	FilesScanned int                `json:"filesScanned"`
+	BytesScanned int64              `json:"bytesScanned"`
+	WastedBytes  int64              `json:"wastedBytes"`
+	TotalGroups  int                `json:"totalGroups"`
+	Offset       int                `json:"offset"`
+	Limit        int                `json:"limit"`
+	Groups       []duplicates.Group `json:"groups"`
+	RequestTime  time.Time          `json:"requestTime"`
+}
+
+type DuplicateResult struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type DuplicatesResolveResponse struct {
+	Success     bool              `json:"success"`
+	Path        string            `json:"path"`
+	Action      string            `json:"action"`
+	Kept        string            `json:"kept"`
+	Results     []DuplicateResult `json:"results"`
+	FreedBytes  int64             `json:"freedBytes"`
+	Group       duplicates.Group  `json:"group"`
+	RequestTime time.Time         `json:"requestTime"`
+}
+
+// WatchEvent is a change of a watched directory sent to /file/watch streams
+type WatchEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+	// From is the previous path of a renamed entry
+	From string `json:"from,omitempty"`
+	// Item is the entry after the change; it is omitted for deletes
+	Item *FileItem `json:"item,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// ChangesResponse is a page of the change journal returned by /file/changes
+type ChangesResponse struct {
+	Success bool            `json:"success"`
+	Events  []changes.Event `json:"events"`
+	// Cursor is passed as cursor to get the following changes
+	Cursor  string `json:"cursor"`
+	HasMore bool   `json:"hasMore"`
+	// Resync is set when changes after the given cursor are no longer kept
+	Resync      bool      `json:"resync"`
+	RequestTime time.Time `json:"requestTime"`
+}
+
+type BatchItemResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Path   string `json:"path"`
+	Target string `json:"target,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse is the result of a /file/batch job
+type BatchResponse struct {
+	Success   bool              `json:"success"`
+	DryRun    bool              `json:"dryRun"`
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Skipped   int               `json:"skipped"`
+	// Stopped is set when a failure skipped the remaining operations
+	Stopped     bool      `json:"stopped"`
+	RequestTime time.Time `json:"requestTime"`
+}
+
+// XattrsResponse lists the extended attributes of a file; values are base64
+type XattrsResponse struct {
+	Success     bool              `json:"success"`
+	Path        string            `json:"path"`
+	Xattrs      map[string]string `json:"xattrs"`
+	RequestTime time.Time         `json:"requestTime"`
+}
+
+// XattrResponse is one extended attribute of a file; the value is base64
+type XattrResponse struct {
+	Success     bool      `json:"success"`
+	Path        string    `json:"path"`
+	Name        string    `json:"name"`
+	Value       string    `json:"value"`
+	RequestTime time.Time `json:"requestTime"`
+}
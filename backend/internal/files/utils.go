@@ -2,43 +2,35 @@ package files
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
-)
-
-// validateAndConstructPath validates the path and constructs the full system path
-func (s *FileService) validateAndConstructPath(path string) (string, error) {
-	// Handle empty path as root
-	if path == "" {
-		return s.basePath, nil
-	}
-
-	// Handle root path
-	if path == "/" {
-		return s.basePath, nil
-	}
+	"time"
 
-	// Clean the path
-	cleanPath := filepath.Clean(path)
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+)
 
-	// If cleanPath is ".", it means we're at root
-	if cleanPath == "." {
-		return s.basePath, nil
+// validatePath validates a client path and returns its clean virtual form on the backend
+func (s *FileService) validatePath(filePath string) (string, error) {
+	// Handle empty path, root path and "." as root
+	cleanPath := path.Clean(filepath.ToSlash(filePath))
+	if filePath == "" || cleanPath == "/" || cleanPath == "." {
+		return "/", nil
 	}
 
-	// Construct full path
-	fullPath := filepath.Join(s.basePath, cleanPath)
-
-	// Clean both paths for comparison
-	cleanFullPath := filepath.Clean(fullPath)
-	cleanBasePath := filepath.Clean(s.basePath)
-
 	// Ensure the path doesn't escape the base directory
-	if !strings.HasPrefix(cleanFullPath, cleanBasePath) {
+	if cleanPath == ".." || strings.HasPrefix(cleanPath, "../") {
 		return "", fmt.Errorf("invalid path: access denied - path escapes base directory")
 	}
 
-	return cleanFullPath, nil
+	return fs.Clean(cleanPath), nil
+}
+
+// ETag builds a stable validator from modification time and size; it changes
+// whenever the file is rewritten
+func ETag(modTime time.Time, size int64) string {
+	return "\"" + strconv.FormatInt(modTime.UnixNano(), 16) + "-" + strconv.FormatInt(size, 10) + "\""
 }
 
 // isValidPath validates if the path is safe to use
@@ -62,4 +54,26 @@ func isValidPath(path string) bool {
 	}
 
 	return true
-}
\ No newline at end of file
+}
+
+// sanitizeFileName validates a client supplied file name for a new file
+func sanitizeFileName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	// Browsers on Windows may send the full client path
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("invalid path: invalid file name %q", name)
+	}
+	return name, nil
+}
+
+// numberedFileName returns "name (n).ext" for automatic renaming
+func numberedFileName(name string, n int) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	return fmt.Sprintf("%s (%d)%s", base, n, ext)
+}
@@ -0,0 +1,233 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/versions"
+)
+
+// keepVersion stores the current content of a file in the version history
+// before it is overwritten; missing files, directories and files over the
+// size limit are skipped
+func (s *FileService) keepVersion(ctx context.Context, cleanPath, reason string) error {
+	info, err := s.backend.Stat(cleanPath)
+	if err != nil || info.IsDir() || !s.versions.Accepts(info.Size()) {
+		return nil
+	}
+
+	file, err := s.backend.OpenFile(cleanPath, os.O_RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to keep previous version: %w", err)
+	}
+	defer file.Close()
+
+	if err := s.versions.Snapshot(ctx, cleanPath, file, info, reason); err != nil {
+		return fmt.Errorf("failed to keep previous version: %w", err)
+	}
+	return nil
+}
+
+// ListVersions lists the kept earlier versions of a file, newest first
+func (s *FileService) ListVersions(ctx context.Context, filePath string) (*FileVersionsResponse, error) {
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	list := s.versions.List(cleanPath)
+	return &FileVersionsResponse{
+		Success:       true,
+		Path:          filePath,
+		Versions:      list,
+		TotalVersions: len(list),
+		RequestTime:   time.Now(),
+	}, nil
+}
+
+// OpenVersion reads the content of an earlier version of a file
+func (s *FileService) OpenVersion(ctx context.Context, filePath, id string) (*FileContentResponse, error) {
+	version, content, err := s.readVersion(filePath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := getMimeType(filePath)
+	encoding := "utf-8"
+	if isBinaryMimeType(mimeType) {
+		encoding = "binary"
+	}
+
+	return &FileContentResponse{
+		Success:     true,
+		Name:        path.Base(filePath),
+		Path:        filePath,
+		Content:     string(content),
+		Size:        version.Size,
+		ModTime:     version.ModTime,
+		MimeType:    mimeType,
+		Encoding:    encoding,
+		RequestTime: time.Now(),
+	}, nil
+}
+
+// ServeVersion serves the raw content of an earlier version of a file
+func (s *FileService) ServeVersion(ctx context.Context, w http.ResponseWriter, r *http.Request, filePath, id string) error {
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+
+	version, err := s.versions.Get(cleanPath, id)
+	if err != nil {
+		return err
+	}
+	file, err := s.versions.Open(version)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	mimeType := getMimeType(cleanPath)
+	w.Header().Set("Content-Type", mimeType)
+	if !isInlineMimeType(mimeType) {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", path.Base(cleanPath)))
+	}
+	http.ServeContent(w, r, path.Base(cleanPath), version.ModTime, file)
+	return nil
+}
+
+// DiffVersion compares an earlier version of a file with its current content
+func (s *FileService) DiffVersion(ctx context.Context, filePath, id string) (*FileVersionDiffResponse, error) {
+	_, old, err := s.readVersion(filePath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A deleted file diffs against empty content
+	cleanPath, _ := s.validatePath(filePath)
+	var current []byte
+	if info, err := s.backend.Stat(cleanPath); err == nil {
+		if info.IsDir() {
+			return nil, fmt.Errorf("cannot diff directory: %s", filePath)
+		}
+		if info.Size() > maxEditableSize {
+			return nil, fmt.Errorf("file too large to diff: %d bytes (max: %d bytes)", info.Size(), maxEditableSize)
+		}
+		content, err := fs.ReadFileContent(s.backend, cleanPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file content: %w", err)
+		}
+		current = []byte(content)
+	}
+
+	result := &FileVersionDiffResponse{
+		Success:     true,
+		Path:        filePath,
+		VersionID:   id,
+		Identical:   bytes.Equal(old, current),
+		RequestTime: time.Now(),
+	}
+	if result.Identical {
+		return result, nil
+	}
+	if bytes.IndexByte(old, 0) >= 0 || bytes.IndexByte(current, 0) >= 0 {
+		result.Binary = true
+		return result, nil
+	}
+
+	result.Diff, err = versions.Diff(string(old), string(current), "a"+cleanPath+"@"+id, "b"+cleanPath)
+	if errors.Is(err, versions.ErrDiffTooLarge) {
+		return nil, fmt.Errorf("file too large to diff: %w", err)
+	}
+	return result, err
+}
+
+// RestoreVersion replaces a file with an earlier version; the content being
+// replaced is kept as a new version, so a restore can itself be undone
+func (s *FileService) RestoreVersion(ctx context.Context, filePath, id string) (result *FileDetailsResponse, err error) {
+	var written int64
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpRestore, filePath, id, written, err))
+	}()
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	_, content, err := s.readVersion(filePath, id)
+	if err != nil {
+		return nil, err
+	}
+	cleanPath, _ := s.validatePath(filePath)
+
+	info, statErr := s.backend.Stat(cleanPath)
+	changeType := changes.TypeModify
+	switch {
+	case statErr == nil && info.IsDir():
+		return nil, fmt.Errorf("target already exists as a directory: %s", filePath)
+	case statErr == nil:
+		if err := s.keepVersion(ctx, cleanPath, versions.ReasonRestore); err != nil {
+			return nil, err
+		}
+		if _, err := fs.WriteAtomic(s.backend, cleanPath, bytes.NewReader(content), nil); err != nil {
+			return nil, fmt.Errorf("failed to restore file: %w", err)
+		}
+	default:
+		// The file was deleted since; recreate it
+		changeType = changes.TypeCreate
+		file, err := fs.CreateFile(s.backend, cleanPath, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore file: %w", err)
+		}
+		_, err = io.Copy(file, bytes.NewReader(content))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore file: %w", err)
+		}
+	}
+	written = int64(len(content))
+	s.recordChange(ctx, changeType, cleanPath, "")
+
+	logging.FromContext(ctx).Info("restored file version", "path", filePath, "version", id)
+	return s.GetFileDetails(ctx, filePath)
+}
+
+// readVersion loads the content of an earlier version of a file
+func (s *FileService) readVersion(filePath, id string) (versions.Version, []byte, error) {
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return versions.Version{}, nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	version, err := s.versions.Get(cleanPath, id)
+	if err != nil {
+		return versions.Version{}, nil, err
+	}
+	file, err := s.versions.Open(version)
+	if err != nil {
+		return versions.Version{}, nil, err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return versions.Version{}, nil, fmt.Errorf("failed to read version content: %w", err)
+	}
+	return version, content, nil
+}
@@ -0,0 +1,89 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/versions"
+)
+
+// enableVersions gives s a version store of its own
+func enableVersions(t *testing.T, s *FileService) {
+	t.Helper()
+	store, err := versions.NewStore(t.TempDir(), 0, 0, maxEditableSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.versions = store
+}
+
+func TestVersionsKeepEditsAndRestore(t *testing.T) {
+	s, root := newTestService(t, map[string]string{"/notes.txt": "one\n"})
+	enableVersions(t, s)
+	ctx := context.Background()
+
+	if _, err := s.SaveFileContent(ctx, "/notes.txt", "two\n", SaveOptions{ETag: currentETag(t, s, "/notes.txt")}); err != nil {
+		t.Fatalf("SaveFileContent() error = %v", err)
+	}
+	if err := s.WriteFile(ctx, "/notes.txt", strings.NewReader("three\n"), nil); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	list, err := s.ListVersions(ctx, "/notes.txt")
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if list.TotalVersions != 2 || list.Versions[0].Reason != versions.ReasonOverwrite || list.Versions[1].Reason != versions.ReasonEdit {
+		t.Fatalf("ListVersions() = %+v, want the overwrite and the edit", list.Versions)
+	}
+	first := list.Versions[1].ID
+
+	old, err := s.OpenVersion(ctx, "/notes.txt", first)
+	if err != nil || old.Content != "one\n" {
+		t.Fatalf("OpenVersion() = %v, %v, want the original content", old, err)
+	}
+	diff, err := s.DiffVersion(ctx, "/notes.txt", first)
+	if err != nil || !strings.Contains(diff.Diff, "-one\n+three\n") {
+		t.Fatalf("DiffVersion() = %+v, %v, want one replaced by three", diff, err)
+	}
+
+	if _, err := s.RestoreVersion(ctx, "/notes.txt", first); err != nil {
+		t.Fatalf("RestoreVersion() error = %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "notes.txt")); string(content) != "one\n" {
+		t.Fatalf("content after restore = %q, want %q", content, "one\n")
+	}
+
+	// The restore can itself be undone
+	list, _ = s.ListVersions(ctx, "/notes.txt")
+	if list.TotalVersions != 3 || list.Versions[0].Reason != versions.ReasonRestore {
+		t.Fatalf("ListVersions() after restore = %+v, want the replaced content kept", list.Versions)
+	}
+}
+
+func TestRestoreDeletedFile(t *testing.T) {
+	s, root := newTestService(t, map[string]string{"/notes.txt": "one\n"})
+	enableVersions(t, s)
+	ctx := context.Background()
+
+	if err := s.WriteFile(ctx, "/notes.txt", strings.NewReader("two\n"), nil); err != nil {
+		t.Fatal(err)
+	}
+	id := s.versions.List("/notes.txt")[0].ID
+	if err := os.Remove(filepath.Join(root, "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RestoreVersion(ctx, "/notes.txt", id); err != nil {
+		t.Fatalf("RestoreVersion() of a deleted file error = %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "notes.txt")); string(content) != "one\n" {
+		t.Fatalf("content after restore = %q, want %q", content, "one\n")
+	}
+	if _, err := s.RestoreVersion(ctx, "/notes.txt", "missing"); err == nil {
+		t.Fatal("RestoreVersion() of an unknown version succeeded")
+	}
+}
@@ -0,0 +1,53 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/watch"
+)
+
+// WatchDirectory subscribes to the changes of the entries of a directory.
+// lastEventID resumes an earlier stream; the subscription is marked for
+// resync when the events since then are no longer available.
+func (s *FileService) WatchDirectory(ctx context.Context, dirPath, lastEventID string) (*watch.Subscription, error) {
+	cleanPath, err := s.validatePath(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	if !fs.IsDirectory(s.backend, cleanPath) {
+		return nil, fmt.Errorf("path is not a directory or does not exist: %s", dirPath)
+	}
+
+	localPath, ok := fs.LocalPath(s.backend, cleanPath)
+	if !ok {
+		return nil, fmt.Errorf("invalid path: watching is not supported on this storage")
+	}
+
+	sub, err := s.watcher.Subscribe(localPath, lastEventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch directory: %w", err)
+	}
+	return sub, nil
+}
+
+// newWatchEvent converts an event of the directory dirPath for clients
+func newWatchEvent(dirPath string, event watch.Event) WatchEvent {
+	result := WatchEvent{
+		ID:   event.ID,
+		Type: event.Type,
+		Path: path.Join(dirPath, event.Name),
+		Time: event.Time,
+	}
+	if event.From != "" {
+		result.From = path.Join(dirPath, event.From)
+	}
+	if event.Info != nil {
+		item := newFileItem(result.Path, event.Info, newFileMetadata(event.Info))
+		result.Item = &item
+	}
+	return result
+}
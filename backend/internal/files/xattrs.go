@@ -0,0 +1,183 @@
+package files
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/changes"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+)
+
+// Limits of extended attributes, as enforced by Linux
+const (
+	maxXattrNameLength = 255
+	maxXattrValueSize  = 64 * 1024
+)
+
+// userXattrNamespace is the namespace every user may read and write; the
+// others (security, trusted, system) are for admins
+const userXattrNamespace = "user."
+
+// xattrNamespaces are the namespaces an attribute name can start with
+var xattrNamespaces = []string{"user.", "security.", "trusted.", "system."}
+
+// ValidateXattrName checks that name is a namespaced extended attribute name
+func ValidateXattrName(name string) error {
+	if name == "" {
+		return fmt.Errorf("attribute name is required")
+	}
+	if len(name) > maxXattrNameLength || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("invalid attribute name")
+	}
+	for _, namespace := range xattrNamespaces {
+		if strings.HasPrefix(name, namespace) && len(name) > len(namespace) {
+			return nil
+		}
+	}
+	return fmt.Errorf("attribute name must start with a namespace such as %s", userXattrNamespace)
+}
+
+// checkXattrAccess rejects attributes outside the user namespace for non-admins
+func checkXattrAccess(ctx context.Context, name string) error {
+	if strings.HasPrefix(name, userXattrNamespace) || auth.FromContext(ctx).IsAdmin() {
+		return nil
+	}
+	return fmt.Errorf("access denied: only admins can use attribute %s", name)
+}
+
+// ListXattrs returns the extended attributes of a file that the caller may
+// read, with base64 values
+func (s *FileService) ListXattrs(ctx context.Context, filePath string) (*XattrsResponse, error) {
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	if _, err := fs.Lstat(s.backend, cleanPath); err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	xattrs, err := s.readXattrs(ctx, cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list extended attributes: %w", err)
+	}
+
+	return &XattrsResponse{
+		Success:     true,
+		Path:        filePath,
+		Xattrs:      xattrs,
+		RequestTime: time.Now(),
+	}, nil
+}
+
+// readXattrs returns the extended attributes of cleanPath that the caller may
+// read, with base64 values
+func (s *FileService) readXattrs(ctx context.Context, cleanPath string) (map[string]string, error) {
+	names, err := fs.ListXattrs(s.backend, cleanPath)
+	if err != nil {
+		return nil, err
+	}
+
+	xattrs := make(map[string]string, len(names))
+	for _, name := range names {
+		if checkXattrAccess(ctx, name) != nil {
+			continue
+		}
+		value, err := fs.GetXattr(s.backend, cleanPath, name)
+		if errors.Is(err, fs.ErrNoXattr) {
+			// Removed while listing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		xattrs[name] = base64.StdEncoding.EncodeToString(value)
+	}
+	return xattrs, nil
+}
+
+// GetXattr returns one extended attribute of a file with a base64 value
+func (s *FileService) GetXattr(ctx context.Context, filePath, name string) (*XattrResponse, error) {
+	if err := checkXattrAccess(ctx, name); err != nil {
+		return nil, err
+	}
+
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+
+	value, err := fs.GetXattr(s.backend, cleanPath, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extended attribute: %w", err)
+	}
+
+	return &XattrResponse{
+		Success:     true,
+		Path:        filePath,
+		Name:        name,
+		Value:       base64.StdEncoding.EncodeToString(value),
+		RequestTime: time.Now(),
+	}, nil
+}
+
+// SetXattr creates or replaces an extended attribute of a file
+func (s *FileService) SetXattr(ctx context.Context, filePath, name string, value []byte) (err error) {
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpXattrSet, filePath, name, int64(len(value)), err))
+	}()
+
+	if err := checkXattrAccess(ctx, name); err != nil {
+		return err
+	}
+	if len(value) > maxXattrValueSize {
+		return fmt.Errorf("attribute value too large: %d bytes (max: %d)", len(value), maxXattrValueSize)
+	}
+
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+
+	if err := fs.SetXattr(s.backend, cleanPath, name, value); err != nil {
+		return fmt.Errorf("failed to set extended attribute: %w", err)
+	}
+	s.recordChange(ctx, changes.TypeModify, cleanPath, "")
+
+	logging.FromContext(ctx).Info("set extended attribute", "path", filePath, "name", name, "bytes", len(value))
+	return nil
+}
+
+// RemoveXattr removes an extended attribute of a file
+func (s *FileService) RemoveXattr(ctx context.Context, filePath, name string) (err error) {
+	defer func() {
+		s.audit.Record(ctx, audit.NewEntry(ctx, audit.OpXattrRemove, filePath, name, 0, err))
+	}()
+
+	if err := checkXattrAccess(ctx, name); err != nil {
+		return err
+	}
+
+	// Validate the path
+	cleanPath, err := s.validatePath(filePath)
+	if err != nil {
+		return fmt.Errorf("path validation failed: %w", err)
+	}
+
+	if err := fs.RemoveXattr(s.backend, cleanPath, name); err != nil {
+		return fmt.Errorf("failed to remove extended attribute: %w", err)
+	}
+	s.recordChange(ctx, changes.TypeModify, cleanPath, "")
+
+	logging.FromContext(ctx).Info("removed extended attribute", "path", filePath, "name", name)
+	return nil
+}
@@ -0,0 +1,37 @@
+// Package filestest builds file trees for tests
+package filestest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tree creates a temporary directory holding files, keyed by slash-separated
+// path from the root, and returns the directory. Files get mode 0644 and
+// their parents are created as needed; keys ending in "/" are empty directories.
+func Tree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		localPath := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(name, "/")))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(localPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(localPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// The create mode is subject to the umask
+		if err := os.Chmod(localPath, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
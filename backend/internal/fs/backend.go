@@ -0,0 +1,41 @@
+package fs
+
+import (
+	"io"
+	"os"
+	"path"
+)
+
+// File is an open file or directory on a storage backend
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Readdir(count int) ([]os.FileInfo, error)
+	Stat() (os.FileInfo, error)
+}
+
+// FileSystemInterface is a storage backend addressed by slash-separated virtual
+// paths rooted at "/". Backends keep every path inside their own root and
+// report errors that match os.ErrNotExist, os.ErrExist and friends.
+type FileSystemInterface interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+	Mkdir(name string, perm os.FileMode) error
+	Rename(oldName, newName string) error
+	Remove(name string) error
+	RemoveAll(name string) error
+}
+
+// LocalPather is implemented by backends whose files may live on the local disk;
+// ok is false for paths that are stored elsewhere
+type LocalPather interface {
+	LocalPath(name string) (localPath string, ok bool)
+}
+
+// Clean returns the canonical virtual form of name: rooted, slash-separated and without dot segments
+func Clean(name string) string {
+	return path.Clean("/" + name)
+}
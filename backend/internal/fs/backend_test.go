@@ -0,0 +1,426 @@
+package fs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The conformance suite runs every backend through the same checks so the
+// service layer can rely on one set of semantics whatever stores the files
+
+func TestLocalFSConformance(t *testing.T) {
+	testBackend(t, func(t *testing.T) FileSystemInterface {
+		return NewLocalFS(t.TempDir())
+	})
+}
+
+func TestMemFSConformance(t *testing.T) {
+	testBackend(t, func(t *testing.T) FileSystemInterface {
+		return NewMemFS()
+	})
+}
+
+func testBackend(t *testing.T, newBackend func(t *testing.T) FileSystemInterface) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, backend FileSystemInterface)
+	}{
+		{"write and read", testWriteRead},
+		{"open flags", testOpenFlags},
+		{"seek", testSeek},
+		{"stat errors", testStatErrors},
+		{"mkdir", testMkdir},
+		{"read dir", testReadDir},
+		{"readdir batches", testReaddirBatches},
+		{"rename", testRename},
+		{"remove", testRemove},
+		{"remove all", testRemoveAll},
+		{"paths stay inside the root", testRootConfinement},
+		{"write atomic", testWriteAtomic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newBackend(t))
+		})
+	}
+}
+
+// writeTestFile creates or truncates name with content
+func writeTestFile(t *testing.T, backend FileSystemInterface, name, content string) {
+	t.Helper()
+	file, err := backend.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile(%s) error = %v", name, err)
+	}
+	if _, err := io.WriteString(file, content); err != nil {
+		t.Fatalf("Write(%s) error = %v", name, err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close(%s) error = %v", name, err)
+	}
+}
+
+// readTestFile returns the content of name
+func readTestFile(t *testing.T, backend FileSystemInterface, name string) string {
+	t.Helper()
+	file, err := backend.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(%s) error = %v", name, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("ReadAll(%s) error = %v", name, err)
+	}
+	return string(data)
+}
+
+// entryNames returns the names of directory entries in order
+func entryNames(entries []os.FileInfo) []string {
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func testWriteRead(t *testing.T, backend FileSystemInterface) {
+	writeTestFile(t, backend, "/hello.txt", "hello world")
+	if got := readTestFile(t, backend, "/hello.txt"); got != "hello world" {
+		t.Fatalf("content = %q, want %q", got, "hello world")
+	}
+
+	info, err := backend.Stat("/hello.txt")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Name() != "hello.txt" || info.Size() != 11 || info.IsDir() || !info.Mode().IsRegular() {
+		t.Fatalf("Stat() = name %q size %d mode %v, want a regular 11 byte hello.txt", info.Name(), info.Size(), info.Mode())
+	}
+
+	file, err := backend.OpenFile("/hello.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err != nil || info.Size() != 11 {
+		t.Fatalf("File.Stat() = %v, %v, want size 11", info, err)
+	}
+	if _, err := file.Write([]byte("x")); err == nil {
+		t.Fatal("Write() on a read-only handle succeeded")
+	}
+}
+
+func testOpenFlags(t *testing.T, backend FileSystemInterface) {
+	if _, err := backend.OpenFile("/missing.txt", os.O_RDONLY, 0); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("OpenFile() of a missing file error = %v, want ErrNotExist", err)
+	}
+
+	writeTestFile(t, backend, "/file.txt", "abc")
+	if _, err := backend.OpenFile("/file.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("OpenFile(O_EXCL) of an existing file error = %v, want ErrExist", err)
+	}
+
+	file, err := backend.OpenFile("/file.txt", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(O_APPEND) error = %v", err)
+	}
+	io.WriteString(file, "def")
+	file.Close()
+	if got := readTestFile(t, backend, "/file.txt"); got != "abcdef" {
+		t.Fatalf("content after append = %q, want %q", got, "abcdef")
+	}
+
+	writeTestFile(t, backend, "/file.txt", "z")
+	if got := readTestFile(t, backend, "/file.txt"); got != "z" {
+		t.Fatalf("content after truncate = %q, want %q", got, "z")
+	}
+
+	if _, err := backend.OpenFile("/nodir/file.txt", os.O_WRONLY|os.O_CREATE, 0644); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("OpenFile(O_CREATE) in a missing directory error = %v, want ErrNotExist", err)
+	}
+
+	if err := backend.Mkdir("/dir", 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if _, err := backend.OpenFile("/dir", os.O_WRONLY, 0); err == nil {
+		t.Fatal("OpenFile() of a directory for writing succeeded")
+	}
+}
+
+func testSeek(t *testing.T, backend FileSystemInterface) {
+	file, err := backend.OpenFile("/seek.bin", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	defer file.Close()
+
+	io.WriteString(file, "0123456789")
+	if pos, err := file.Seek(-4, io.SeekEnd); err != nil || pos != 6 {
+		t.Fatalf("Seek(-4, SeekEnd) = %d, %v, want 6", pos, err)
+	}
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(file, buf); err != nil || string(buf) != "67" {
+		t.Fatalf("Read() = %q, %v, want %q", buf, err, "67")
+	}
+	if pos, err := file.Seek(-5, io.SeekCurrent); err != nil || pos != 3 {
+		t.Fatalf("Seek(-5, SeekCurrent) = %d, %v, want 3", pos, err)
+	}
+	io.WriteString(file, "xy")
+	if _, err := file.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("Seek() to a negative offset succeeded")
+	}
+
+	file.Seek(0, io.SeekStart)
+	data, _ := io.ReadAll(file)
+	if string(data) != "012xy56789" {
+		t.Fatalf("content = %q, want %q", data, "012xy56789")
+	}
+}
+
+func testStatErrors(t *testing.T, backend FileSystemInterface) {
+	if _, err := backend.Stat("/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() of a missing path error = %v, want ErrNotExist", err)
+	}
+
+	info, err := backend.Stat("/")
+	if err != nil || !info.IsDir() {
+		t.Fatalf("Stat(/) = %v, %v, want a directory", info, err)
+	}
+
+	writeTestFile(t, backend, "/file.txt", "data")
+	if _, err := backend.Stat("/file.txt/child"); err == nil {
+		t.Fatal("Stat() below a file succeeded")
+	}
+
+	// Errors must never reveal where a backend keeps its files
+	if _, err := backend.Stat("/missing"); err != nil && strings.Contains(err.Error(), os.TempDir()) {
+		t.Fatalf("Stat() error %q reveals the backend root", err)
+	}
+}
+
+func testMkdir(t *testing.T, backend FileSystemInterface) {
+	if err := backend.Mkdir("/dir", 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if info, err := backend.Stat("/dir"); err != nil || !info.IsDir() {
+		t.Fatalf("Stat() = %v, %v, want a directory", info, err)
+	}
+	if err := backend.Mkdir("/dir", 0755); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Mkdir() of an existing directory error = %v, want ErrExist", err)
+	}
+	if err := backend.Mkdir("/", 0755); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Mkdir(/) error = %v, want ErrExist", err)
+	}
+	if err := backend.Mkdir("/a/b", 0755); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Mkdir() with a missing parent error = %v, want ErrNotExist", err)
+	}
+}
+
+func testReadDir(t *testing.T, backend FileSystemInterface) {
+	for _, name := range []string{"/b.txt", "/a.txt", "/c.txt"} {
+		writeTestFile(t, backend, name, name)
+	}
+	backend.Mkdir("/sub", 0755)
+	writeTestFile(t, backend, "/sub/nested.txt", "nested")
+
+	entries, err := backend.ReadDir("/")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if got, want := strings.Join(entryNames(entries), ","), "a.txt,b.txt,c.txt,sub"; got != want {
+		t.Fatalf("ReadDir() = %s, want %s", got, want)
+	}
+	for _, entry := range entries {
+		if entry.Name() == "sub" && !entry.IsDir() {
+			t.Fatal("ReadDir() reports sub as a file")
+		}
+	}
+
+	if _, err := backend.ReadDir("/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ReadDir() of a missing directory error = %v, want ErrNotExist", err)
+	}
+	if _, err := backend.ReadDir("/a.txt"); err == nil {
+		t.Fatal("ReadDir() of a file succeeded")
+	}
+}
+
+func testReaddirBatches(t *testing.T, backend FileSystemInterface) {
+	for _, name := range []string{"/1", "/2", "/3"} {
+		writeTestFile(t, backend, name, "")
+	}
+
+	dir, err := backend.OpenFile("/", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(/) error = %v", err)
+	}
+	defer dir.Close()
+
+	var names []string
+	for {
+		batch, err := dir.Readdir(2)
+		names = append(names, entryNames(batch)...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Readdir() error = %v", err)
+		}
+		if len(batch) == 0 || len(batch) > 2 {
+			t.Fatalf("Readdir(2) returned %d entries", len(batch))
+		}
+	}
+	if len(names) != 3 {
+		t.Fatalf("Readdir() listed %v, want 3 entries", names)
+	}
+
+	rest, err := dir.Readdir(-1)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("Readdir(-1) after EOF = %v, %v, want no entries and no error", rest, err)
+	}
+}
+
+func testRename(t *testing.T, backend FileSystemInterface) {
+	writeTestFile(t, backend, "/old.txt", "content")
+	if err := backend.Rename("/old.txt", "/new.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if _, err := backend.Stat("/old.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() of the old name error = %v, want ErrNotExist", err)
+	}
+	if got := readTestFile(t, backend, "/new.txt"); got != "content" {
+		t.Fatalf("content = %q, want %q", got, "content")
+	}
+
+	// Renaming over a file replaces it
+	writeTestFile(t, backend, "/other.txt", "other")
+	if err := backend.Rename("/other.txt", "/new.txt"); err != nil {
+		t.Fatalf("Rename() over a file error = %v", err)
+	}
+	if got := readTestFile(t, backend, "/new.txt"); got != "other" {
+		t.Fatalf("content = %q, want %q", got, "other")
+	}
+
+	// Directories move with their contents
+	backend.Mkdir("/dir", 0755)
+	writeTestFile(t, backend, "/dir/inner.txt", "inner")
+	if err := backend.Rename("/dir", "/moved"); err != nil {
+		t.Fatalf("Rename() of a directory error = %v", err)
+	}
+	if got := readTestFile(t, backend, "/moved/inner.txt"); got != "inner" {
+		t.Fatalf("content = %q, want %q", got, "inner")
+	}
+
+	if err := backend.Rename("/moved", "/moved/below"); err == nil {
+		t.Fatal("Rename() of a directory into itself succeeded")
+	}
+	backend.Mkdir("/full", 0755)
+	writeTestFile(t, backend, "/full/file", "")
+	if err := backend.Rename("/moved", "/full"); err == nil {
+		t.Fatal("Rename() over a non-empty directory succeeded")
+	}
+	if err := backend.Rename("/missing", "/elsewhere"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Rename() of a missing path error = %v, want ErrNotExist", err)
+	}
+}
+
+func testRemove(t *testing.T, backend FileSystemInterface) {
+	writeTestFile(t, backend, "/file.txt", "")
+	if err := backend.Remove("/file.txt"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := backend.Stat("/file.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() after Remove() error = %v, want ErrNotExist", err)
+	}
+	if err := backend.Remove("/file.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Remove() of a missing file error = %v, want ErrNotExist", err)
+	}
+
+	backend.Mkdir("/dir", 0755)
+	writeTestFile(t, backend, "/dir/file.txt", "")
+	if err := backend.Remove("/dir"); err == nil {
+		t.Fatal("Remove() of a non-empty directory succeeded")
+	}
+	backend.Remove("/dir/file.txt")
+	if err := backend.Remove("/dir"); err != nil {
+		t.Fatalf("Remove() of an empty directory error = %v", err)
+	}
+}
+
+func testRemoveAll(t *testing.T, backend FileSystemInterface) {
+	backend.Mkdir("/tree", 0755)
+	backend.Mkdir("/tree/sub", 0755)
+	writeTestFile(t, backend, "/tree/sub/file.txt", "")
+	writeTestFile(t, backend, "/keep.txt", "")
+
+	if err := backend.RemoveAll("/tree"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if _, err := backend.Stat("/tree"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() after RemoveAll() error = %v, want ErrNotExist", err)
+	}
+	if _, err := backend.Stat("/keep.txt"); err != nil {
+		t.Fatalf("RemoveAll() removed a sibling: %v", err)
+	}
+	if err := backend.RemoveAll("/missing"); err != nil {
+		t.Fatalf("RemoveAll() of a missing path error = %v", err)
+	}
+}
+
+func testRootConfinement(t *testing.T, backend FileSystemInterface) {
+	writeTestFile(t, backend, "/../../escape.txt", "inside")
+	if got := readTestFile(t, backend, "/escape.txt"); got != "inside" {
+		t.Fatalf("content = %q, want %q", got, "inside")
+	}
+	if got := readTestFile(t, backend, "../escape.txt"); got != "inside" {
+		t.Fatalf("content of a relative path = %q, want %q", got, "inside")
+	}
+
+	entries, err := backend.ReadDir("/..")
+	if err != nil {
+		t.Fatalf("ReadDir(/..) error = %v", err)
+	}
+	if got := strings.Join(entryNames(entries), ","); got != "escape.txt" {
+		t.Fatalf("ReadDir(/..) = %s, want the root listing", got)
+	}
+}
+
+func testWriteAtomic(t *testing.T, backend FileSystemInterface) {
+	written, err := WriteAtomic(backend, "/file.txt", strings.NewReader("first"), nil)
+	if err != nil || written != 5 {
+		t.Fatalf("WriteAtomic() = %d, %v, want 5 bytes", written, err)
+	}
+	if got := readTestFile(t, backend, "/file.txt"); got != "first" {
+		t.Fatalf("content = %q, want %q", got, "first")
+	}
+
+	// A rejected write leaves the existing file alone
+	rejected := errors.New("rejected")
+	if _, err := WriteAtomic(backend, "/file.txt", bytes.NewReader([]byte("second")), func() error { return rejected }); !errors.Is(err, rejected) {
+		t.Fatalf("WriteAtomic() error = %v, want %v", err, rejected)
+	}
+	if got := readTestFile(t, backend, "/file.txt"); got != "first" {
+		t.Fatalf("content after a rejected write = %q, want %q", got, "first")
+	}
+
+	if _, err := WriteAtomic(backend, "/file.txt", strings.NewReader("second"), func() error { return nil }); err != nil {
+		t.Fatalf("WriteAtomic() error = %v", err)
+	}
+	if got := readTestFile(t, backend, "/file.txt"); got != "second" {
+		t.Fatalf("content = %q, want %q", got, "second")
+	}
+
+	backend.Mkdir("/dir", 0755)
+	if _, err := WriteAtomic(backend, "/dir", strings.NewReader("x"), nil); err == nil {
+		t.Fatal("WriteAtomic() over a directory succeeded")
+	}
+
+	entries, _ := backend.ReadDir("/")
+	if got := strings.Join(entryNames(entries), ","); got != "dir,file.txt" {
+		t.Fatalf("ReadDir() after WriteAtomic() = %s, want no leftover temporary files", got)
+	}
+}
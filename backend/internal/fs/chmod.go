@@ -0,0 +1,40 @@
+package fs
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrChmodUnsupported is returned when a backend does not store permissions
+var ErrChmodUnsupported = errors.New("changing permissions is not supported on this storage")
+
+// Chmoder is implemented by backends that store file permissions
+type Chmoder interface {
+	Chmod(name string, mode os.FileMode) error
+}
+
+// Chmod changes the mode of name on backends that store permissions
+func Chmod(backend FileSystemInterface, name string, mode os.FileMode) error {
+	if chmoder, ok := backend.(Chmoder); ok {
+		return chmoder.Chmod(name, mode)
+	}
+	return ErrChmodUnsupported
+}
+
+// ErrChownUnsupported is returned when a backend does not store ownership
+var ErrChownUnsupported = errors.New("changing ownership is not supported on this storage")
+
+// Chowner is implemented by backends that store file ownership. Lchown changes
+// symbolic links themselves rather than what they point to; -1 keeps the
+// current owner or group.
+type Chowner interface {
+	Lchown(name string, uid, gid int) error
+}
+
+// Lchown changes the owner and group of name on backends that store ownership
+func Lchown(backend FileSystemInterface, name string, uid, gid int) error {
+	if chowner, ok := backend.(Chowner); ok {
+		return chowner.Lchown(name, uid, gid)
+	}
+	return ErrChownUnsupported
+}
@@ -0,0 +1,103 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// Copy copies a file or directory tree to target, which must not exist, and
+// returns the number of bytes copied. Files and directories keep their
+// permission bits where the backend stores them. Symbolic links are copied as
+// the files they point to; links to directories are skipped. A failed copy is
+// removed again.
+func Copy(ctx context.Context, backend FileSystemInterface, source, target string) (int64, error) {
+	info, err := backend.Stat(source)
+	if err != nil {
+		return 0, err
+	}
+
+	copied, err := copyEntry(ctx, backend, source, target, info)
+	// Only the target itself can already exist, and then it is not ours to remove
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		if removeErr := backend.RemoveAll(target); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			return copied, fmt.Errorf("%w (partial copy left behind: %v)", err, removeErr)
+		}
+	}
+	return copied, err
+}
+
+// copyEntry copies one file or directory, recursing into directories
+func copyEntry(ctx context.Context, backend FileSystemInterface, source, target string, info os.FileInfo) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return copyFile(backend, source, target, info)
+	}
+
+	if err := backend.Mkdir(target, info.Mode().Perm()); err != nil {
+		return 0, err
+	}
+	if err := keepMode(backend, target, info); err != nil {
+		return 0, err
+	}
+
+	entries, err := backend.ReadDir(source)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, entry := range entries {
+		childSource := path.Join(source, entry.Name())
+		if entry.Mode()&os.ModeSymlink != 0 {
+			if entry, err = backend.Stat(childSource); err != nil {
+				return total, err
+			}
+			if entry.IsDir() {
+				continue
+			}
+		}
+		n, err := copyEntry(ctx, backend, childSource, path.Join(target, entry.Name()), entry)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// copyFile copies the content of one file into a new file
+func copyFile(backend FileSystemInterface, source, target string, info os.FileInfo) (int64, error) {
+	src, err := backend.OpenFile(source, os.O_RDONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := backend.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, keepMode(backend, target, info)
+}
+
+// keepMode gives a copy the permission bits of its source, which the create
+// mode cannot do because of the umask
+func keepMode(backend FileSystemInterface, target string, info os.FileInfo) error {
+	err := Chmod(backend, target, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+	if errors.Is(err, ErrChmodUnsupported) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package fs
+
+import "os"
+
+// FileID is not available on platforms without inode numbers
+func FileID(info os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
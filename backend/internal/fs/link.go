@@ -0,0 +1,55 @@
+package fs
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrHardLinkUnsupported is returned when a backend cannot hard link files
+var ErrHardLinkUnsupported = errors.New("hard links are not supported on this storage")
+
+// ReplaceWithLink atomically replaces name with a hard link to target. Both
+// must be on the same local filesystem.
+func ReplaceWithLink(backend FileSystemInterface, target, name string) error {
+	targetPath, targetLocal := LocalPath(backend, target)
+	namePath, nameLocal := LocalPath(backend, name)
+	if !targetLocal || !nameLocal {
+		return ErrHardLinkUnsupported
+	}
+
+	// Renaming onto another link of the same file would leave the temporary link behind
+	targetInfo, targetErr := os.Stat(targetPath)
+	nameInfo, nameErr := os.Lstat(namePath)
+	if targetErr == nil && nameErr == nil && os.SameFile(targetInfo, nameInfo) {
+		return nil
+	}
+
+	tmpName, err := tempName(name)
+	if err != nil {
+		return err
+	}
+	tmpPath, _ := LocalPath(backend, tmpName)
+
+	// Errors name the virtual paths rather than locations on disk
+	if err := os.Link(targetPath, tmpPath); err != nil {
+		return &os.LinkError{Op: "link", Old: target, New: name, Err: errors.Unwrap(err)}
+	}
+	if err := os.Rename(tmpPath, namePath); err != nil {
+		os.Remove(tmpPath)
+		return &os.LinkError{Op: "rename", Old: tmpName, New: name, Err: errors.Unwrap(err)}
+	}
+	return nil
+}
+
+// Linker is implemented by backends that can create hard links
+type Linker interface {
+	Link(target, name string) error
+}
+
+// Link creates name as a hard link to the file target on the same backend
+func Link(backend FileSystemInterface, target, name string) error {
+	if linker, ok := backend.(Linker); ok {
+		return linker.Link(target, name)
+	}
+	return ErrHardLinkUnsupported
+}
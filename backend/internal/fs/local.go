@@ -0,0 +1,268 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalFS stores files in a directory on the local disk
+type LocalFS struct {
+	root string
+}
+
+// NewLocalFS creates a backend rooted at the given directory
+func NewLocalFS(root string) *LocalFS {
+	return &LocalFS{root: filepath.Clean(root)}
+}
+
+// Root returns the directory the backend is rooted at
+func (l *LocalFS) Root() string {
+	return l.root
+}
+
+// LocalPath maps a virtual path to its location on disk
+func (l *LocalFS) LocalPath(name string) (string, bool) {
+	return l.resolve(name), true
+}
+
+// resolve maps a virtual path to its location on disk
+func (l *LocalFS) resolve(name string) string {
+	return filepath.Join(l.root, filepath.FromSlash(Clean(name)))
+}
+
+// OpenFile opens a file with the given flags and permissions
+func (l *LocalFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	file, err := os.OpenFile(l.resolve(name), flag, perm)
+	if err != nil {
+		return nil, l.virtualError(err)
+	}
+	return file, nil
+}
+
+// Stat returns file information
+func (l *LocalFS) Stat(name string) (os.FileInfo, error) {
+	info, err := os.Stat(l.resolve(name))
+	if err != nil {
+		return nil, l.virtualError(err)
+	}
+	return info, nil
+}
+
+// Lstat returns file information without following a final symbolic link
+func (l *LocalFS) Lstat(name string) (os.FileInfo, error) {
+	info, err := os.Lstat(l.resolve(name))
+	if err != nil {
+		return nil, l.virtualError(err)
+	}
+	return info, nil
+}
+
+// Readlink returns the target of a symbolic link
+func (l *LocalFS) Readlink(name string) (string, error) {
+	target, err := os.Readlink(l.resolve(name))
+	return target, l.virtualError(err)
+}
+
+// LinkTarget returns the target of a symbolic link. Relative targets are
+// returned as stored and absolute targets below the root as virtual paths;
+// targets outside the root are withheld since they name host paths.
+func (l *LocalFS) LinkTarget(name string) (string, bool, error) {
+	linkPath := l.resolve(name)
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		return "", false, l.virtualError(err)
+	}
+
+	absolute := target
+	if !filepath.IsAbs(target) {
+		absolute = filepath.Join(filepath.Dir(linkPath), target)
+	}
+	roots := []string{l.root}
+	if real, err := filepath.EvalSymlinks(l.root); err == nil && real != l.root {
+		roots = append(roots, real)
+	}
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, absolute)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if filepath.IsAbs(target) {
+			return Clean(filepath.ToSlash(rel)), true, nil
+		}
+		return filepath.ToSlash(target), true, nil
+	}
+	return "", false, nil
+}
+
+// EvalSymlinks returns the path name resolves to once every symbolic link is
+// followed; paths that resolve outside the root are refused
+func (l *LocalFS) EvalSymlinks(name string) (string, error) {
+	root, err := filepath.EvalSymlinks(l.root)
+	if err != nil {
+		return "", l.virtualError(err)
+	}
+	// A link may point anywhere, so errors name the path asked for
+	real, err := filepath.EvalSymlinks(l.resolve(name))
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return "", &os.PathError{Op: "evalsymlinks", Path: Clean(name), Err: err}
+	}
+	rel, err := filepath.Rel(root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &os.PathError{Op: "evalsymlinks", Path: Clean(name), Err: os.ErrPermission}
+	}
+	return Clean(filepath.ToSlash(rel)), nil
+}
+
+// ReadDir lists a directory, skipping entries removed while it is read
+func (l *LocalFS) ReadDir(name string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(l.resolve(name))
+	if err != nil {
+		return nil, l.virtualError(err)
+	}
+
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, l.virtualError(err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Mkdir creates a single directory
+func (l *LocalFS) Mkdir(name string, perm os.FileMode) error {
+	return l.virtualError(os.Mkdir(l.resolve(name), perm))
+}
+
+// Rename moves a file or directory
+func (l *LocalFS) Rename(oldName, newName string) error {
+	return l.virtualError(os.Rename(l.resolve(oldName), l.resolve(newName)))
+}
+
+// Remove removes a file or empty directory
+func (l *LocalFS) Remove(name string) error {
+	return l.virtualError(os.Remove(l.resolve(name)))
+}
+
+// RemoveAll removes a file or directory tree
+func (l *LocalFS) RemoveAll(name string) error {
+	return l.virtualError(os.RemoveAll(l.resolve(name)))
+}
+
+// Chmod changes the mode of a file or directory
+func (l *LocalFS) Chmod(name string, mode os.FileMode) error {
+	return l.virtualError(os.Chmod(l.resolve(name), mode))
+}
+
+// Lchown changes the owner and group of a file or directory without following symbolic links
+func (l *LocalFS) Lchown(name string, uid, gid int) error {
+	return l.virtualError(os.Lchown(l.resolve(name), uid, gid))
+}
+
+// Symlink creates name as a symbolic link to target. The link stores the
+// target relative to its own directory so it keeps working wherever the root
+// is mounted.
+func (l *LocalFS) Symlink(target, name string) error {
+	if err := l.checkInside("symlink", target, name); err != nil {
+		return err
+	}
+	linkPath := l.resolve(name)
+	rel, err := filepath.Rel(filepath.Dir(linkPath), l.resolve(target))
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: err}
+	}
+	if err := os.Symlink(rel, linkPath); err != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: errors.Unwrap(err)}
+	}
+	return nil
+}
+
+// Link creates name as a hard link to the file target
+func (l *LocalFS) Link(target, name string) error {
+	if err := l.checkInside("link", target, name); err != nil {
+		return err
+	}
+	return l.virtualError(os.Link(l.resolve(target), l.resolve(name)))
+}
+
+// checkInside refuses to create the link name to target when symbolic links
+// along the path of target, or of the directory that would hold name, lead
+// out of the root
+func (l *LocalFS) checkInside(op, target, name string) error {
+	root, err := filepath.EvalSymlinks(l.root)
+	if err != nil {
+		return l.virtualError(err)
+	}
+	for _, localPath := range []string{l.resolve(target), filepath.Dir(l.resolve(name))} {
+		real, err := filepath.EvalSymlinks(localPath)
+		if err != nil {
+			var pathErr *os.PathError
+			if errors.As(err, &pathErr) {
+				err = pathErr.Err
+			}
+			return &os.LinkError{Op: op, Old: target, New: name, Err: err}
+		}
+		if real != root && !strings.HasPrefix(real, root+string(filepath.Separator)) {
+			return &os.LinkError{Op: op, Old: target, New: name, Err: os.ErrPermission}
+		}
+	}
+	return nil
+}
+
+// ListXattrs returns the names of the extended attributes of a file
+func (l *LocalFS) ListXattrs(name string) ([]string, error) {
+	names, err := listXattrs(l.resolve(name))
+	return names, l.virtualError(err)
+}
+
+// GetXattr returns the value of an extended attribute
+func (l *LocalFS) GetXattr(name, attr string) ([]byte, error) {
+	value, err := getXattr(l.resolve(name), attr)
+	return value, l.virtualError(err)
+}
+
+// SetXattr creates or replaces an extended attribute
+func (l *LocalFS) SetXattr(name, attr string, value []byte) error {
+	return l.virtualError(setXattr(l.resolve(name), attr, value))
+}
+
+// RemoveXattr removes an extended attribute
+func (l *LocalFS) RemoveXattr(name, attr string) error {
+	return l.virtualError(removeXattr(l.resolve(name), attr))
+}
+
+// virtualError rewrites disk paths in errors to virtual paths so callers
+// never see where the root lives
+func (l *LocalFS) virtualError(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		pathErr.Path = l.virtualPath(pathErr.Path)
+		return err
+	}
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		linkErr.Old = l.virtualPath(linkErr.Old)
+		linkErr.New = l.virtualPath(linkErr.New)
+	}
+	return err
+}
+
+// virtualPath maps a path on disk back to its virtual path
+func (l *LocalFS) virtualPath(localPath string) string {
+	rel, err := filepath.Rel(l.root, localPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return localPath
+	}
+	return Clean(filepath.ToSlash(rel))
+}
@@ -0,0 +1,97 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalFSLinkTarget(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "docs", "sub"), 0755)
+	os.WriteFile(filepath.Join(root, "docs", "report.pdf"), nil, 0644)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantTarget string
+		wantInside bool
+	}{
+		{name: "relative", target: "report.pdf", wantTarget: "report.pdf", wantInside: true},
+		{name: "relative climbing inside", target: "../docs/report.pdf", wantTarget: "../docs/report.pdf", wantInside: true},
+		{name: "relative climbing out", target: "../../outside", wantInside: false},
+		{name: "absolute inside", target: filepath.Join(root, "docs", "report.pdf"), wantTarget: "/docs/report.pdf", wantInside: true},
+		{name: "absolute root", target: root, wantTarget: "/", wantInside: true},
+		{name: "absolute outside", target: "/etc/passwd", wantInside: false},
+		{name: "absolute sibling of the root", target: root + "-other/file", wantInside: false},
+	}
+
+	backend := NewLocalFS(root)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := filepath.Join(root, "docs", "link")
+			os.Remove(link)
+			if err := os.Symlink(tt.target, link); err != nil {
+				t.Fatal(err)
+			}
+
+			target, inside, err := backend.LinkTarget("/docs/link")
+			if err != nil {
+				t.Fatalf("LinkTarget() error = %v", err)
+			}
+			if target != tt.wantTarget || inside != tt.wantInside {
+				t.Fatalf("LinkTarget() = %q, %v, want %q, %v", target, inside, tt.wantTarget, tt.wantInside)
+			}
+		})
+	}
+
+	// Mounted backends report absolute targets in the mount table
+	mounts := NewMountFS(NewMemFS())
+	if err := mounts.Mount("/archive", backend); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(root, "docs", "link"))
+	os.Symlink(filepath.Join(root, "docs", "report.pdf"), filepath.Join(root, "docs", "link"))
+	if target, inside, err := mounts.LinkTarget("/archive/docs/link"); err != nil || target != "/archive/docs/report.pdf" || !inside {
+		t.Fatalf("MountFS.LinkTarget() = %q, %v, %v, want /archive/docs/report.pdf", target, inside, err)
+	}
+}
+
+func TestLocalFSLinksStayInside(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(root, "file.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(outside, "secret.txt"), nil, 0644)
+	// escape is a directory inside the root that leads out of it
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		link   string
+	}{
+		{name: "target through an escaping link", target: "/escape/secret.txt", link: "/link"},
+		{name: "link below an escaping link", target: "/file.txt", link: "/escape/link"},
+	}
+
+	backend := NewLocalFS(root)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := backend.Symlink(tt.target, tt.link); !errors.Is(err, os.ErrPermission) {
+				t.Fatalf("Symlink() error = %v, want ErrPermission", err)
+			}
+			if err := backend.Link(tt.target, tt.link); !errors.Is(err, os.ErrPermission) {
+				t.Fatalf("Link() error = %v, want ErrPermission", err)
+			}
+		})
+	}
+
+	if entries, _ := os.ReadDir(outside); len(entries) != 1 {
+		t.Fatalf("links were created outside the root: %v", entries)
+	}
+	if err := backend.Symlink("/file.txt", "/inside"); err != nil {
+		t.Fatalf("Symlink() inside the root error = %v", err)
+	}
+}
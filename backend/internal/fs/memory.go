@@ -0,0 +1,423 @@
+package fs
+
+import (
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemFS keeps files in memory; it is meant for tests and scratch storage
+type MemFS struct {
+	mu   sync.RWMutex
+	root *memNode
+}
+
+// memNode is a file or directory in a MemFS
+type memNode struct {
+	name     string
+	mode     os.FileMode
+	modTime  time.Time
+	data     []byte
+	children map[string]*memNode
+}
+
+// NewMemFS creates an empty in-memory backend
+func NewMemFS() *MemFS {
+	return &MemFS{root: newMemDir("/", 0755)}
+}
+
+func newMemDir(name string, perm os.FileMode) *memNode {
+	return &memNode{name: name, mode: os.ModeDir | perm.Perm(), modTime: time.Now(), children: make(map[string]*memNode)}
+}
+
+// info returns a snapshot of the node's metadata
+func (n *memNode) info() os.FileInfo {
+	return &memInfo{name: n.name, size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+// lookup finds the node for a virtual path; the caller holds the lock
+func (m *MemFS) lookup(op, name string) (*memNode, error) {
+	node := m.root
+	for _, part := range splitPath(name) {
+		if !node.mode.IsDir() {
+			return nil, &os.PathError{Op: op, Path: Clean(name), Err: syscall.ENOTDIR}
+		}
+		child, ok := node.children[part]
+		if !ok {
+			return nil, &os.PathError{Op: op, Path: Clean(name), Err: syscall.ENOENT}
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// lookupParent finds the directory that holds name and the final path element
+func (m *MemFS) lookupParent(op, name string) (*memNode, string, error) {
+	clean := Clean(name)
+	if clean == "/" {
+		return nil, "", &os.PathError{Op: op, Path: clean, Err: syscall.EINVAL}
+	}
+	parent, err := m.lookup(op, path.Dir(clean))
+	if err != nil {
+		return nil, "", &os.PathError{Op: op, Path: clean, Err: syscall.ENOENT}
+	}
+	if !parent.mode.IsDir() {
+		return nil, "", &os.PathError{Op: op, Path: clean, Err: syscall.ENOTDIR}
+	}
+	return parent, path.Base(clean), nil
+}
+
+// OpenFile opens a file with the given flags and permissions
+func (m *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	node, err := m.lookup("open", name)
+	switch {
+	case err == nil && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: Clean(name), Err: syscall.EEXIST}
+	case err == nil:
+		if node.mode.IsDir() && writable {
+			return nil, &os.PathError{Op: "open", Path: Clean(name), Err: syscall.EISDIR}
+		}
+		if flag&os.O_TRUNC != 0 && writable {
+			node.data = nil
+			node.modTime = time.Now()
+		}
+	case flag&os.O_CREATE != 0:
+		parent, base, err := m.lookupParent("open", name)
+		if err != nil {
+			return nil, err
+		}
+		node = &memNode{name: base, mode: perm.Perm(), modTime: time.Now()}
+		parent.children[base] = node
+		parent.modTime = node.modTime
+	default:
+		return nil, err
+	}
+
+	return &memFile{fs: m, node: node, name: Clean(name), flag: flag}, nil
+}
+
+// Stat returns file information
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return node.info(), nil
+}
+
+// ReadDir lists a directory sorted by name
+func (m *MemFS) ReadDir(name string) ([]os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node, err := m.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: Clean(name), Err: syscall.ENOTDIR}
+	}
+	return node.sortedChildren(), nil
+}
+
+// sortedChildren returns directory entries sorted by name; the caller holds the lock
+func (n *memNode) sortedChildren() []os.FileInfo {
+	infos := make([]os.FileInfo, 0, len(n.children))
+	for _, child := range n.children {
+		infos = append(infos, child.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos
+}
+
+// Mkdir creates a single directory
+func (m *MemFS) Mkdir(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parent, base, err := m.lookupParent("mkdir", name)
+	if err != nil {
+		if Clean(name) == "/" {
+			return &os.PathError{Op: "mkdir", Path: "/", Err: syscall.EEXIST}
+		}
+		return err
+	}
+	if _, exists := parent.children[base]; exists {
+		return &os.PathError{Op: "mkdir", Path: Clean(name), Err: syscall.EEXIST}
+	}
+	parent.children[base] = newMemDir(base, perm)
+	parent.modTime = time.Now()
+	return nil
+}
+
+// Chmod changes the permission bits of a file or directory
+func (m *MemFS) Chmod(name string, mode os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, err := m.lookup("chmod", name)
+	if err != nil {
+		return err
+	}
+	node.mode = node.mode&os.ModeType | mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)
+	return nil
+}
+
+// Rename moves a file or directory, replacing an existing file or empty directory like os.Rename
+func (m *MemFS) Rename(oldName, newName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldClean, newClean := Clean(oldName), Clean(newName)
+	linkError := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldClean, New: newClean, Err: err}
+	}
+
+	oldParent, oldBase, err := m.lookupParent("rename", oldClean)
+	if err != nil {
+		return linkError(syscall.EINVAL)
+	}
+	node, ok := oldParent.children[oldBase]
+	if !ok {
+		return linkError(syscall.ENOENT)
+	}
+	newParent, newBase, err := m.lookupParent("rename", newClean)
+	if err != nil {
+		return linkError(syscall.ENOENT)
+	}
+	if node.mode.IsDir() && strings.HasPrefix(newClean, oldClean+"/") {
+		return linkError(syscall.EINVAL)
+	}
+	if oldClean == newClean {
+		return nil
+	}
+
+	if existing, exists := newParent.children[newBase]; exists {
+		switch {
+		case existing.mode.IsDir() && !node.mode.IsDir():
+			return linkError(syscall.EISDIR)
+		case !existing.mode.IsDir() && node.mode.IsDir():
+			return linkError(syscall.ENOTDIR)
+		case existing.mode.IsDir() && len(existing.children) > 0:
+			return linkError(syscall.ENOTEMPTY)
+		}
+	}
+
+	delete(oldParent.children, oldBase)
+	node.name = newBase
+	newParent.children[newBase] = node
+	now := time.Now()
+	oldParent.modTime, newParent.modTime = now, now
+	return nil
+}
+
+// Remove removes a file or empty directory
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parent, base, err := m.lookupParent("remove", name)
+	if err != nil {
+		return err
+	}
+	node, ok := parent.children[base]
+	if !ok {
+		return &os.PathError{Op: "remove", Path: Clean(name), Err: syscall.ENOENT}
+	}
+	if node.mode.IsDir() && len(node.children) > 0 {
+		return &os.PathError{Op: "remove", Path: Clean(name), Err: syscall.ENOTEMPTY}
+	}
+	delete(parent.children, base)
+	parent.modTime = time.Now()
+	return nil
+}
+
+// RemoveAll removes a file or directory tree; a missing path is not an error
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if Clean(name) == "/" {
+		m.root.children = make(map[string]*memNode)
+		return nil
+	}
+	parent, base, err := m.lookupParent("removeall", name)
+	if err != nil {
+		return nil
+	}
+	if _, ok := parent.children[base]; ok {
+		delete(parent.children, base)
+		parent.modTime = time.Now()
+	}
+	return nil
+}
+
+// splitPath returns the elements of a virtual path
+func splitPath(name string) []string {
+	clean := Clean(name)
+	if clean == "/" {
+		return nil
+	}
+	return strings.Split(clean[1:], "/")
+}
+
+// memFile is an open handle on a MemFS node
+type memFile struct {
+	fs     *MemFS
+	node   *memNode
+	name   string
+	flag   int
+	offset int64
+	dirPos int
+	closed bool
+}
+
+// Read reads from the current offset
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.node.mode.IsDir() {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: syscall.EISDIR}
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: syscall.EBADF}
+	}
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+// Write writes at the current offset, or at the end with O_APPEND
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: syscall.EBADF}
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+
+	end := f.offset + int64(len(p))
+	if end > int64(len(f.node.data)) {
+		grown := make([]byte, end)
+		copy(grown, f.node.data)
+		f.node.data = grown
+	}
+	copy(f.node.data[f.offset:], p)
+	f.offset = end
+	f.node.modTime = time.Now()
+	return len(p), nil
+}
+
+// Seek sets the offset for the next Read or Write
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	default:
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Readdir lists directory entries like os.File.Readdir
+func (f *memFile) Readdir(count int) ([]os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	if !f.node.mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+	}
+
+	entries := f.node.sortedChildren()
+	if f.dirPos >= len(entries) {
+		if count > 0 {
+			return nil, io.EOF
+		}
+		return []os.FileInfo{}, nil
+	}
+	entries = entries[f.dirPos:]
+	if count > 0 && count < len(entries) {
+		entries = entries[:count]
+	}
+	f.dirPos += len(entries)
+	return entries, nil
+}
+
+// Stat returns file information for the open node
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	return f.node.info(), nil
+}
+
+// Close releases the handle
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// memInfo is the os.FileInfo for a MemFS node
+type memInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.size }
+func (i *memInfo) Mode() os.FileMode  { return i.mode }
+func (i *memInfo) ModTime() time.Time { return i.modTime }
+func (i *memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,35 @@
+package fs
+
+import (
+	"os"
+	"time"
+)
+
+// Metadata is the Unix metadata of a file, where the platform records it
+type Metadata struct {
+	UID    uint32
+	GID    uint32
+	Inode  uint64
+	Links  uint64
+	Device uint64
+	// Times are zero when the platform or file system does not record them
+	AccessTime time.Time
+	ChangeTime time.Time
+	BirthTime  time.Time
+}
+
+// MetadataOf returns the Unix metadata recorded in info; ok is false when
+// the backend or platform has none
+func MetadataOf(info os.FileInfo) (Metadata, bool) {
+	return metadataOf(info)
+}
+
+// BirthTime looks up the birth time of name on disk, for platforms whose
+// FileInfo does not carry it; it is zero when unknown
+func BirthTime(backend FileSystemInterface, name string) time.Time {
+	localPath, ok := LocalPath(backend, name)
+	if !ok {
+		return time.Time{}
+	}
+	return birthTime(localPath)
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package fs
+
+import (
+	"os"
+	"time"
+)
+
+// metadataOf reports no metadata on platforms without Unix file attributes
+func metadataOf(info os.FileInfo) (Metadata, bool) {
+	return Metadata{}, false
+}
+
+// birthTime is unknown on platforms without Unix file attributes
+func birthTime(localPath string) time.Time {
+	return time.Time{}
+}
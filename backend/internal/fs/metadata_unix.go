@@ -0,0 +1,35 @@
+//go:build unix
+
+package fs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// metadataOf reads the metadata recorded in info by the operating system
+func metadataOf(info os.FileInfo) (Metadata, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return Metadata{}, false
+	}
+
+	meta := Metadata{
+		UID:    stat.Uid,
+		GID:    stat.Gid,
+		Inode:  uint64(stat.Ino),
+		Links:  uint64(stat.Nlink),
+		Device: uint64(stat.Dev),
+	}
+	meta.AccessTime, meta.ChangeTime, meta.BirthTime = statTimes(stat)
+	return meta, true
+}
+
+// timespecTime converts a timespec, leaving unset times zero
+func timespecTime(ts syscall.Timespec) time.Time {
+	if ts.Sec == 0 && ts.Nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(ts.Sec), int64(ts.Nsec)).UTC()
+}
@@ -0,0 +1,405 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MountFS presents several backends as one tree: a root backend with other
+// backends mounted at fixed virtual paths, like a Unix mount table
+type MountFS struct {
+	root   FileSystemInterface
+	mounts []mountPoint
+}
+
+// mountPoint is one backend mounted below the root
+type mountPoint struct {
+	path    string
+	backend FileSystemInterface
+}
+
+// NewMountFS creates a mount table over the root backend
+func NewMountFS(root FileSystemInterface) *MountFS {
+	return &MountFS{root: root}
+}
+
+// Mount attaches backend at a top-level mountPath such as "/archive",
+// hiding whatever the root has there
+func (m *MountFS) Mount(mountPath string, backend FileSystemInterface) error {
+	mountPath = Clean(mountPath)
+	if mountPath == "/" || path.Dir(mountPath) != "/" {
+		return fmt.Errorf("invalid mount path %s: mounts must be top-level directories", mountPath)
+	}
+	for _, existing := range m.mounts {
+		if existing.path == mountPath {
+			return fmt.Errorf("mount path already in use: %s", mountPath)
+		}
+	}
+
+	m.mounts = append(m.mounts, mountPoint{path: mountPath, backend: backend})
+	sort.Slice(m.mounts, func(i, j int) bool { return m.mounts[i].path < m.mounts[j].path })
+	return nil
+}
+
+// MountPaths returns the virtual paths with a mounted backend
+func (m *MountFS) MountPaths() []string {
+	paths := make([]string, 0, len(m.mounts))
+	for _, mount := range m.mounts {
+		paths = append(paths, mount.path)
+	}
+	return paths
+}
+
+// resolve returns the backend responsible for name and the path inside it
+func (m *MountFS) resolve(name string) (FileSystemInterface, string, string) {
+	clean := Clean(name)
+	for _, mount := range m.mounts {
+		if clean == mount.path {
+			return mount.backend, "/", mount.path
+		}
+		if strings.HasPrefix(clean, mount.path+"/") {
+			return mount.backend, clean[len(mount.path):], mount.path
+		}
+	}
+	return m.root, clean, "/"
+}
+
+// isMountPoint reports whether name is exactly a mount path
+func (m *MountFS) isMountPoint(name string) bool {
+	clean := Clean(name)
+	for _, mount := range m.mounts {
+		if mount.path == clean {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenFile opens a file on the backend that holds it
+func (m *MountFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	backend, inner, _ := m.resolve(name)
+	file, err := backend.OpenFile(inner, flag, perm)
+	if err != nil {
+		return nil, m.mountError(err, name)
+	}
+	if inner == "/" && m.isMountPoint(name) {
+		return &mountRootFile{File: file, name: path.Base(Clean(name))}, nil
+	}
+	if Clean(name) == "/" && len(m.mounts) > 0 {
+		return &mountDirFile{File: file, mounts: m}, nil
+	}
+	return file, nil
+}
+
+// Stat returns file information; a mount point reports its own name
+func (m *MountFS) Stat(name string) (os.FileInfo, error) {
+	backend, inner, _ := m.resolve(name)
+	info, err := backend.Stat(inner)
+	if err != nil {
+		return nil, m.mountError(err, name)
+	}
+	if inner == "/" && m.isMountPoint(name) {
+		return renamedInfo{FileInfo: info, name: path.Base(Clean(name))}, nil
+	}
+	return info, nil
+}
+
+// Lstat returns file information from the backend that holds name without
+// following a final symbolic link
+func (m *MountFS) Lstat(name string) (os.FileInfo, error) {
+	backend, inner, _ := m.resolve(name)
+	info, err := Lstat(backend, inner)
+	if err != nil {
+		return nil, m.mountError(err, name)
+	}
+	if inner == "/" && m.isMountPoint(name) {
+		return renamedInfo{FileInfo: info, name: path.Base(Clean(name))}, nil
+	}
+	return info, nil
+}
+
+// Readlink returns the target of a symbolic link on the backend that holds name
+func (m *MountFS) Readlink(name string) (string, error) {
+	backend, inner, _ := m.resolve(name)
+	target, err := Readlink(backend, inner)
+	return target, m.mountError(err, name)
+}
+
+// LinkTarget returns the target of a symbolic link on the backend that holds
+// name, with absolute targets in the mount table. The backend reports
+// relative targets that climb out of the mount as outside.
+func (m *MountFS) LinkTarget(name string) (string, bool, error) {
+	backend, inner, mountPath := m.resolve(name)
+	target, inside, err := LinkTarget(backend, inner)
+	if err != nil {
+		return "", false, m.mountError(err, name)
+	}
+	if inside && path.IsAbs(target) {
+		target = path.Join(mountPath, target)
+	}
+	return target, inside, nil
+}
+
+// EvalSymlinks resolves symbolic links on the backend that holds name
+func (m *MountFS) EvalSymlinks(name string) (string, error) {
+	backend, inner, mountPath := m.resolve(name)
+	real, err := EvalSymlinks(backend, inner)
+	if err != nil {
+		return "", m.mountError(err, name)
+	}
+	return path.Join(mountPath, real), nil
+}
+
+// ReadDir lists a directory; the root listing includes the mount points
+func (m *MountFS) ReadDir(name string) ([]os.FileInfo, error) {
+	backend, inner, _ := m.resolve(name)
+	entries, err := backend.ReadDir(inner)
+	if err != nil {
+		return nil, m.mountError(err, name)
+	}
+	if Clean(name) == "/" {
+		entries = m.mergeMounts(entries)
+	}
+	return entries, nil
+}
+
+// mountEntries returns directory entries for the mount points
+func (m *MountFS) mountEntries() []os.FileInfo {
+	var children []os.FileInfo
+	for _, mount := range m.mounts {
+		info, err := mount.backend.Stat("/")
+		if err != nil {
+			// Keep unreachable mounts visible so users can see they exist
+			info = &memInfo{mode: os.ModeDir | 0755, modTime: time.Time{}}
+		}
+		children = append(children, renamedInfo{FileInfo: info, name: path.Base(mount.path)})
+	}
+	return children
+}
+
+// mergeMounts replaces or adds root entries for the mount points
+func (m *MountFS) mergeMounts(entries []os.FileInfo) []os.FileInfo {
+	children := m.mountEntries()
+	if len(children) == 0 {
+		return entries
+	}
+
+	mounted := make(map[string]bool, len(children))
+	for _, child := range children {
+		mounted[child.Name()] = true
+	}
+	merged := make([]os.FileInfo, 0, len(entries)+len(children))
+	for _, entry := range entries {
+		if !mounted[entry.Name()] {
+			merged = append(merged, entry)
+		}
+	}
+	return append(merged, children...)
+}
+
+// Mkdir creates a directory on the backend that holds it
+func (m *MountFS) Mkdir(name string, perm os.FileMode) error {
+	if m.isMountPoint(name) {
+		return &os.PathError{Op: "mkdir", Path: Clean(name), Err: syscall.EEXIST}
+	}
+	backend, inner, _ := m.resolve(name)
+	return m.mountError(backend.Mkdir(inner, perm), name)
+}
+
+// Rename moves a file or directory within one backend; moves across mounts are refused
+func (m *MountFS) Rename(oldName, newName string) error {
+	if m.isMountPoint(oldName) || m.isMountPoint(newName) {
+		return &os.LinkError{Op: "rename", Old: Clean(oldName), New: Clean(newName), Err: syscall.EBUSY}
+	}
+	oldBackend, oldInner, oldMount := m.resolve(oldName)
+	_, newInner, newMount := m.resolve(newName)
+	if oldMount != newMount {
+		return &os.LinkError{Op: "rename", Old: Clean(oldName), New: Clean(newName), Err: syscall.EXDEV}
+	}
+	return m.mountError(oldBackend.Rename(oldInner, newInner), oldName)
+}
+
+// Remove removes a file or empty directory; mount points cannot be removed
+func (m *MountFS) Remove(name string) error {
+	if m.isMountPoint(name) {
+		return &os.PathError{Op: "remove", Path: Clean(name), Err: syscall.EBUSY}
+	}
+	backend, inner, _ := m.resolve(name)
+	return m.mountError(backend.Remove(inner), name)
+}
+
+// RemoveAll removes a file or directory tree; mount points and the root cannot be removed
+func (m *MountFS) RemoveAll(name string) error {
+	if m.isMountPoint(name) || (Clean(name) == "/" && len(m.mounts) > 0) {
+		return &os.PathError{Op: "removeall", Path: Clean(name), Err: syscall.EBUSY}
+	}
+	backend, inner, _ := m.resolve(name)
+	return m.mountError(backend.RemoveAll(inner), name)
+}
+
+// Chmod changes the mode on the backend that holds name
+func (m *MountFS) Chmod(name string, mode os.FileMode) error {
+	backend, inner, _ := m.resolve(name)
+	return m.mountError(Chmod(backend, inner, mode), name)
+}
+
+// Lchown changes the owner and group on the backend that holds name
+func (m *MountFS) Lchown(name string, uid, gid int) error {
+	backend, inner, _ := m.resolve(name)
+	return m.mountError(Lchown(backend, inner, uid, gid), name)
+}
+
+// Symlink creates a symbolic link within one backend; links across mounts
+// are refused since the backends may not share a disk
+func (m *MountFS) Symlink(target, name string) error {
+	return m.link("symlink", target, name, Symlink)
+}
+
+// Link creates a hard link within one backend; links across mounts are refused
+func (m *MountFS) Link(target, name string) error {
+	return m.link("link", target, name, Link)
+}
+
+// link creates name as a link to target with create, on the backend holding both
+func (m *MountFS) link(op, target, name string, create func(FileSystemInterface, string, string) error) error {
+	if m.isMountPoint(name) {
+		return &os.PathError{Op: op, Path: Clean(name), Err: syscall.EEXIST}
+	}
+	backend, targetInner, targetMount := m.resolve(target)
+	_, nameInner, nameMount := m.resolve(name)
+	if targetMount != nameMount {
+		return &os.LinkError{Op: op, Old: Clean(target), New: Clean(name), Err: syscall.EXDEV}
+	}
+	return m.mountError(create(backend, targetInner, nameInner), name)
+}
+
+// ListXattrs returns extended attribute names from the backend that holds name
+func (m *MountFS) ListXattrs(name string) ([]string, error) {
+	backend, inner, _ := m.resolve(name)
+	names, err := ListXattrs(backend, inner)
+	return names, m.mountError(err, name)
+}
+
+// GetXattr returns an extended attribute from the backend that holds name
+func (m *MountFS) GetXattr(name, attr string) ([]byte, error) {
+	backend, inner, _ := m.resolve(name)
+	value, err := GetXattr(backend, inner, attr)
+	return value, m.mountError(err, name)
+}
+
+// SetXattr sets an extended attribute on the backend that holds name
+func (m *MountFS) SetXattr(name, attr string, value []byte) error {
+	backend, inner, _ := m.resolve(name)
+	return m.mountError(SetXattr(backend, inner, attr, value), name)
+}
+
+// RemoveXattr removes an extended attribute on the backend that holds name
+func (m *MountFS) RemoveXattr(name, attr string) error {
+	backend, inner, _ := m.resolve(name)
+	return m.mountError(RemoveXattr(backend, inner, attr), name)
+}
+
+// LocalPath delegates to the backend that holds name
+func (m *MountFS) LocalPath(name string) (string, bool) {
+	backend, inner, _ := m.resolve(name)
+	return LocalPath(backend, inner)
+}
+
+// mountError rewrites backend-relative paths in errors to paths in the mount
+// table, including path errors that a backend wrapped in its own messages
+func (m *MountFS) mountError(err error, name string) error {
+	if err == nil {
+		return nil
+	}
+	_, _, mountPath := m.resolve(name)
+	if mountPath == "/" {
+		return err
+	}
+
+	var inner, rewritten error
+	var pathErr *os.PathError
+	var linkErr *os.LinkError
+	switch {
+	case errors.As(err, &pathErr):
+		inner = pathErr
+		rewritten = &os.PathError{Op: pathErr.Op, Path: path.Join(mountPath, pathErr.Path), Err: pathErr.Err}
+	case errors.As(err, &linkErr):
+		inner = linkErr
+		rewritten = &os.LinkError{Op: linkErr.Op, Old: path.Join(mountPath, linkErr.Old), New: path.Join(mountPath, linkErr.New), Err: linkErr.Err}
+	default:
+		return err
+	}
+	if err == inner {
+		return rewritten
+	}
+	return &mountedError{msg: strings.Replace(err.Error(), inner.Error(), rewritten.Error(), 1), err: rewritten}
+}
+
+// mountedError keeps a backend's wrapping message around a rewritten path error
+type mountedError struct {
+	msg string
+	err error
+}
+
+// Error returns the backend message with the mount path in place
+func (e *mountedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the rewritten path error
+func (e *mountedError) Unwrap() error {
+	return e.err
+}
+
+// renamedInfo reports a mounted backend's root under its mount name
+type renamedInfo struct {
+	os.FileInfo
+	name string
+}
+
+// Name returns the mount name
+func (i renamedInfo) Name() string {
+	return i.name
+}
+
+// mountRootFile is the root directory of a mounted backend
+type mountRootFile struct {
+	File
+	name string
+}
+
+// Stat reports the mount name instead of the backend root's name
+func (f *mountRootFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: f.name}, nil
+}
+
+// mountDirFile adds the mount points to root listings read through a handle
+type mountDirFile struct {
+	File
+	mounts *MountFS
+	merged bool
+}
+
+// Readdir lists the root; mount points are added to the first batch
+func (f *mountDirFile) Readdir(count int) ([]os.FileInfo, error) {
+	entries, err := f.File.Readdir(count)
+	if f.merged {
+		return entries, err
+	}
+	f.merged = true
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return f.mounts.mergeMounts(entries), err
+}
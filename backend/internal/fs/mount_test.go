@@ -0,0 +1,41 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// wrappingFS wraps the errors of its backend the way remote backends do
+type wrappingFS struct {
+	FileSystemInterface
+}
+
+func (w wrappingFS) Stat(name string) (os.FileInfo, error) {
+	info, err := w.FileSystemInterface.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("remote stat failed: %w", err)
+	}
+	return info, nil
+}
+
+func TestMountFSRewritesWrappedErrors(t *testing.T) {
+	mounts := NewMountFS(NewMemFS())
+	if err := mounts.Mount("/cloud", wrappingFS{NewMemFS()}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := mounts.Stat("/cloud/missing.txt")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() error = %v, want not exist", err)
+	}
+	var pathErr *os.PathError
+	if !errors.As(err, &pathErr) || pathErr.Path != "/cloud/missing.txt" {
+		t.Fatalf("Stat() path error = %v, want /cloud/missing.txt", pathErr)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "remote stat failed: ") || !strings.Contains(msg, "/cloud/missing.txt") {
+		t.Fatalf("Stat() error = %q, want the backend message with the mount path", msg)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package fs
+
+import "os"
+
+// copyOwner is a no-op on platforms without Unix ownership
+func copyOwner(localPath string, info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package fs
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives the file at localPath the owner and group recorded in info;
+// it is a no-op when they already match, so unprivileged processes only fail
+// for files owned by someone else
+func copyOwner(localPath string, info os.FileInfo) error {
+	want, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	current, err := os.Lstat(localPath)
+	if err != nil {
+		return err
+	}
+	if have, ok := current.Sys().(*syscall.Stat_t); ok && have.Uid == want.Uid && have.Gid == want.Gid {
+		return nil
+	}
+	return os.Lchown(localPath, int(want.Uid), int(want.Gid))
+}
@@ -0,0 +1,114 @@
+package fs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// WriteAtomic streams content into name, creating or replacing it, and
+// returns the bytes written. Content goes to a temporary sibling that verify,
+// when given, must accept before it is renamed over name, so a failed or
+// rejected write leaves the existing file untouched and readers never see a
+// partial one. A symbolic link has the file it points to replaced, and on the
+// local disk the replacement keeps the original's mode and ownership.
+func WriteAtomic(backend FileSystemInterface, name string, content io.Reader, verify func() error) (int64, error) {
+	target := name
+	if localPath, ok := LocalPath(backend, name); ok && isSymlink(localPath) {
+		real, err := EvalSymlinks(backend, name)
+		if err != nil {
+			return 0, fmt.Errorf("failed to resolve link: %w", err)
+		}
+		target = real
+	}
+
+	perm := os.FileMode(0644)
+	info, err := backend.Stat(target)
+	if err == nil && info.IsDir() {
+		return 0, fmt.Errorf("cannot replace directory: %s", Clean(name))
+	} else if err == nil {
+		perm = info.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	tmpName, err := tempName(target)
+	if err != nil {
+		return 0, err
+	}
+	written, err := writeTemp(backend, target, tmpName, content, perm, info, verify)
+	if err != nil {
+		backend.Remove(tmpName)
+		return written, err
+	}
+
+	// Persist the rename itself; failing here does not undo the replace
+	if localPath, ok := LocalPath(backend, target); ok {
+		if dir, err := os.Open(filepath.Dir(localPath)); err == nil {
+			dir.Sync()
+			dir.Close()
+		}
+	}
+	return written, nil
+}
+
+// writeTemp fills the temporary file, has verify check it, copies the
+// original's metadata onto it and renames it over target
+func writeTemp(backend FileSystemInterface, target, tmpName string, content io.Reader, perm os.FileMode, info os.FileInfo, verify func() error) (int64, error) {
+	file, err := backend.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	written, err := io.Copy(file, content)
+	if syncer, ok := file.(interface{ Sync() error }); ok && err == nil {
+		err = syncer.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if verify != nil {
+		if err := verify(); err != nil {
+			return written, err
+		}
+	}
+
+	if tmpPath, ok := LocalPath(backend, tmpName); ok && info != nil {
+		// The create mode is subject to the umask, so set it explicitly
+		if err := os.Chmod(tmpPath, perm); err != nil {
+			return written, fmt.Errorf("failed to preserve file mode: %w", err)
+		}
+		if err := copyOwner(tmpPath, info); err != nil {
+			return written, fmt.Errorf("failed to preserve file ownership: %w", err)
+		}
+	}
+
+	if err := backend.Rename(tmpName, target); err != nil {
+		return written, fmt.Errorf("failed to replace file: %w", err)
+	}
+	return written, nil
+}
+
+// tempName returns a hidden, unique sibling name for name
+func tempName(name string) (string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to create temporary name: %w", err)
+	}
+	dir, base := path.Split(Clean(name))
+	return path.Join(dir, "."+base+"."+hex.EncodeToString(suffix)+".tmp"), nil
+}
+
+// isSymlink reports whether a local path is a symbolic link
+func isSymlink(localPath string) bool {
+	info, err := os.Lstat(localPath)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,85 @@
+package fs
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrSymlinkUnsupported is returned when a backend does not store symbolic links
+var ErrSymlinkUnsupported = errors.New("symbolic links are not supported on this storage")
+
+// Lstater is implemented by backends that can describe a symbolic link itself
+type Lstater interface {
+	Lstat(name string) (os.FileInfo, error)
+}
+
+// Lstat describes name without following a final symbolic link; backends
+// without links describe it with Stat
+func Lstat(backend FileSystemInterface, name string) (os.FileInfo, error) {
+	if lstater, ok := backend.(Lstater); ok {
+		return lstater.Lstat(name)
+	}
+	return backend.Stat(name)
+}
+
+// Readlinker is implemented by backends that store symbolic links
+type Readlinker interface {
+	Readlink(name string) (string, error)
+}
+
+// Readlink returns the target of the symbolic link name as stored on disk
+func Readlink(backend FileSystemInterface, name string) (string, error) {
+	if readlinker, ok := backend.(Readlinker); ok {
+		return readlinker.Readlink(name)
+	}
+	return "", ErrSymlinkUnsupported
+}
+
+// LinkTargeter is implemented by backends whose stored link targets can name
+// paths outside the backend
+type LinkTargeter interface {
+	LinkTarget(name string) (target string, inside bool, err error)
+}
+
+// LinkTarget returns where the symbolic link name points in a form safe to
+// show clients: relative targets as stored and absolute ones as virtual
+// paths. inside is false, and target empty, when the link points outside the
+// backend's root.
+func LinkTarget(backend FileSystemInterface, name string) (target string, inside bool, err error) {
+	if targeter, ok := backend.(LinkTargeter); ok {
+		return targeter.LinkTarget(name)
+	}
+	target, err = Readlink(backend, name)
+	if err != nil {
+		return "", false, err
+	}
+	return target, true, nil
+}
+
+// Symlinker is implemented by backends that can create symbolic links
+type Symlinker interface {
+	Symlink(target, name string) error
+}
+
+// Symlink creates name as a symbolic link to the path target on the same
+// backend
+func Symlink(backend FileSystemInterface, target, name string) error {
+	if symlinker, ok := backend.(Symlinker); ok {
+		return symlinker.Symlink(target, name)
+	}
+	return ErrSymlinkUnsupported
+}
+
+// SymlinkEvaluator is implemented by backends that can resolve symbolic links
+type SymlinkEvaluator interface {
+	EvalSymlinks(name string) (string, error)
+}
+
+// EvalSymlinks returns name with every symbolic link along it resolved.
+// Backends without links return the clean name.
+func EvalSymlinks(backend FileSystemInterface, name string) (string, error) {
+	if evaluator, ok := backend.(SymlinkEvaluator); ok {
+		return evaluator.EvalSymlinks(name)
+	}
+	return Clean(name), nil
+}
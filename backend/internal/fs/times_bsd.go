@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd
+
+package fs
+
+import (
+	"syscall"
+	"time"
+)
+
+// statTimes returns the access, change and birth times recorded in stat
+func statTimes(stat *syscall.Stat_t) (atime, ctime, btime time.Time) {
+	return timespecTime(stat.Atimespec), timespecTime(stat.Ctimespec), timespecTime(stat.Birthtimespec)
+}
+
+// birthTime is only needed when stat has none, which the BSDs always provide
+func birthTime(localPath string) time.Time {
+	return time.Time{}
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package fs
+
+import (
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// statTimes returns the access and change times; stat has no birth time on Linux
+func statTimes(stat *syscall.Stat_t) (atime, ctime, btime time.Time) {
+	return timespecTime(stat.Atim), timespecTime(stat.Ctim), time.Time{}
+}
+
+// birthTime asks statx for the birth time, which only some file systems record
+func birthTime(localPath string) time.Time {
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, localPath, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stx); err != nil {
+		return time.Time{}
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)).UTC()
+}
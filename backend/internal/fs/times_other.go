@@ -0,0 +1,18 @@
+//go:build unix && !linux && !darwin && !freebsd && !netbsd
+
+package fs
+
+import (
+	"syscall"
+	"time"
+)
+
+// statTimes leaves the times out where the layout of stat is not known
+func statTimes(stat *syscall.Stat_t) (atime, ctime, btime time.Time) {
+	return time.Time{}, time.Time{}, time.Time{}
+}
+
+// birthTime is not available on this platform
+func birthTime(localPath string) time.Time {
+	return time.Time{}
+}
@@ -4,29 +4,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 )
 
-// FileSystemInterface defines the contract for file system operations
-type FileSystemInterface interface {
-	ReadFileContent(path string) (string, error)
-	ListDirectory(path string) ([]os.DirEntry, error)
-	GetFileInfo(path string) (os.FileInfo, error)
-	IsDirectory(path string) bool
-	Exists(path string) bool
-	Delete(path string) error
-}
-
-// FileSystemUtils implements FileSystemInterface
-type FileSystemUtils struct{}
-
-// NewFileSystemUtils creates a new FileSystemUtils instance
-func NewFileSystemUtils() *FileSystemUtils {
-	return &FileSystemUtils{}
-}
-
 // ReadFileContent reads the entire content of a file
-func (fs *FileSystemUtils) ReadFileContent(path string) (string, error) {
-	file, err := os.Open(path)
+func ReadFileContent(backend FileSystemInterface, name string) (string, error) {
+	file, err := backend.OpenFile(name, os.O_RDONLY, 0)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %w", err)
 	}
@@ -40,27 +23,9 @@ func (fs *FileSystemUtils) ReadFileContent(path string) (string, error) {
 	return string(content), nil
 }
 
-// ListDirectory lists all entries in a directory
-func (fs *FileSystemUtils) ListDirectory(path string) ([]os.DirEntry, error) {
-	entries, err := os.ReadDir(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: %w", err)
-	}
-	return entries, nil
-}
-
-// GetFileInfo gets file information
-func (fs *FileSystemUtils) GetFileInfo(path string) (os.FileInfo, error) {
-	info, err := os.Stat(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
-	}
-	return info, nil
-}
-
 // IsDirectory checks if the path is a directory
-func (fs *FileSystemUtils) IsDirectory(path string) bool {
-	info, err := os.Stat(path)
+func IsDirectory(backend FileSystemInterface, name string) bool {
+	info, err := backend.Stat(name)
 	if err != nil {
 		return false
 	}
@@ -68,24 +33,24 @@ func (fs *FileSystemUtils) IsDirectory(path string) bool {
 }
 
 // Exists checks if a file or directory exists
-func (fs *FileSystemUtils) Exists(path string) bool {
-	_, err := os.Stat(path)
+func Exists(backend FileSystemInterface, name string) bool {
+	_, err := backend.Stat(name)
 	return err == nil
 }
 
 // Delete removes a file or directory
-func (fs *FileSystemUtils) Delete(path string) error {
-	info, err := os.Stat(path)
+func Delete(backend FileSystemInterface, name string) error {
+	info, err := backend.Stat(name)
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
 
 	if info.IsDir() {
 		// Remove directory and all its contents
-		err = os.RemoveAll(path)
+		err = backend.RemoveAll(name)
 	} else {
 		// Remove single file
-		err = os.Remove(path)
+		err = backend.Remove(name)
 	}
 
 	if err != nil {
@@ -94,3 +59,55 @@ func (fs *FileSystemUtils) Delete(path string) error {
 
 	return nil
 }
+
+// CreateFile creates a file for writing; with exclusive set it fails if the file already exists
+func CreateFile(backend FileSystemInterface, name string, exclusive bool) (File, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if exclusive {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+
+	file, err := backend.OpenFile(name, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return file, nil
+}
+
+// DiskUsage returns the total size of a file or of all files below a directory
+func DiskUsage(backend FileSystemInterface, name string) (int64, error) {
+	info, err := backend.Stat(name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute disk usage: %w", err)
+	}
+	if !info.IsDir() {
+		return info.Size(), nil
+	}
+
+	entries, err := backend.ReadDir(name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute disk usage: %w", err)
+	}
+
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			total += entry.Size()
+			continue
+		}
+		size, err := DiskUsage(backend, path.Join(name, entry.Name()))
+		total += size
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// LocalPath returns where name lives on disk; ok is false when the backend does not store it locally
+func LocalPath(backend FileSystemInterface, name string) (string, bool) {
+	if local, ok := backend.(LocalPather); ok {
+		return local.LocalPath(name)
+	}
+	return "", false
+}
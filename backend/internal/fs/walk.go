@@ -0,0 +1,45 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path"
+)
+
+// WalkFunc is called for every file and directory visited by Walk
+type WalkFunc func(name string, info os.FileInfo) error
+
+// Walk calls fn for name and, when it is a directory, everything below it,
+// parents before their children. Symbolic links are reported but never
+// followed. Walk stops at the first error from fn, from the backend or from ctx.
+func Walk(ctx context.Context, backend FileSystemInterface, name string, fn WalkFunc) error {
+	info, err := backend.Stat(name)
+	if err != nil {
+		return err
+	}
+	return walk(ctx, backend, name, info, fn)
+}
+
+// walk visits one entry and recurses into directories
+func walk(ctx context.Context, backend FileSystemInterface, name string, info os.FileInfo, fn WalkFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := fn(name, info); err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	entries, err := backend.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := walk(ctx, backend, path.Join(name, entry.Name()), entry, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
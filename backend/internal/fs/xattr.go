@@ -0,0 +1,51 @@
+package fs
+
+import "errors"
+
+var (
+	// ErrXattrUnsupported is returned when a backend or file system does not store extended attributes
+	ErrXattrUnsupported = errors.New("extended attributes are not supported on this storage")
+	// ErrNoXattr is returned for attributes a file does not have
+	ErrNoXattr = errors.New("extended attribute not found")
+)
+
+// Xattrer is implemented by backends that store extended attributes. They
+// act on symbolic links themselves rather than what they point to.
+type Xattrer interface {
+	ListXattrs(name string) ([]string, error)
+	GetXattr(name, attr string) ([]byte, error)
+	SetXattr(name, attr string, value []byte) error
+	RemoveXattr(name, attr string) error
+}
+
+// ListXattrs returns the names of the extended attributes of name
+func ListXattrs(backend FileSystemInterface, name string) ([]string, error) {
+	if xattrer, ok := backend.(Xattrer); ok {
+		return xattrer.ListXattrs(name)
+	}
+	return nil, ErrXattrUnsupported
+}
+
+// GetXattr returns the value of an extended attribute of name
+func GetXattr(backend FileSystemInterface, name, attr string) ([]byte, error) {
+	if xattrer, ok := backend.(Xattrer); ok {
+		return xattrer.GetXattr(name, attr)
+	}
+	return nil, ErrXattrUnsupported
+}
+
+// SetXattr creates or replaces an extended attribute of name
+func SetXattr(backend FileSystemInterface, name, attr string, value []byte) error {
+	if xattrer, ok := backend.(Xattrer); ok {
+		return xattrer.SetXattr(name, attr, value)
+	}
+	return ErrXattrUnsupported
+}
+
+// RemoveXattr removes an extended attribute of name
+func RemoveXattr(backend FileSystemInterface, name, attr string) error {
+	if xattrer, ok := backend.(Xattrer); ok {
+		return xattrer.RemoveXattr(name, attr)
+	}
+	return ErrXattrUnsupported
+}
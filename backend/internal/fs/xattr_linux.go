@@ -0,0 +1,85 @@
+//go:build linux
+
+package fs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// listXattrs returns the attribute names of the file at localPath
+func listXattrs(localPath string) ([]string, error) {
+	buf, err := readXattrBuffer(func(dest []byte) (int, error) {
+		return unix.Llistxattr(localPath, dest)
+	})
+	if err != nil {
+		return nil, xattrError("listxattr", localPath, err)
+	}
+
+	var names []string
+	for _, name := range bytes.Split(buf, []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+// getXattr returns the value of an attribute of the file at localPath
+func getXattr(localPath, attr string) ([]byte, error) {
+	value, err := readXattrBuffer(func(dest []byte) (int, error) {
+		return unix.Lgetxattr(localPath, attr, dest)
+	})
+	if err != nil {
+		return nil, xattrError("getxattr", localPath, err)
+	}
+	return value, nil
+}
+
+// setXattr creates or replaces an attribute of the file at localPath
+func setXattr(localPath, attr string, value []byte) error {
+	return xattrError("setxattr", localPath, unix.Lsetxattr(localPath, attr, value, 0))
+}
+
+// removeXattr removes an attribute of the file at localPath
+func removeXattr(localPath, attr string) error {
+	return xattrError("removexattr", localPath, unix.Lremovexattr(localPath, attr))
+}
+
+// readXattrBuffer asks read for the size first and retries while the value grows
+func readXattrBuffer(read func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := read(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return []byte{}, nil
+		}
+		buf := make([]byte, size)
+		n, err := read(buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// xattrError maps errno values to the package's errors
+func xattrError(op, localPath string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, unix.ENODATA):
+		return ErrNoXattr
+	case errors.Is(err, unix.ENOTSUP):
+		return ErrXattrUnsupported
+	}
+	return &os.PathError{Op: op, Path: localPath, Err: err}
+}
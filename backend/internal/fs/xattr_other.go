@@ -0,0 +1,23 @@
+//go:build !linux
+
+package fs
+
+// listXattrs is only implemented on Linux
+func listXattrs(localPath string) ([]string, error) {
+	return nil, ErrXattrUnsupported
+}
+
+// getXattr is only implemented on Linux
+func getXattr(localPath, attr string) ([]byte, error) {
+	return nil, ErrXattrUnsupported
+}
+
+// setXattr is only implemented on Linux
+func setXattr(localPath, attr string, value []byte) error {
+	return ErrXattrUnsupported
+}
+
+// removeXattr is only implemented on Linux
+func removeXattr(localPath, attr string) error {
+	return ErrXattrUnsupported
+}
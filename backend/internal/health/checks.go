@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+)
+
+// defaultMinFreeMB is the free space threshold used when HEALTH_MIN_FREE_MB is unset
+const defaultMinFreeMB = 100
+
+// RegisterStorageChecks registers the base path readiness checks on the default
+// checker. HEALTH_REQUIRE_WRITABLE enables the write probe and HEALTH_MIN_FREE_MB
+// sets the free space threshold (0 disables it).
+func RegisterStorageChecks(basePath string) {
+	Register("base_path", BasePathCheck(basePath))
+
+	if requireWritable, _ := strconv.ParseBool(os.Getenv("HEALTH_REQUIRE_WRITABLE")); requireWritable {
+		Register("base_path_writable", WritableCheck(basePath))
+	}
+
+	if minFreeMB := config.EnvInt("HEALTH_MIN_FREE_MB", defaultMinFreeMB, 0); minFreeMB > 0 {
+		Register("disk_space", DiskSpaceCheck(basePath, uint64(minFreeMB)*1024*1024))
+	}
+}
+
+// BasePathCheck verifies that the base path exists, is a directory and is readable
+func BasePathCheck(basePath string) CheckFunc {
+	return func(ctx context.Context) error {
+		info, err := os.Stat(basePath)
+		if err != nil {
+			return fmt.Errorf("base path not mounted: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("base path is not a directory: %s", basePath)
+		}
+
+		dir, err := os.Open(basePath)
+		if err != nil {
+			return fmt.Errorf("base path not readable: %w", err)
+		}
+		defer dir.Close()
+
+		if _, err := dir.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("base path not readable: %w", err)
+		}
+		return nil
+	}
+}
+
+// ProbePrefix starts the names of the files created by WritableCheck; the
+// directory watches ignore them so probes do not show up as changes
+const ProbePrefix = ".readyz-"
+
+// WritableCheck verifies that a file can be created and removed in the base path
+func WritableCheck(basePath string) CheckFunc {
+	return func(ctx context.Context) error {
+		file, err := os.CreateTemp(basePath, ProbePrefix+"*")
+		if err != nil {
+			return fmt.Errorf("base path not writable: %w", err)
+		}
+		name := file.Name()
+		file.Close()
+
+		if err := os.Remove(name); err != nil {
+			return fmt.Errorf("failed to remove probe file %s: %w", filepath.Base(name), err)
+		}
+		return nil
+	}
+}
+
+// DiskSpaceCheck verifies that the filesystem holding path has at least minFree bytes available
+func DiskSpaceCheck(path string, minFree uint64) CheckFunc {
+	return func(ctx context.Context) error {
+		free, err := freeBytes(path)
+		if err != nil {
+			return fmt.Errorf("failed to get free disk space: %w", err)
+		}
+		if free < minFree {
+			return fmt.Errorf("low disk space: %d bytes free (min: %d bytes)", free, minFree)
+		}
+		return nil
+	}
+}
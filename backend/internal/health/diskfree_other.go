@@ -0,0 +1,10 @@
+//go:build !unix
+
+package health
+
+import "errors"
+
+// freeBytes is not supported on this platform
+func freeBytes(path string) (uint64, error) {
+	return 0, errors.New("disk space check not supported on this platform")
+}
@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckFunc reports whether a dependency is ready; a nil error means healthy
+type CheckFunc func(ctx context.Context) error
+
+// Checker aggregates readiness checks and tracks the shutdown state
+type Checker struct {
+	mu           sync.RWMutex
+	checks       map[string]CheckFunc
+	shuttingDown atomic.Bool
+	startTime    time.Time
+}
+
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Response is the JSON body returned by /healthz and /readyz
+type Response struct {
+	Status    string                 `json:"status"`
+	Uptime    string                 `json:"uptime"`
+	Checks    map[string]CheckResult `json:"checks,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+const (
+	statusOK       = "ok"
+	statusFailing  = "failing"
+	statusShutdown = "shutting_down"
+
+	checkTimeout = 5 * time.Second
+)
+
+// Default is the process-wide checker used by subsystems to register checks
+var Default = NewChecker()
+
+// NewChecker creates an empty checker
+func NewChecker() *Checker {
+	return &Checker{
+		checks:    make(map[string]CheckFunc),
+		startTime: time.Now(),
+	}
+}
+
+// Register adds or replaces a named readiness check
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Register adds a named readiness check to the default checker
+func Register(name string, check CheckFunc) {
+	Default.Register(name, check)
+}
+
+// MarkShuttingDown makes readiness fail so load balancers stop sending traffic
+func (c *Checker) MarkShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// MarkShuttingDown flips the default checker into the shutdown state
+func MarkShuttingDown() {
+	Default.MarkShuttingDown()
+}
+
+// Ready runs every registered check and reports the overall readiness
+func (c *Checker) Ready(ctx context.Context) (bool, map[string]CheckResult) {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]CheckFunc, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	ready := true
+	results := make(map[string]CheckResult, len(names))
+	for i, name := range names {
+		start := time.Now()
+		err := checks[i](ctx)
+
+		result := CheckResult{Status: statusOK, Duration: time.Since(start).String()}
+		if err != nil {
+			ready = false
+			result.Status = statusFailing
+			result.Error = err.Error()
+		}
+		results[name] = result
+	}
+
+	return ready, results
+}
+
+// LivenessHandler serves /healthz, which only reports that the process is alive
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		c.writeResponse(w, Response{
+			Status:    statusOK,
+			Uptime:    time.Since(c.startTime).Round(time.Second).String(),
+			Timestamp: time.Now(),
+		}, http.StatusOK)
+	})
+}
+
+// ReadinessHandler serves /readyz, which runs every registered check
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		response := Response{
+			Status:    statusOK,
+			Uptime:    time.Since(c.startTime).Round(time.Second).String(),
+			Timestamp: time.Now(),
+		}
+
+		if c.shuttingDown.Load() {
+			response.Status = statusShutdown
+			c.writeResponse(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		ready, results := c.Ready(r.Context())
+		response.Checks = results
+
+		statusCode := http.StatusOK
+		if !ready {
+			response.Status = statusFailing
+			statusCode = http.StatusServiceUnavailable
+		}
+		c.writeResponse(w, response, statusCode)
+	})
+}
+
+// writeResponse encodes a health response as JSON
+func (c *Checker) writeResponse(w http.ResponseWriter, response Response, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("failed to encode health response", "error", err)
+	}
+}
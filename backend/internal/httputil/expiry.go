@@ -0,0 +1,33 @@
+package httputil
+
+import (
+	"errors"
+	"time"
+)
+
+// ResolveExpiry turns an optional relative duration or absolute time from a
+// request body into an absolute expiry; both unset means no expiry
+func ResolveExpiry(expiresIn string, expiresAt *time.Time) (*time.Time, error) {
+	if expiresIn != "" && expiresAt != nil {
+		return nil, errors.New("expiresIn and expiresAt are mutually exclusive")
+	}
+
+	if expiresIn != "" {
+		duration, err := time.ParseDuration(expiresIn)
+		if err != nil || duration <= 0 {
+			return nil, errors.New("expiresIn must be a positive duration such as 72h")
+		}
+		expiry := time.Now().Add(duration).UTC()
+		return &expiry, nil
+	}
+
+	if expiresAt != nil {
+		if expiresAt.Before(time.Now()) {
+			return nil, errors.New("expiresAt must be in the future")
+		}
+		expiry := expiresAt.UTC()
+		return &expiry, nil
+	}
+
+	return nil, nil
+}
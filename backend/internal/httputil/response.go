@@ -0,0 +1,33 @@
+package httputil
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// SendJSON sends a JSON response with proper headers
+func SendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// SendError sends an error response in the API's standard format
+func SendError(w http.ResponseWriter, message string, statusCode int) {
+	SendJSON(w, map[string]interface{}{
+		"success": false,
+		"error":   message,
+		"code":    statusCode,
+	}, statusCode)
+}
+
+// DecodeJSON decodes a JSON request body, rejecting unknown fields and bodies over maxBytes
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dst)
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/httputil"
+)
+
+// JobResponse is returned by GET and DELETE /file/jobs?id=
+type JobResponse struct {
+	Success     bool      `json:"success"`
+	Job         Job       `json:"job"`
+	RequestTime time.Time `json:"requestTime"`
+}
+
+// JobsResponse is returned by GET /file/jobs
+type JobsResponse struct {
+	Success     bool      `json:"success"`
+	Jobs        []Job     `json:"jobs"`
+	RequestTime time.Time `json:"requestTime"`
+}
+
+// Handler serves /file/jobs: GET lists jobs or polls one by id, DELETE
+// cancels a job or forgets a finished one. Users only see their own jobs,
+// admins see all of them.
+func Handler(m *Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+			httputil.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		identity := auth.FromContext(r.Context())
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			if r.Method == http.MethodDelete {
+				httputil.SendError(w, "Job id is required", http.StatusBadRequest)
+				return
+			}
+
+			username := identity.Username
+			if identity.IsAdmin() {
+				username = r.URL.Query().Get("user")
+			}
+			list := m.List(username)
+			if status := r.URL.Query().Get("status"); status != "" {
+				filtered := list[:0]
+				for _, job := range list {
+					if job.Status == status {
+						filtered = append(filtered, job)
+					}
+				}
+				list = filtered
+			}
+
+			httputil.SendJSON(w, JobsResponse{
+				Success:     true,
+				Jobs:        list,
+				RequestTime: time.Now(),
+			}, http.StatusOK)
+			return
+		}
+
+		job, err := m.Get(id)
+		if err != nil || (job.CreatedBy != identity.Username && !identity.IsAdmin()) {
+			httputil.SendError(w, "Job not found", http.StatusNotFound)
+			return
+		}
+
+		status := http.StatusOK
+		if r.Method == http.MethodDelete {
+			if job, err = m.Cancel(id); err != nil {
+				httputil.SendError(w, "Job not found", http.StatusNotFound)
+				return
+			}
+			// A running job stops once it reaches a safe point
+			if !job.Finished() {
+				status = http.StatusAccepted
+			}
+		}
+
+		httputil.SendJSON(w, JobResponse{
+			Success:     true,
+			Job:         job,
+			RequestTime: time.Now(),
+		}, status)
+	})
+}
@@ -0,0 +1,435 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/statefile"
+)
+
+// Job states
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+	// StatusInterrupted marks jobs that were stopped by a server shutdown
+	StatusInterrupted = "interrupted"
+)
+
+// finishedRetention is how long finished jobs stay available for polling
+const finishedRetention = time.Hour
+
+// progressSaveInterval is how often progress reports are persisted, so a job
+// stopped by a crash still shows roughly how far it got
+const progressSaveInterval = 5 * time.Second
+
+// Defaults for Setup
+const (
+	defaultWorkers   = 4
+	defaultMaxQueued = 100
+)
+
+var (
+	// ErrNotFound is returned for unknown or expired job IDs
+	ErrNotFound = errors.New("job not found")
+	// ErrQueueFull is returned by Start when too many jobs are waiting for a worker
+	ErrQueueFull = errors.New("too many background jobs queued")
+	// ErrClosed is returned by Start once the manager is shutting down
+	ErrClosed = errors.New("background jobs are shutting down")
+)
+
+// Progress reports how much of a job is done, in units chosen by the job (bytes, files, ...)
+type Progress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+// Job is a snapshot of a background operation
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Target     string      `json:"target"`
+	Status     string      `json:"status"`
+	Progress   Progress    `json:"progress"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedBy  string      `json:"createdBy"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	StartedAt  *time.Time  `json:"startedAt,omitempty"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// Finished reports whether the job has stopped running
+func (j *Job) Finished() bool {
+	return j.Status != StatusQueued && j.Status != StatusRunning
+}
+
+// ReportFunc updates the progress of the running job
+type ReportFunc func(done, total int64)
+
+// Func is the work of a job; its result is returned to clients polling the job.
+// ctx is canceled when the job is canceled or the server shuts down; the job
+// should then stop at the next safe point. A result returned along with the
+// cancellation is kept, so jobs can report what they finished.
+type Func func(ctx context.Context, report ReportFunc) (interface{}, error)
+
+// task is a queued or running job
+type task struct {
+	job    *Job
+	fn     Func
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Manager runs jobs on a bounded number of workers and keeps their state for
+// polling, persisted so finished jobs survive restarts. Jobs are canceled
+// cleanly on shutdown but never resumed; unfinished ones come back as
+// interrupted with their last saved progress.
+type Manager struct {
+	// path is the state file; empty for in-memory managers
+	path      string
+	workers   int
+	maxQueued int
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	tasks   map[string]*task
+	pending []*task
+	running int
+	closed  bool
+	wg      sync.WaitGroup
+	// savedAt is when the state was last persisted
+	savedAt time.Time
+}
+
+// Default is the process-wide job manager
+var Default = NewManager(defaultWorkers, defaultMaxQueued)
+
+// Setup replaces Default with a manager persisted in the state directory,
+// configured from JOBS_WORKERS and JOBS_MAX_QUEUED
+func Setup() error {
+	manager, err := Open(config.StateDir("jobs.json"),
+		config.EnvInt("JOBS_WORKERS", defaultWorkers, 1), config.EnvInt("JOBS_MAX_QUEUED", defaultMaxQueued, 1))
+	if err != nil {
+		return err
+	}
+	Default = manager
+	return nil
+}
+
+// NewManager creates an empty in-memory job manager running at most workers
+// jobs at a time, with at most maxQueued more waiting
+func NewManager(workers, maxQueued int) *Manager {
+	return &Manager{
+		workers:   workers,
+		maxQueued: maxQueued,
+		jobs:      make(map[string]*Job),
+		tasks:     make(map[string]*task),
+	}
+}
+
+// Open loads the jobs persisted at path. Jobs that were still queued or
+// running when the server stopped are marked interrupted; they are not resumed.
+func Open(path string, workers, maxQueued int) (*Manager, error) {
+	m := NewManager(workers, maxQueued)
+	m.path = path
+	if _, err := statefile.Load(path, &m.jobs); err != nil {
+		return nil, fmt.Errorf("failed to load jobs: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, job := range m.jobs {
+		if !job.Finished() {
+			interrupt(job, now)
+		}
+	}
+	m.removeExpired()
+	return m, nil
+}
+
+// Start queues fn as a new job and runs it as soon as a worker is free. The
+// job keeps the caller's identity and request ID but is not canceled when
+// the request ends.
+func (m *Manager) Start(ctx context.Context, kind, target string, fn Func) (Job, error) {
+	id, err := auth.RandomToken(12)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	job := &Job{
+		ID:        id,
+		Kind:      kind,
+		Target:    target,
+		Status:    StatusQueued,
+		CreatedBy: auth.FromContext(ctx).Username,
+		CreatedAt: time.Now().UTC(),
+	}
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	t := &task{job: job, fn: fn, ctx: jobCtx, cancel: cancel}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		cancel()
+		return Job{}, ErrClosed
+	}
+	if m.running >= m.workers && len(m.pending) >= m.maxQueued {
+		cancel()
+		return Job{}, ErrQueueFull
+	}
+
+	m.removeExpired()
+	m.jobs[id] = job
+	m.tasks[id] = t
+	if m.running < m.workers {
+		m.running++
+		m.begin(t)
+		m.wg.Add(1)
+		go m.work(t)
+	} else {
+		m.pending = append(m.pending, t)
+	}
+	m.updateMetrics()
+	m.save()
+	return *job, nil
+}
+
+// begin marks a task as running; the caller holds the lock
+func (m *Manager) begin(t *task) {
+	started := time.Now().UTC()
+	t.job.Status = StatusRunning
+	t.job.StartedAt = &started
+}
+
+// work runs a task and then queued tasks until none are left
+func (m *Manager) work(t *task) {
+	defer m.wg.Done()
+	for ; t != nil; t = m.next() {
+		m.run(t)
+	}
+}
+
+// next takes the next queued task, or gives up the worker
+func (m *Manager) next() *task {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed || len(m.pending) == 0 {
+		m.running--
+		m.updateMetrics()
+		return nil
+	}
+	t := m.pending[0]
+	m.pending = m.pending[1:]
+	m.begin(t)
+	m.updateMetrics()
+	m.save()
+	return t
+}
+
+// run executes a job and records its outcome
+func (m *Manager) run(t *task) {
+	job := t.job
+	report := func(done, total int64) {
+		m.mu.Lock()
+		job.Progress = Progress{Done: done, Total: total}
+		if time.Since(m.savedAt) >= progressSaveInterval {
+			m.save()
+		}
+		m.mu.Unlock()
+	}
+
+	result, err := t.fn(t.ctx, report)
+	canceled := t.ctx.Err() != nil
+	t.cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.tasks, job.ID)
+	if job.Finished() {
+		// Given up on by Shutdown
+		return
+	}
+
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	log := logging.FromContext(t.ctx)
+	switch {
+	case canceled && m.closed:
+		interrupt(job, finished)
+		job.Result = result
+		log.Info("job interrupted by shutdown", "job", job.ID, "kind", job.Kind, "target", job.Target)
+	case canceled:
+		job.Status = StatusCanceled
+		job.Error = "job was canceled"
+		job.Result = result
+		log.Info("job canceled", "job", job.ID, "kind", job.Kind, "target", job.Target)
+	case err != nil:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		log.Warn("job failed", "job", job.ID, "kind", job.Kind, "target", job.Target, "error", err)
+	default:
+		job.Status = StatusSucceeded
+		job.Result = result
+		log.Debug("job finished", "job", job.ID, "kind", job.Kind, "duration", finished.Sub(job.CreatedAt))
+	}
+	m.save()
+}
+
+// interrupt marks a job as stopped by a shutdown
+func interrupt(job *Job, now time.Time) {
+	job.Status = StatusInterrupted
+	job.Error = "server stopped before the job finished"
+	job.FinishedAt = &now
+}
+
+// Get returns a snapshot of a job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// List returns snapshots of the jobs created by username, or of all jobs when
+// username is empty, newest first
+func (m *Manager) List(username string) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeExpired()
+	list := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if username == "" || job.CreatedBy == username {
+			list = append(list, *job)
+		}
+	}
+	sort.Slice(list, func(i, k int) bool {
+		return list[i].CreatedAt.After(list[k].CreatedAt)
+	})
+	return list
+}
+
+// Cancel stops a queued or running job, or forgets a finished one. Queued
+// jobs are canceled right away; running jobs are canceled once they reach a
+// safe point, so the returned snapshot may still be running.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, ErrNotFound
+	}
+
+	if job.Finished() {
+		delete(m.jobs, id)
+		m.save()
+		return *job, nil
+	}
+
+	t := m.tasks[id]
+	t.cancel()
+	if job.Status == StatusQueued {
+		for i, queued := range m.pending {
+			if queued == t {
+				m.pending = append(m.pending[:i], m.pending[i+1:]...)
+				break
+			}
+		}
+		delete(m.tasks, id)
+		finished := time.Now().UTC()
+		job.Status = StatusCanceled
+		job.Error = "job was canceled"
+		job.FinishedAt = &finished
+		m.updateMetrics()
+		m.save()
+	}
+	return *job, nil
+}
+
+// Shutdown stops accepting jobs, cancels queued and running ones and waits
+// for running jobs to return what they finished. Jobs still running when ctx
+// ends are recorded as interrupted and left behind.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	now := time.Now().UTC()
+	for _, t := range m.pending {
+		interrupt(t.job, now)
+		delete(m.tasks, t.job.ID)
+	}
+	m.pending = nil
+	for _, t := range m.tasks {
+		t.cancel()
+	}
+	m.updateMetrics()
+	m.save()
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		now := time.Now().UTC()
+		for _, t := range m.tasks {
+			interrupt(t.job, now)
+		}
+		m.save()
+		return fmt.Errorf("%d jobs did not stop in time: %w", len(m.tasks), ctx.Err())
+	}
+}
+
+// updateMetrics publishes the queue depth and number of running jobs; the
+// caller holds the lock
+func (m *Manager) updateMetrics() {
+	queueDepth.Set(float64(len(m.pending)))
+	runningJobs.Set(float64(m.running))
+}
+
+// save persists the jobs; the caller holds the lock. Failures are logged
+// since the jobs themselves keep running.
+func (m *Manager) save() {
+	if m.path == "" {
+		return
+	}
+	m.savedAt = time.Now()
+	if err := statefile.Save(m.path, m.jobs); err != nil {
+		slog.Error("failed to save jobs", "error", err)
+	}
+}
+
+// removeExpired forgets jobs that finished more than finishedRetention ago;
+// the caller holds the lock
+func (m *Manager) removeExpired() {
+	cutoff := time.Now().Add(-finishedRetention)
+	for id, job := range m.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
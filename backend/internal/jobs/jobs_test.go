@@ -0,0 +1,205 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+)
+
+// waitFinished polls a job until it stops running
+func waitFinished(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if job.Finished() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+// blockingJob runs until release is closed or the job is canceled, and then
+// returns what it finished
+func blockingJob(started chan<- struct{}, release <-chan struct{}) Func {
+	return func(ctx context.Context, report ReportFunc) (interface{}, error) {
+		report(1, 2)
+		if started != nil {
+			close(started)
+		}
+		select {
+		case <-release:
+			return "done", nil
+		case <-ctx.Done():
+			return "partial", ctx.Err()
+		}
+	}
+}
+
+func TestManagerRunsJobs(t *testing.T) {
+	m := NewManager(2, 10)
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Username: "alice", Role: auth.RoleUser})
+
+	job, err := m.Start(ctx, "checksum", "/file.iso", func(ctx context.Context, report ReportFunc) (interface{}, error) {
+		report(10, 10)
+		return "sum", nil
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if job.CreatedBy != "alice" || job.Kind != "checksum" || job.Target != "/file.iso" {
+		t.Fatalf("Start() = %+v, want alice's checksum of /file.iso", job)
+	}
+
+	done := waitFinished(t, m, job.ID)
+	if done.Status != StatusSucceeded || done.Result != "sum" || done.Progress != (Progress{Done: 10, Total: 10}) {
+		t.Fatalf("finished job = %+v, want succeeded with its result and progress", done)
+	}
+
+	failed, _ := m.Start(ctx, "checksum", "/bad", func(ctx context.Context, report ReportFunc) (interface{}, error) {
+		return nil, errors.New("read error")
+	})
+	if done := waitFinished(t, m, failed.ID); done.Status != StatusFailed || done.Error != "read error" {
+		t.Fatalf("failed job = %+v, want failed with its error", done)
+	}
+
+	if got := m.List("alice"); len(got) != 2 || got[0].ID != failed.ID {
+		t.Fatalf("List(alice) = %+v, want both jobs newest first", got)
+	}
+	if got := m.List("bob"); len(got) != 0 {
+		t.Fatalf("List(bob) = %+v, want none", got)
+	}
+}
+
+func TestManagerQueue(t *testing.T) {
+	m := NewManager(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	running, err := m.Start(context.Background(), "scan", "/a", blockingJob(started, release))
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-started
+	queued, err := m.Start(context.Background(), "scan", "/b", blockingJob(nil, release))
+	if err != nil {
+		t.Fatalf("Start() of a queued job error = %v", err)
+	}
+	if queued.Status != StatusQueued {
+		t.Fatalf("second job status = %s, want %s", queued.Status, StatusQueued)
+	}
+	if _, err := m.Start(context.Background(), "scan", "/c", blockingJob(nil, release)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Start() with a full queue error = %v, want %v", err, ErrQueueFull)
+	}
+
+	close(release)
+	for _, id := range []string{running.ID, queued.ID} {
+		if job := waitFinished(t, m, id); job.Status != StatusSucceeded {
+			t.Fatalf("job %s status = %s, want %s", id, job.Status, StatusSucceeded)
+		}
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	m := NewManager(1, 10)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+
+	running, _ := m.Start(context.Background(), "batch", "/", blockingJob(started, release))
+	<-started
+	queued, _ := m.Start(context.Background(), "batch", "/", blockingJob(nil, release))
+
+	job, err := m.Cancel(queued.ID)
+	if err != nil || job.Status != StatusCanceled {
+		t.Fatalf("Cancel() of a queued job = %+v, %v, want canceled right away", job, err)
+	}
+
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatalf("Cancel() of a running job error = %v", err)
+	}
+	job = waitFinished(t, m, running.ID)
+	if job.Status != StatusCanceled || job.Result != "partial" {
+		t.Fatalf("canceled job = %+v, want canceled with its partial result", job)
+	}
+
+	// Canceling a finished job forgets it
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatalf("Cancel() of a finished job error = %v", err)
+	}
+	if _, err := m.Get(running.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of a forgotten job error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestManagerShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	m, err := Open(path, 1, 10)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+
+	running, _ := m.Start(context.Background(), "scan", "/a", blockingJob(started, release))
+	<-started
+	queued, _ := m.Start(context.Background(), "scan", "/b", blockingJob(nil, release))
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	for _, id := range []string{running.ID, queued.ID} {
+		if job, _ := m.Get(id); job.Status != StatusInterrupted {
+			t.Fatalf("job %s status = %s, want %s", id, job.Status, StatusInterrupted)
+		}
+	}
+	if job, _ := m.Get(running.ID); job.Result != "partial" || job.Progress.Done != 1 {
+		t.Fatalf("interrupted job = %+v, want its partial result and progress", job)
+	}
+	if _, err := m.Start(context.Background(), "scan", "/c", blockingJob(nil, release)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Start() after Shutdown() error = %v, want %v", err, ErrClosed)
+	}
+
+	// The interrupted jobs survive a restart but are not run again
+	reopened, err := Open(path, 1, 10)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if job, err := reopened.Get(running.ID); err != nil || job.Status != StatusInterrupted || job.Result != "partial" {
+		t.Fatalf("reopened job = %+v, %v, want interrupted with its partial result", job, err)
+	}
+}
+
+func TestOpenInterruptsUnfinishedJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	m, err := Open(path, 1, 10)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	// Simulate a crash: the state file still says running
+	job, _ := m.Start(context.Background(), "scan", "/a", blockingJob(started, release))
+	<-started
+
+	reopened, err := Open(path, 1, 10)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	got, err := reopened.Get(job.ID)
+	if err != nil || got.Status != StatusInterrupted || got.FinishedAt == nil {
+		t.Fatalf("reopened job = %+v, %v, want interrupted", got, err)
+	}
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"github.com/BomScoob12/homelab-file-manager/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// queueDepth reports the number of background jobs waiting to run
+	queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Name:      "job_queue_depth",
+		Help:      "Number of background jobs waiting for a worker.",
+	})
+
+	// runningJobs reports the number of background jobs currently running
+	runningJobs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Name:      "jobs_running",
+		Help:      "Number of background jobs currently running.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(queueDepth, runningJobs)
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the header used to receive and echo request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// ResponseWriter wraps http.ResponseWriter to record status code and bytes written
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// NewResponseWriter wraps w for status and size tracking
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+// WriteHeader records the status code before delegating
+func (rw *ResponseWriter) WriteHeader(statusCode int) {
+	if rw.status == 0 {
+		rw.status = statusCode
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the number of bytes written before delegating
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Status returns the recorded status code
+func (rw *ResponseWriter) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+// BytesWritten returns the number of body bytes written
+func (rw *ResponseWriter) BytesWritten() int64 {
+	return rw.bytes
+}
+
+// Flush implements http.Flusher when the underlying writer supports it
+func (rw *ResponseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer supports it
+func (rw *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Middleware assigns a request ID to every request and writes an access log line
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = NewRequestID()
+		}
+
+		ctx := WithRequestID(r.Context(), requestID)
+		w.Header().Set(RequestIDHeader, requestID)
+
+		rw := NewResponseWriter(w)
+		start := time.Now()
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		FromContext(ctx).Info("request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rw.Status(),
+			"bytes", rw.BytesWritten(),
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// isValidRequestID accepts client-supplied IDs that are short and printable
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
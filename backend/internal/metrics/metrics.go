@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric name, including those registered by the
+// packages that feed them
+const Namespace = "filemanager"
+
+// Registry holds every collector exported by the service
+var Registry = prometheus.NewRegistry()
+
+var (
+	// RequestsTotal counts HTTP requests by route, method and status code
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	// RequestDuration observes HTTP request latency by route and method
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// RequestsInFlight tracks the number of requests currently being served
+	RequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests currently being served.",
+	})
+
+	// RawBytesServed counts file bytes streamed by /file/raw ("raw") and
+	// public share downloads ("share")
+	RawBytesServed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "raw_bytes_served_total",
+		Help:      "Total number of file bytes streamed by /file/raw (source=raw) and public share downloads (source=share).",
+	}, []string{"source"})
+
+	// ServiceErrors counts service errors by the classification returned to clients
+	ServiceErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "service_errors_total",
+		Help:      "Service layer errors by classification.",
+	}, []string{"class"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RequestsTotal,
+		RequestDuration,
+		RequestsInFlight,
+		RawBytesServed,
+		ServiceErrors,
+	)
+}
+
+// InstrumentRoute records request count, latency and in-flight gauge for a route
+func InstrumentRoute(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RequestsInFlight.Inc()
+		defer RequestsInFlight.Dec()
+
+		rw := logging.NewResponseWriter(w)
+		start := time.Now()
+
+		next.ServeHTTP(rw, r)
+
+		RequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		RequestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(rw.Status())).Inc()
+	})
+}
+
+// Handler returns the /metrics handler, protected by basic auth when
+// METRICS_USERNAME and METRICS_PASSWORD are set
+func Handler() http.Handler {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+
+	username := os.Getenv("METRICS_USERNAME")
+	password := os.Getenv("METRICS_PASSWORD")
+	if username == "" && password == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// ListenAddr returns the dedicated metrics listen address, if configured
+func ListenAddr() string {
+	return os.Getenv("METRICS_ADDR")
+}
@@ -0,0 +1,119 @@
+package mounts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/health"
+	"github.com/BomScoob12/homelab-file-manager/internal/objectfs"
+	"github.com/BomScoob12/homelab-file-manager/internal/remotefs"
+)
+
+// Mount types
+const (
+	TypeLocal  = "local"
+	TypeS3     = "s3"
+	TypeRemote = "remote"
+)
+
+// Mount describes one backend mounted as a top-level directory
+type Mount struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+
+	// Local mounts
+	Root string `json:"root,omitempty"`
+
+	// S3 mounts
+	Endpoint        string `json:"endpoint,omitempty"`
+	Bucket          string `json:"bucket,omitempty"`
+	Prefix          string `json:"prefix,omitempty"`
+	Region          string `json:"region,omitempty"`
+	AccessKeyID     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	UseSSL          bool   `json:"useSSL,omitempty"`
+
+	// Remote mounts: another instance's base URL and an API token it accepts
+	URL   string `json:"url,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+// mountsFile is the on-disk format of FILE_MANAGER_MOUNTS_FILE
+type mountsFile struct {
+	Mounts []Mount `json:"mounts"`
+}
+
+// checker is implemented by backends that can verify their remote end is reachable
+type checker interface {
+	Check(ctx context.Context) error
+}
+
+// Setup builds the storage backend: the base path, plus the mounts listed in
+// FILE_MANAGER_MOUNTS_FILE. Each mount gets a readiness check.
+func Setup() (fs.FileSystemInterface, error) {
+	root := fs.NewLocalFS(config.BasePath())
+
+	mountsPath := os.Getenv("FILE_MANAGER_MOUNTS_FILE")
+	if mountsPath == "" {
+		return root, nil
+	}
+
+	data, err := os.ReadFile(mountsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mounts file: %w", err)
+	}
+	var file mountsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse mounts file: %w", err)
+	}
+
+	mountFS := fs.NewMountFS(root)
+	for _, mount := range file.Mounts {
+		backend, err := NewBackend(mount)
+		if err != nil {
+			return nil, fmt.Errorf("mount %s: %w", mount.Path, err)
+		}
+		if err := mountFS.Mount(mount.Path, backend); err != nil {
+			return nil, err
+		}
+
+		if c, ok := backend.(checker); ok {
+			health.Register("mount:"+fs.Clean(mount.Path), c.Check)
+		}
+		slog.Info("mounted storage backend", "path", fs.Clean(mount.Path), "type", mount.Type)
+	}
+	return mountFS, nil
+}
+
+// NewBackend creates the backend for a mount; credentials may reference
+// environment variables as ${NAME} so secrets stay out of the file
+func NewBackend(mount Mount) (fs.FileSystemInterface, error) {
+	switch mount.Type {
+	case TypeLocal:
+		if mount.Root == "" {
+			return nil, fmt.Errorf("local mounts require a root directory")
+		}
+		return fs.NewLocalFS(mount.Root), nil
+	case TypeS3:
+		return objectfs.New(objectfs.Config{
+			Endpoint:        mount.Endpoint,
+			Bucket:          mount.Bucket,
+			Prefix:          mount.Prefix,
+			Region:          mount.Region,
+			AccessKeyID:     os.ExpandEnv(mount.AccessKeyID),
+			SecretAccessKey: os.ExpandEnv(mount.SecretAccessKey),
+			UseSSL:          mount.UseSSL,
+		})
+	case TypeRemote:
+		return remotefs.New(remotefs.Config{
+			URL:   mount.URL,
+			Token: os.ExpandEnv(mount.Token),
+		})
+	}
+	return nil, fmt.Errorf("unknown mount type %q", mount.Type)
+}
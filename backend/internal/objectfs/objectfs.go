@@ -0,0 +1,563 @@
+package objectfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	// requestTimeout bounds metadata requests; object bodies stream without a deadline
+	requestTimeout = 30 * time.Second
+	// uploadPartSize is the multipart chunk size for uploads of unknown length,
+	// which is also how much of each upload is buffered in memory
+	uploadPartSize = 16 << 20
+	// maxCopySize is the largest object S3 copies in a single request
+	maxCopySize = 5 << 30
+	// dirMarkerMode is reported for prefixes, which have no metadata of their own
+	dirMarkerMode = os.ModeDir | 0755
+)
+
+// Config describes an S3-compatible bucket, optionally restricted to a key prefix
+type Config struct {
+	Endpoint        string
+	Bucket          string
+	Prefix          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UseSSL          bool
+}
+
+// ObjectFS is a storage backend over an S3-compatible bucket. Key prefixes
+// ending in "/" are directories; empty "dir/" marker objects keep empty
+// directories visible. Renames are copy followed by delete and are not atomic.
+type ObjectFS struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// New connects to the bucket described by cfg
+func New(cfg Config) (*ObjectFS, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("object storage requires an endpoint and a bucket")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create object storage client: %w", err)
+	}
+
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &ObjectFS{client: client, bucket: cfg.Bucket, prefix: prefix}, nil
+}
+
+// Check verifies that the bucket is reachable
+func (o *ObjectFS) Check(ctx context.Context) error {
+	exists, err := o.client.BucketExists(ctx, o.bucket)
+	if err != nil {
+		return fmt.Errorf("bucket %s unreachable: %w", o.bucket, err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", o.bucket)
+	}
+	return nil
+}
+
+// objectKey maps a virtual file path to its object key
+func (o *ObjectFS) objectKey(name string) string {
+	return o.prefix + strings.TrimPrefix(fs.Clean(name), "/")
+}
+
+// dirKey maps a virtual directory path to the key prefix of its contents
+func (o *ObjectFS) dirKey(name string) string {
+	if fs.Clean(name) == "/" {
+		return o.prefix
+	}
+	return o.objectKey(name) + "/"
+}
+
+// Stat returns information for an object or a prefix
+func (o *ObjectFS) Stat(name string) (os.FileInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return o.stat(ctx, "stat", name)
+}
+
+func (o *ObjectFS) stat(ctx context.Context, op, name string) (os.FileInfo, error) {
+	clean := fs.Clean(name)
+	if clean == "/" {
+		return &objectInfo{name: "/", mode: dirMarkerMode}, nil
+	}
+
+	object, err := o.client.StatObject(ctx, o.bucket, o.objectKey(clean), minio.StatObjectOptions{})
+	if err == nil {
+		return &objectInfo{name: path.Base(clean), size: object.Size, mode: 0644, modTime: object.LastModified}, nil
+	}
+	if !isNotFound(err) {
+		return nil, o.pathError(op, clean, err)
+	}
+
+	// Not an object; it is a directory when it has a marker or anything below it
+	marker, err := o.client.StatObject(ctx, o.bucket, o.dirKey(clean), minio.StatObjectOptions{})
+	if err == nil {
+		return &objectInfo{name: path.Base(clean), mode: dirMarkerMode, modTime: marker.LastModified}, nil
+	}
+	if !isNotFound(err) {
+		return nil, o.pathError(op, clean, err)
+	}
+
+	isDir, err := o.hasChildren(ctx, clean)
+	if err != nil {
+		return nil, o.pathError(op, clean, err)
+	}
+	if !isDir {
+		return nil, &os.PathError{Op: op, Path: clean, Err: syscall.ENOENT}
+	}
+	return &objectInfo{name: path.Base(clean), mode: dirMarkerMode}, nil
+}
+
+// hasChildren reports whether any key starts with the directory prefix, including its marker
+func (o *ObjectFS) hasChildren(ctx context.Context, name string) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for object := range o.client.ListObjects(ctx, o.bucket, minio.ListObjectsOptions{Prefix: o.dirKey(name), MaxKeys: 1}) {
+		if object.Err != nil {
+			return false, object.Err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// ReadDir lists objects and prefixes directly below a directory
+func (o *ObjectFS) ReadDir(name string) ([]os.FileInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return o.readDir(ctx, name)
+}
+
+func (o *ObjectFS) readDir(ctx context.Context, name string) ([]os.FileInfo, error) {
+	clean := fs.Clean(name)
+	prefix := o.dirKey(clean)
+
+	var infos []os.FileInfo
+	found := clean == "/"
+	for object := range o.client.ListObjects(ctx, o.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, o.pathError("readdir", clean, object.Err)
+		}
+		found = true
+
+		rest := strings.TrimPrefix(object.Key, prefix)
+		switch {
+		case rest == "":
+			// The directory's own marker
+		case strings.HasSuffix(rest, "/"):
+			infos = append(infos, &objectInfo{name: strings.TrimSuffix(rest, "/"), mode: dirMarkerMode})
+		default:
+			infos = append(infos, &objectInfo{name: rest, size: object.Size, mode: 0644, modTime: object.LastModified})
+		}
+	}
+
+	if !found {
+		// An empty listing is a missing path, a file, or an empty directory
+		// on stores that leave the marker out of its own listing
+		info, err := o.stat(ctx, "readdir", clean)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, &os.PathError{Op: "readdir", Path: clean, Err: syscall.ENOTDIR}
+		}
+	}
+	return infos, nil
+}
+
+// OpenFile opens an object for reading, or starts a streaming upload when
+// writing. Objects can only be replaced as a whole: writes need O_TRUNC or a new object.
+func (o *ObjectFS) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	clean := fs.Clean(name)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	info, statErr := o.stat(ctx, "open", clean)
+	exists := statErr == nil
+	if statErr != nil && !errors.Is(statErr, os.ErrNotExist) {
+		return nil, statErr
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if !writable {
+		if !exists {
+			return nil, statErr
+		}
+		if info.IsDir() {
+			return &dirFile{fs: o, name: clean, info: info}, nil
+		}
+		object, err := o.client.GetObject(context.Background(), o.bucket, o.objectKey(clean), minio.GetObjectOptions{})
+		if err != nil {
+			return nil, o.pathError("open", clean, err)
+		}
+		return &readFile{Object: object, info: info}, nil
+	}
+
+	switch {
+	case clean == "/" || (exists && info.IsDir()):
+		return nil, &os.PathError{Op: "open", Path: clean, Err: syscall.EISDIR}
+	case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: clean, Err: syscall.EEXIST}
+	case !exists && flag&os.O_CREATE == 0:
+		return nil, statErr
+	case flag&os.O_APPEND != 0 || (exists && flag&os.O_TRUNC == 0):
+		return nil, &os.PathError{Op: "open", Path: clean, Err: errors.ErrUnsupported}
+	}
+
+	// The parent directory must exist, as on a local disk
+	if parent, err := o.stat(ctx, "open", path.Dir(clean)); err != nil {
+		return nil, err
+	} else if !parent.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: clean, Err: syscall.ENOTDIR}
+	}
+
+	return o.newWriteFile(clean), nil
+}
+
+// Mkdir creates an empty directory marker
+func (o *ObjectFS) Mkdir(name string, perm os.FileMode) error {
+	clean := fs.Clean(name)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if _, err := o.stat(ctx, "mkdir", clean); err == nil {
+		return &os.PathError{Op: "mkdir", Path: clean, Err: syscall.EEXIST}
+	}
+	parent, err := o.stat(ctx, "mkdir", path.Dir(clean))
+	if err != nil {
+		return err
+	}
+	if !parent.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: clean, Err: syscall.ENOTDIR}
+	}
+
+	_, err = o.client.PutObject(ctx, o.bucket, o.dirKey(clean), strings.NewReader(""), 0, minio.PutObjectOptions{})
+	return o.pathError("mkdir", clean, err)
+}
+
+// Remove removes an object or an empty directory
+func (o *ObjectFS) Remove(name string) error {
+	clean := fs.Clean(name)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	info, err := o.stat(ctx, "remove", clean)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return o.pathError("remove", clean, o.client.RemoveObject(ctx, o.bucket, o.objectKey(clean), minio.RemoveObjectOptions{}))
+	}
+
+	entries, err := o.readDir(ctx, clean)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return &os.PathError{Op: "remove", Path: clean, Err: syscall.ENOTEMPTY}
+	}
+	return o.pathError("remove", clean, o.client.RemoveObject(ctx, o.bucket, o.dirKey(clean), minio.RemoveObjectOptions{}))
+}
+
+// RemoveAll removes an object or every object below a directory
+func (o *ObjectFS) RemoveAll(name string) error {
+	clean := fs.Clean(name)
+	ctx := context.Background()
+
+	if clean != "/" {
+		if err := o.client.RemoveObject(ctx, o.bucket, o.objectKey(clean), minio.RemoveObjectOptions{}); err != nil && !isNotFound(err) {
+			return o.pathError("removeall", clean, err)
+		}
+	}
+
+	keys := o.client.ListObjects(ctx, o.bucket, minio.ListObjectsOptions{Prefix: o.dirKey(clean), Recursive: true})
+	for result := range o.client.RemoveObjects(ctx, o.bucket, keys, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil {
+			return o.pathError("removeall", clean, result.Err)
+		}
+	}
+	return nil
+}
+
+// Rename copies an object, or every object below a directory, and then deletes the originals
+func (o *ObjectFS) Rename(oldName, newName string) error {
+	oldClean, newClean := fs.Clean(oldName), fs.Clean(newName)
+	ctx := context.Background()
+	linkError := func(err error) error {
+		if err == nil {
+			return nil
+		}
+		return &os.LinkError{Op: "rename", Old: oldClean, New: newClean, Err: translateError(err)}
+	}
+
+	info, err := o.stat(ctx, "rename", oldClean)
+	if err != nil {
+		return linkError(syscall.ENOENT)
+	}
+	if oldClean == "/" || newClean == "/" || strings.HasPrefix(newClean, oldClean+"/") {
+		return linkError(syscall.EINVAL)
+	}
+
+	if !info.IsDir() {
+		if err := o.copyObject(ctx, o.objectKey(oldClean), o.objectKey(newClean)); err != nil {
+			return linkError(err)
+		}
+		return linkError(o.client.RemoveObject(ctx, o.bucket, o.objectKey(oldClean), minio.RemoveObjectOptions{}))
+	}
+
+	// Copy everything first so a failure never loses data, then delete the originals
+	oldPrefix, newPrefix := o.dirKey(oldClean), o.dirKey(newClean)
+	var copied []string
+	for object := range o.client.ListObjects(ctx, o.bucket, minio.ListObjectsOptions{Prefix: oldPrefix, Recursive: true}) {
+		if object.Err != nil {
+			return linkError(object.Err)
+		}
+		if err := o.copyObject(ctx, object.Key, newPrefix+strings.TrimPrefix(object.Key, oldPrefix)); err != nil {
+			return linkError(err)
+		}
+		copied = append(copied, object.Key)
+	}
+	for _, key := range copied {
+		if err := o.client.RemoveObject(ctx, o.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+			return linkError(err)
+		}
+	}
+	return nil
+}
+
+// copyObject copies one object server-side; objects over the 5 GiB single
+// copy limit go through ComposeObject, which needs multipart copy support
+func (o *ObjectFS) copyObject(ctx context.Context, srcKey, dstKey string) error {
+	info, err := o.client.StatObject(ctx, o.bucket, srcKey, minio.StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+
+	dst := minio.CopyDestOptions{Bucket: o.bucket, Object: dstKey}
+	src := minio.CopySrcOptions{Bucket: o.bucket, Object: srcKey}
+	if info.Size > maxCopySize {
+		_, err = o.client.ComposeObject(ctx, dst, src)
+	} else {
+		_, err = o.client.CopyObject(ctx, dst, src)
+	}
+	return err
+}
+
+// pathError wraps a client error with the virtual path, translating S3 error codes
+func (o *ObjectFS) pathError(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return err
+	}
+	return &os.PathError{Op: op, Path: name, Err: translateError(err)}
+}
+
+// translateError maps S3 error codes onto the errno values callers check for
+func translateError(err error) error {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket", "NotFound":
+		return syscall.ENOENT
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+		return syscall.EACCES
+	}
+	return err
+}
+
+// isNotFound reports whether a client error means the object does not exist
+func isNotFound(err error) bool {
+	return errors.Is(translateError(err), syscall.ENOENT)
+}
+
+// objectInfo is the os.FileInfo for an object or prefix
+type objectInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i *objectInfo) Name() string       { return i.name }
+func (i *objectInfo) Size() int64        { return i.size }
+func (i *objectInfo) Mode() os.FileMode  { return i.mode }
+func (i *objectInfo) ModTime() time.Time { return i.modTime }
+func (i *objectInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *objectInfo) Sys() interface{}   { return nil }
+
+// readFile streams an object; minio.Object fetches ranges lazily on Read and Seek
+type readFile struct {
+	*minio.Object
+	info os.FileInfo
+}
+
+// Write is not supported on read handles
+func (f *readFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.info.Name(), Err: syscall.EBADF}
+}
+
+// Readdir is not supported on files
+func (f *readFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.info.Name(), Err: syscall.ENOTDIR}
+}
+
+// Stat returns the object information looked up when the handle was opened
+func (f *readFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+// dirFile is an open directory; entries are listed on the first Readdir
+type dirFile struct {
+	fs      *ObjectFS
+	name    string
+	info    os.FileInfo
+	entries []os.FileInfo
+	listed  bool
+}
+
+func (d *dirFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *dirFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *dirFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, nil
+}
+
+func (d *dirFile) Close() error {
+	return nil
+}
+
+func (d *dirFile) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+// Readdir lists the directory like os.File.Readdir
+func (d *dirFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.listed {
+		entries, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.listed = entries, true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		if entries == nil {
+			entries = []os.FileInfo{}
+		}
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+
+// writeFile streams writes into a PutObject running in the background
+type writeFile struct {
+	name    string
+	pipe    *io.PipeWriter
+	done    chan error
+	written int64
+	closed  bool
+}
+
+// newWriteFile starts an upload of unknown length that completes on Close
+func (o *ObjectFS) newWriteFile(name string) *writeFile {
+	reader, writer := io.Pipe()
+	f := &writeFile{name: name, pipe: writer, done: make(chan error, 1)}
+
+	go func() {
+		_, err := o.client.PutObject(context.Background(), o.bucket, o.objectKey(name), reader, -1, minio.PutObjectOptions{PartSize: uploadPartSize})
+		reader.CloseWithError(err)
+		f.done <- err
+	}()
+	return f
+}
+
+// Write appends to the upload
+func (f *writeFile) Write(p []byte) (int, error) {
+	n, err := f.pipe.Write(p)
+	f.written += int64(n)
+	return n, err
+}
+
+// Seek only supports positioning at the current end, since uploads are sequential
+func (f *writeFile) Seek(offset int64, whence int) (int64, error) {
+	if (whence == io.SeekStart && offset == f.written) || (whence != io.SeekStart && offset == 0) {
+		return f.written, nil
+	}
+	return 0, &os.PathError{Op: "seek", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+// Read is not supported on write handles
+func (f *writeFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: f.name, Err: syscall.EBADF}
+}
+
+// Readdir is not supported on files
+func (f *writeFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+}
+
+// Stat reports the bytes written so far
+func (f *writeFile) Stat() (os.FileInfo, error) {
+	return &objectInfo{name: path.Base(f.name), size: f.written, mode: 0644, modTime: time.Now()}, nil
+}
+
+// Close finishes the upload and reports whether the object was stored
+func (f *writeFile) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	f.pipe.Close()
+	if err := <-f.done; err != nil {
+		return &os.PathError{Op: "close", Path: f.name, Err: translateError(err)}
+	}
+	return nil
+}
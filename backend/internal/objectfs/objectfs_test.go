@@ -0,0 +1,221 @@
+package objectfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"github.com/BomScoob12/homelab-file-manager/internal/s3"
+)
+
+// newTestObjectFS returns an ObjectFS over the bucket "bucket" of the S3
+// gateway, serving a temporary directory, so requests take the real S3 wire format
+func newTestObjectFS(t *testing.T, prefix string) *ObjectFS {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "bucket"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("S3_ACCESS_KEY_ID", "test")
+	t.Setenv("S3_SECRET_ACCESS_KEY", "testsecret")
+	t.Setenv("FILE_MANAGER_STATE_DIR", t.TempDir())
+
+	gateway, err := s3.NewServer(files.NewFileServiceWithBackend(fs.NewLocalFS(root)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+
+	o, err := New(Config{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		Bucket:          "bucket",
+		Prefix:          prefix,
+		Region:          "us-east-1",
+		AccessKeyID:     "test",
+		SecretAccessKey: "testsecret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+// writeObject stores content at name through the backend
+func writeObject(t *testing.T, o *ObjectFS, name, content string) {
+	t.Helper()
+	file, err := o.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile(%s) error = %v", name, err)
+	}
+	if _, err := io.WriteString(file, content); err != nil {
+		t.Fatalf("Write(%s) error = %v", name, err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close(%s) error = %v", name, err)
+	}
+}
+
+// readObject returns the content stored at name
+func readObject(t *testing.T, o *ObjectFS, name string) string {
+	t.Helper()
+	file, err := o.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(%s) error = %v", name, err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("ReadAll(%s) error = %v", name, err)
+	}
+	return string(content)
+}
+
+// names returns the sorted names of infos
+func names(infos []os.FileInfo) string {
+	var list []string
+	for _, info := range infos {
+		list = append(list, info.Name())
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+func TestObjectFSFiles(t *testing.T) {
+	o := newTestObjectFS(t, "")
+
+	if err := o.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if _, err := o.Stat("/missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() of a missing object error = %v, want not exist", err)
+	}
+
+	writeObject(t, o, "/hello.txt", "hello world")
+	info, err := o.Stat("/hello.txt")
+	if err != nil || info.IsDir() || info.Size() != 11 {
+		t.Fatalf("Stat() = %v, %v, want an 11 byte file", info, err)
+	}
+	if got := readObject(t, o, "/hello.txt"); got != "hello world" {
+		t.Fatalf("content = %q, want %q", got, "hello world")
+	}
+
+	// Objects are replaced as a whole
+	if _, err := o.OpenFile("/hello.txt", os.O_WRONLY, 0); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("OpenFile() without O_TRUNC error = %v, want unsupported", err)
+	}
+	if _, err := o.OpenFile("/hello.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("OpenFile() with O_EXCL error = %v, want exist", err)
+	}
+	writeObject(t, o, "/hello.txt", "bye")
+	if got := readObject(t, o, "/hello.txt"); got != "bye" {
+		t.Fatalf("content after overwrite = %q, want %q", got, "bye")
+	}
+
+	if _, err := o.OpenFile("/nodir/file.txt", os.O_WRONLY|os.O_CREATE, 0644); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("OpenFile() below a missing directory error = %v, want not exist", err)
+	}
+
+	if err := o.Rename("/hello.txt", "/renamed.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if _, err := o.Stat("/hello.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() of the old name error = %v, want not exist", err)
+	}
+	if got := readObject(t, o, "/renamed.txt"); got != "bye" {
+		t.Fatalf("content after rename = %q, want %q", got, "bye")
+	}
+
+	if err := o.Remove("/renamed.txt"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := o.Remove("/renamed.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Remove() of a removed object error = %v, want not exist", err)
+	}
+}
+
+func TestObjectFSDirectories(t *testing.T) {
+	o := newTestObjectFS(t, "")
+
+	if err := o.Mkdir("/docs", 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if err := o.Mkdir("/docs", 0755); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Mkdir() of an existing directory error = %v, want exist", err)
+	}
+	if info, err := o.Stat("/docs"); err != nil || !info.IsDir() {
+		t.Fatalf("Stat() of an empty directory = %v, %v, want a directory", info, err)
+	}
+	if entries, err := o.ReadDir("/docs"); err != nil || len(entries) != 0 {
+		t.Fatalf("ReadDir() of an empty directory = %v, %v, want no entries", entries, err)
+	}
+
+	writeObject(t, o, "/docs/a.txt", "a")
+	if err := o.Mkdir("/docs/sub", 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	writeObject(t, o, "/docs/sub/b.txt", "b")
+
+	entries, err := o.ReadDir("/docs")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if got := names(entries); got != "a.txt,sub" {
+		t.Fatalf("ReadDir() = %s, want a.txt,sub", got)
+	}
+	if _, err := o.ReadDir("/docs/a.txt"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatalf("ReadDir() of a file error = %v, want ENOTDIR", err)
+	}
+	if err := o.Remove("/docs"); !errors.Is(err, syscall.ENOTEMPTY) {
+		t.Fatalf("Remove() of a non-empty directory error = %v, want ENOTEMPTY", err)
+	}
+
+	if err := o.Rename("/docs", "/archive"); err != nil {
+		t.Fatalf("Rename() of a directory error = %v", err)
+	}
+	if got := readObject(t, o, "/archive/sub/b.txt"); got != "b" {
+		t.Fatalf("content after directory rename = %q, want %q", got, "b")
+	}
+	if _, err := o.Stat("/docs"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() of the old directory error = %v, want not exist", err)
+	}
+	if err := o.Rename("/archive", "/archive/inner"); !errors.Is(err, syscall.EINVAL) {
+		t.Fatalf("Rename() into itself error = %v, want EINVAL", err)
+	}
+
+	if err := o.RemoveAll("/archive"); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if _, err := o.Stat("/archive"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat() after RemoveAll() error = %v, want not exist", err)
+	}
+}
+
+func TestObjectFSPrefix(t *testing.T) {
+	o := newTestObjectFS(t, "/homelab/")
+	writeObject(t, o, "/file.txt", "inside")
+
+	// The prefix is the backend root; sibling keys stay invisible
+	other := &ObjectFS{client: o.client, bucket: o.bucket}
+	if got := readObject(t, other, "/homelab/file.txt"); got != "inside" {
+		t.Fatalf("content under the prefix = %q, want %q", got, "inside")
+	}
+	writeObject(t, other, "/outside.txt", "outside")
+
+	entries, err := o.ReadDir("/")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if got := names(entries); got != "file.txt" {
+		t.Fatalf("ReadDir() = %s, want file.txt", got)
+	}
+}
@@ -0,0 +1,238 @@
+package remotefs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+// remoteInfo is an os.FileInfo built from a remote details or list entry
+type remoteInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// newInfo converts a remote entry, keeping the permission bits it reports
+func newInfo(details detailsResponse) *remoteInfo {
+	mode := parsePermissions(details.Permissions)
+	if details.IsDir {
+		mode |= os.ModeDir
+	}
+	return &remoteInfo{
+		name:    details.Name,
+		size:    details.Size,
+		mode:    mode,
+		modTime: details.ModTime,
+	}
+}
+
+// parsePermissions reads the rwx bits from the end of an os.FileMode string
+// such as "-rw-r--r--" or "drwxr-xr-x"
+func parsePermissions(permissions string) os.FileMode {
+	const rwx = "rwxrwxrwx"
+	if len(permissions) < len(rwx) {
+		return 0644
+	}
+
+	var mode os.FileMode
+	bits := permissions[len(permissions)-len(rwx):]
+	for i := range rwx {
+		if bits[i] == rwx[i] {
+			mode |= 1 << uint(len(rwx)-1-i)
+		}
+	}
+	return mode
+}
+
+func (i *remoteInfo) Name() string       { return i.name }
+func (i *remoteInfo) Size() int64        { return i.size }
+func (i *remoteInfo) Mode() os.FileMode  { return i.mode }
+func (i *remoteInfo) ModTime() time.Time { return i.modTime }
+func (i *remoteInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *remoteInfo) Sys() interface{}   { return nil }
+
+// readFile reads a remote file through /file/raw. The body is requested
+// lazily from the current offset, so seeking only costs a new Range request
+// when data is actually read. A request is cancelled once the remote sends
+// nothing for requestTimeout.
+type readFile struct {
+	fs     *RemoteFS
+	name   string
+	info   os.FileInfo
+	offset int64
+	body   io.ReadCloser
+	cancel context.CancelFunc
+	stall  *time.Timer
+}
+
+// Read streams from the current offset, opening a ranged request as needed
+func (f *readFile) Read(p []byte) (int, error) {
+	if f.offset >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.body.Read(p)
+	if n > 0 {
+		f.stall.Reset(requestTimeout)
+	}
+	f.offset += int64(n)
+	return n, err
+}
+
+// open requests the file body from the current offset to the end
+func (f *readFile) open() error {
+	header := http.Header{}
+	if f.offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", f.offset))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stall := time.AfterFunc(requestTimeout, cancel)
+	resp, err := f.fs.do(ctx, http.MethodGet, "/file/raw", f.name, header)
+	if err != nil {
+		stall.Stop()
+		cancel()
+		return &os.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	if f.offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		stall.Stop()
+		resp.Body.Close()
+		cancel()
+		return &os.PathError{Op: "read", Path: f.name, Err: fmt.Errorf("remote ignored range request (status %d)", resp.StatusCode)}
+	}
+	f.body, f.cancel, f.stall = resp.Body, cancel, stall
+	return nil
+}
+
+// closeBody releases the open response body and its request
+func (f *readFile) closeBody() error {
+	if f.body == nil {
+		return nil
+	}
+	f.stall.Stop()
+	err := f.body.Close()
+	f.cancel()
+	f.body, f.cancel, f.stall = nil, nil, nil
+	return err
+}
+
+// Seek moves the offset, dropping any open body that no longer matches it
+func (f *readFile) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = f.offset + offset
+	case io.SeekEnd:
+		target = f.info.Size() + offset
+	default:
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	if target < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+
+	if target != f.offset {
+		f.closeBody()
+	}
+	f.offset = target
+	return target, nil
+}
+
+// Write is refused; remote mounts are read-only
+func (f *readFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: syscall.EBADF}
+}
+
+// Readdir fails on files
+func (f *readFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.name, Err: syscall.ENOTDIR}
+}
+
+// Stat returns the information captured when the file was opened
+func (f *readFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close releases any open response body
+func (f *readFile) Close() error {
+	return f.closeBody()
+}
+
+// dirFile is an open remote directory; entries are fetched on the first Readdir
+type dirFile struct {
+	fs      *RemoteFS
+	name    string
+	info    os.FileInfo
+	entries []os.FileInfo
+	loaded  bool
+}
+
+// Readdir returns directory entries, following the os.File count semantics
+func (d *dirFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.loaded {
+		entries, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+
+// Stat returns the information captured when the directory was opened
+func (d *dirFile) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read fails on directories
+func (d *dirFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.name, Err: syscall.EISDIR}
+}
+
+// Write fails on directories
+func (d *dirFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: d.name, Err: syscall.EISDIR}
+}
+
+// Seek only supports rewinding, which restarts the listing
+func (d *dirFile) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.loaded = false
+		d.entries = nil
+		return 0, nil
+	}
+	return 0, &os.PathError{Op: "seek", Path: d.name, Err: syscall.EINVAL}
+}
+
+// Close is a no-op for directories
+func (d *dirFile) Close() error {
+	return nil
+}
@@ -0,0 +1,251 @@
+package remotefs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+)
+
+const (
+	// requestTimeout bounds metadata requests and how long a file body may go
+	// without data; bodies that keep streaming have no overall deadline
+	requestTimeout = 30 * time.Second
+	// maxErrorBody caps how much of an error response is read for its message
+	maxErrorBody = 64 << 10
+)
+
+// Config describes another file manager instance and the API token used to reach it
+type Config struct {
+	URL   string
+	Token string
+}
+
+// RemoteFS is a storage backend that proxies another instance's /file API.
+// Listing, stat, reads (with Range) and deletes are supported; everything
+// else fails with a permission error, so the mount is effectively read-only.
+type RemoteFS struct {
+	base   *url.URL
+	token  string
+	client *http.Client
+}
+
+// New creates a backend for the instance at cfg.URL
+func New(cfg Config) (*RemoteFS, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("remote mounts require a url")
+	}
+	base, err := url.Parse(strings.TrimRight(cfg.URL, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid remote url %q", cfg.URL)
+	}
+	return &RemoteFS{base: base, token: cfg.Token, client: &http.Client{}}, nil
+}
+
+// Check verifies that the remote instance is reachable and accepts the token
+func (r *RemoteFS) Check(ctx context.Context) error {
+	var details detailsResponse
+	if err := r.getJSON(ctx, "/file/details", "/", &details); err != nil {
+		return fmt.Errorf("remote %s unreachable: %w", r.base.Host, err)
+	}
+	return nil
+}
+
+// detailsResponse is the subset of the remote /file/details response used here
+type detailsResponse struct {
+	Name        string    `json:"name"`
+	IsDir       bool      `json:"isDir"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"modTime"`
+	Permissions string    `json:"permissions"`
+}
+
+// listResponse is the subset of the remote /file/list response used here
+type listResponse struct {
+	Items []detailsResponse `json:"items"`
+}
+
+// errorResponse is the error body returned by the remote API
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Stat returns file information from the remote /file/details endpoint
+func (r *RemoteFS) Stat(name string) (os.FileInfo, error) {
+	clean := fs.Clean(name)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var details detailsResponse
+	if err := r.getJSON(ctx, "/file/details", clean, &details); err != nil {
+		return nil, &os.PathError{Op: "stat", Path: clean, Err: err}
+	}
+	info := newInfo(details)
+	info.name = path.Base(clean)
+	return info, nil
+}
+
+// ReadDir lists a directory through the remote /file/list endpoint
+func (r *RemoteFS) ReadDir(name string) ([]os.FileInfo, error) {
+	clean := fs.Clean(name)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var list listResponse
+	if err := r.getJSON(ctx, "/file/list", clean, &list); err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: clean, Err: err}
+	}
+	infos := make([]os.FileInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		infos = append(infos, newInfo(item))
+	}
+	return infos, nil
+}
+
+// OpenFile opens a remote file or directory for reading; writes are refused
+func (r *RemoteFS) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	clean := fs.Clean(name)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &os.PathError{Op: "open", Path: clean, Err: syscall.EACCES}
+	}
+
+	info, err := r.Stat(clean)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dirFile{fs: r, name: clean, info: info}, nil
+	}
+	return &readFile{fs: r, name: clean, info: info}, nil
+}
+
+// Mkdir is not supported on remote mounts
+func (r *RemoteFS) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: fs.Clean(name), Err: syscall.EACCES}
+}
+
+// Rename is not supported on remote mounts
+func (r *RemoteFS) Rename(oldName, newName string) error {
+	return &os.LinkError{Op: "rename", Old: fs.Clean(oldName), New: fs.Clean(newName), Err: syscall.EACCES}
+}
+
+// Remove deletes a file or an empty directory on the remote instance
+func (r *RemoteFS) Remove(name string) error {
+	clean := fs.Clean(name)
+	info, err := r.Stat(clean)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := r.ReadDir(clean)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return &os.PathError{Op: "remove", Path: clean, Err: syscall.ENOTEMPTY}
+		}
+	}
+	return r.delete("remove", clean)
+}
+
+// RemoveAll deletes a file or directory tree on the remote instance
+func (r *RemoteFS) RemoveAll(name string) error {
+	clean := fs.Clean(name)
+	if clean == "/" {
+		return &os.PathError{Op: "removeall", Path: clean, Err: syscall.EBUSY}
+	}
+	if _, err := r.Stat(clean); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return r.delete("removeall", clean)
+}
+
+// delete calls the remote /file/delete endpoint
+func (r *RemoteFS) delete(op, clean string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp, err := r.do(ctx, http.MethodDelete, "/file/delete", clean, nil)
+	if err != nil {
+		return &os.PathError{Op: op, Path: clean, Err: err}
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// getJSON calls a remote GET endpoint and decodes its JSON response
+func (r *RemoteFS) getJSON(ctx context.Context, endpoint, clean string, dst interface{}) error {
+	resp, err := r.do(ctx, http.MethodGet, endpoint, clean, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("invalid response from remote: %w", err)
+	}
+	return nil
+}
+
+// do sends an authenticated request for a path and turns error statuses into errors
+func (r *RemoteFS) do(ctx context.Context, method, endpoint, clean string, header http.Header) (*http.Response, error) {
+	target := *r.base
+	target.Path += endpoint
+	target.RawQuery = url.Values{"path": {clean}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, statusError(resp)
+	}
+	return resp, nil
+}
+
+// statusError maps a remote error response onto the errno the local service
+// layer classifies the same way, keeping the remote message for the rest
+func statusError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return syscall.ENOENT
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return syscall.EACCES
+	case http.StatusConflict:
+		return syscall.EEXIST
+	case http.StatusBadRequest:
+		return syscall.EINVAL
+	}
+
+	var body errorResponse
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		return fmt.Errorf("remote returned %d: %s", resp.StatusCode, body.Error)
+	}
+	return fmt.Errorf("remote returned %d", resp.StatusCode)
+}
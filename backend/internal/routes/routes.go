@@ -3,15 +3,65 @@ package routes
 import (
 	"net/http"
 
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/dav"
+	"github.com/BomScoob12/homelab-file-manager/internal/filerequests"
 	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/health"
+	"github.com/BomScoob12/homelab-file-manager/internal/jobs"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/metrics"
+	"github.com/BomScoob12/homelab-file-manager/internal/shares"
 )
 
-func NewRouter() http.Handler {
+// NewRouter builds the application router over the shared file service and initializes the stores it depends on
+func NewRouter(fileService files.FileServiceInterface) (http.Handler, error) {
 	// mux = multiplexter (router)
 	mux := http.NewServeMux()
 
+	// Readiness depends on the base path being usable
+	health.RegisterStorageChecks(config.BasePath())
+
+	// Share links persist across restarts
+	shareStore, err := shares.NewStore(config.StateDir("shares.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Upload-only file request links persist the same way
+	fileRequestStore, err := filerequests.NewStore(config.StateDir("file-requests.json"))
+	if err != nil {
+		return nil, err
+	}
+
 	// API routes
-	mux.Handle("/file/", http.StripPrefix("/file", files.NewHandler()))
+	mux.Handle("/file/", auth.Middleware(http.StripPrefix("/file", files.NewHandler(fileService))))
+	mux.Handle("/file/jobs", auth.Middleware(metrics.InstrumentRoute("/file/jobs", jobs.Handler(jobs.Default))))
+	mux.Handle("/shares", auth.Middleware(shares.NewHandler(shareStore, fileService)))
+	mux.Handle("/file-requests", auth.Middleware(filerequests.NewHandler(fileRequestStore, fileService)))
+
+	// WebDAV over the same sandboxed file service
+	mux.Handle(dav.Prefix+"/", auth.Middleware(metrics.InstrumentRoute(dav.Prefix, dav.NewHandler(fileService))))
+
+	// Public share links, scoped to the shared subtree and protected by the share itself
+	mux.Handle("/s/", shares.NewPublicHandler(shareStore, fileService))
+
+	// Public upload-only links; senders can never list the target directory
+	mux.Handle("/u/", filerequests.NewPublicHandler(fileRequestStore, fileService))
+
+	// Admin routes
+	mux.Handle("/admin/audit", auth.Middleware(auth.RequireAdmin(audit.Handler(audit.Default))))
+
+	// Liveness and readiness probes
+	mux.Handle("/healthz", health.Default.LivenessHandler())
+	mux.Handle("/readyz", health.Default.ReadinessHandler())
+
+	// Prometheus metrics, unless served on a dedicated address
+	if metrics.ListenAddr() == "" {
+		mux.Handle("/metrics", metrics.Handler())
+	}
 
 	// Serve frontend static files (for production)
 	// Uncomment this when you build the frontend
@@ -50,6 +100,27 @@ func NewRouter() http.Handler {
     <div class="endpoint">
         <span class="method">DELETE</span> /file/delete?path=/file.txt - Delete file
     </div>
+    <div class="endpoint">
+        <span class="method">GET/POST/DELETE</span> /shares - Manage public share links
+    </div>
+    <div class="endpoint">
+        <span class="method">GET/POST/DELETE</span> /file-requests - Manage upload-only links
+    </div>
+    <div class="endpoint">
+        <span class="method">WebDAV</span> /dav/ - Mount as a network drive
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /admin/audit?user=&op=&path= - Query the audit log
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /metrics - Prometheus metrics
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /healthz - Liveness probe
+    </div>
+    <div class="endpoint">
+        <span class="method">GET</span> /readyz - Readiness probe
+    </div>
     
     <h2>Quick Test:</h2>
     <p><a href="/file/list?path=/">List root directory</a></p>
@@ -61,5 +132,5 @@ func NewRouter() http.Handler {
 		}
 	})
 
-	return mux
+	return logging.Middleware(mux), nil
 }
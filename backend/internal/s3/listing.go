@@ -0,0 +1,265 @@
+package s3
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+)
+
+// maxListKeys is the largest page ListObjects returns
+const maxListKeys = 1000
+
+// listEntry is an object or common prefix found while listing a bucket
+type listEntry struct {
+	key      string
+	isPrefix bool
+	size     int64
+	modTime  time.Time
+	etag     string
+}
+
+// listPage is one page of listing results
+type listPage struct {
+	contents    []ObjectInfo
+	prefixes    []CommonPrefix
+	isTruncated bool
+	lastKey     string
+}
+
+// handleListObjectsV2 serves ListObjectsV2
+func (s *Server) handleListObjectsV2(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	maxKeys, ok := parseMaxKeys(query.Get("max-keys"))
+	if !ok {
+		s.sendError(w, r, "InvalidArgument", "max-keys must be a non-negative integer.", http.StatusBadRequest)
+		return
+	}
+
+	after := query.Get("start-after")
+	token := query.Get("continuation-token")
+	if token != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			s.sendError(w, r, "InvalidArgument", "The continuation token provided is incorrect.", http.StatusBadRequest)
+			return
+		}
+		after = string(decoded)
+	}
+
+	page, ok := s.listPage(w, r, bucket, prefix, delimiter, after, maxKeys)
+	if !ok {
+		return
+	}
+
+	result := ListBucketResultV2{
+		Xmlns:             s3Namespace,
+		Name:              bucket,
+		Prefix:            prefix,
+		Delimiter:         delimiter,
+		StartAfter:        query.Get("start-after"),
+		ContinuationToken: token,
+		KeyCount:          len(page.contents) + len(page.prefixes),
+		MaxKeys:           maxKeys,
+		IsTruncated:       page.isTruncated,
+		Contents:          page.contents,
+		CommonPrefixes:    page.prefixes,
+	}
+	if page.isTruncated {
+		result.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(page.lastKey))
+	}
+	s.sendXML(w, result, http.StatusOK)
+}
+
+// handleListObjectsV1 serves the legacy ListObjects call
+func (s *Server) handleListObjectsV1(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	prefix, delimiter, marker := query.Get("prefix"), query.Get("delimiter"), query.Get("marker")
+	maxKeys, ok := parseMaxKeys(query.Get("max-keys"))
+	if !ok {
+		s.sendError(w, r, "InvalidArgument", "max-keys must be a non-negative integer.", http.StatusBadRequest)
+		return
+	}
+
+	page, ok := s.listPage(w, r, bucket, prefix, delimiter, marker, maxKeys)
+	if !ok {
+		return
+	}
+
+	result := ListBucketResultV1{
+		Xmlns:          s3Namespace,
+		Name:           bucket,
+		Prefix:         prefix,
+		Marker:         marker,
+		Delimiter:      delimiter,
+		MaxKeys:        maxKeys,
+		IsTruncated:    page.isTruncated,
+		Contents:       page.contents,
+		CommonPrefixes: page.prefixes,
+	}
+	if page.isTruncated {
+		result.NextMarker = page.lastKey
+	}
+	s.sendXML(w, result, http.StatusOK)
+}
+
+// parseMaxKeys parses max-keys, defaulting to and capping at maxListKeys
+func parseMaxKeys(value string) (int, bool) {
+	if value == "" {
+		return maxListKeys, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if n > maxListKeys {
+		n = maxListKeys
+	}
+	return n, true
+}
+
+// listPage collects, sorts and pages the entries after the given key; it writes the error response itself
+func (s *Server) listPage(w http.ResponseWriter, r *http.Request, bucket, prefix, delimiter, after string, maxKeys int) (*listPage, bool) {
+	if !s.bucketExists(r.Context(), bucket) {
+		s.sendError(w, r, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+		return nil, false
+	}
+
+	entries, err := s.collectEntries(r.Context(), bucket, prefix, delimiter)
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchBucket")
+		return nil, false
+	}
+
+	page := &listPage{contents: []ObjectInfo{}}
+	for _, entry := range entries {
+		if entry.key <= after {
+			continue
+		}
+		if len(page.contents)+len(page.prefixes) >= maxKeys {
+			page.isTruncated = true
+			break
+		}
+		page.lastKey = entry.key
+		if entry.isPrefix {
+			page.prefixes = append(page.prefixes, CommonPrefix{Prefix: entry.key})
+			continue
+		}
+		page.contents = append(page.contents, ObjectInfo{
+			Key:          entry.key,
+			LastModified: entry.modTime.UTC(),
+			ETag:         entry.etag,
+			Size:         entry.size,
+			StorageClass: "STANDARD",
+		})
+	}
+	return page, true
+}
+
+// collectEntries returns the sorted objects and common prefixes under prefix
+func (s *Server) collectEntries(ctx context.Context, bucket, prefix, delimiter string) ([]listEntry, error) {
+	// Start from the deepest directory the prefix fully names
+	dir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = prefix[:i+1]
+	}
+
+	var entries []listEntry
+	if delimiter == "/" {
+		// One directory level maps directly onto a single listing
+		listing, err := s.svc.ListFiles(ctx, objectPath(bucket, dir))
+		if err != nil {
+			return nil, ignoreNotFound(err)
+		}
+		for _, item := range listing.Items {
+			key := dir + item.Name
+			if item.IsDir {
+				key += "/"
+			}
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			entries = append(entries, listEntry{
+				key:      key,
+				isPrefix: item.IsDir,
+				size:     item.Size,
+				modTime:  item.ModTime,
+				etag:     itemETag(item.ModTime, item.Size),
+			})
+		}
+	} else {
+		var objects []listEntry
+		if err := s.walk(ctx, bucket, dir, prefix, &objects); err != nil {
+			return nil, ignoreNotFound(err)
+		}
+
+		seen := make(map[string]bool)
+		for _, object := range objects {
+			if delimiter != "" {
+				if i := strings.Index(object.key[len(prefix):], delimiter); i >= 0 {
+					common := object.key[:len(prefix)+i+len(delimiter)]
+					if !seen[common] {
+						seen[common] = true
+						entries = append(entries, listEntry{key: common, isPrefix: true})
+					}
+					continue
+				}
+			}
+			entries = append(entries, object)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return entries, nil
+}
+
+// walk recursively collects files under dir whose keys start with prefix
+func (s *Server) walk(ctx context.Context, bucket, dir, prefix string, objects *[]listEntry) error {
+	listing, err := s.svc.ListFiles(ctx, objectPath(bucket, dir))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range listing.Items {
+		key := dir + item.Name
+		if item.IsDir {
+			key += "/"
+			// Only descend into directories that can still contain matching keys
+			if strings.HasPrefix(key, prefix) || strings.HasPrefix(prefix, key) {
+				if err := s.walk(ctx, bucket, key, prefix, objects); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			*objects = append(*objects, listEntry{
+				key:     key,
+				size:    item.Size,
+				modTime: item.ModTime,
+				etag:    itemETag(item.ModTime, item.Size),
+			})
+		}
+	}
+	return nil
+}
+
+// itemETag is the file service ETag; its dash marks it as non-MD5 so
+// clients skip checksum comparison
+func itemETag(modTime time.Time, size int64) string {
+	return files.ETag(modTime, size)
+}
+
+// ignoreNotFound treats a missing prefix directory as an empty listing
+func ignoreNotFound(err error) error {
+	if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no such file") || strings.Contains(err.Error(), "not a directory") {
+		return nil
+	}
+	return err
+}
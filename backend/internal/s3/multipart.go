@@ -0,0 +1,360 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/statefile"
+)
+
+const (
+	// maxPartNumber is the highest part number S3 accepts
+	maxPartNumber = 10000
+	// staleUploadAge is how long an unfinished multipart upload is kept
+	staleUploadAge = 7 * 24 * time.Hour
+	// maxCompleteBody bounds the CompleteMultipartUpload request body
+	maxCompleteBody = 2 << 20
+)
+
+var uploadIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,64}$`)
+
+// errNoSuchUpload is returned for unknown or foreign upload IDs
+var errNoSuchUpload = errors.New("no such upload")
+
+// multipartUpload is the metadata stored with an in-progress upload
+type multipartUpload struct {
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	User      string    `json:"user"`
+	Initiated time.Time `json:"initiated"`
+}
+
+// multipartStore keeps in-progress upload parts in the state directory, outside the base path
+type multipartStore struct {
+	dir string
+}
+
+// newMultipartStore creates the store and discards uploads abandoned for longer than staleUploadAge
+func newMultipartStore(dir string) (*multipartStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create multipart directory: %w", err)
+	}
+
+	store := &multipartStore{dir: dir}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multipart directory: %w", err)
+	}
+	for _, entry := range entries {
+		var upload multipartUpload
+		found, err := statefile.Load(filepath.Join(dir, entry.Name(), "upload.json"), &upload)
+		if err != nil || !found || time.Since(upload.Initiated) > staleUploadAge {
+			slog.Info("removing stale multipart upload", "upload_id", entry.Name())
+			os.RemoveAll(filepath.Join(dir, entry.Name()))
+		}
+	}
+	return store, nil
+}
+
+// create starts a new upload and returns its ID
+func (m *multipartStore) create(upload multipartUpload) (string, error) {
+	id, err := auth.RandomToken(24)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate upload ID: %w", err)
+	}
+	if err := os.Mkdir(filepath.Join(m.dir, id), 0700); err != nil {
+		return "", fmt.Errorf("failed to create upload: %w", err)
+	}
+	if err := statefile.Save(filepath.Join(m.dir, id, "upload.json"), upload); err != nil {
+		os.RemoveAll(filepath.Join(m.dir, id))
+		return "", err
+	}
+	return id, nil
+}
+
+// get loads an upload, checking that it belongs to the bucket, key and user
+func (m *multipartStore) get(id, bucket, key, user string) (*multipartUpload, error) {
+	if !uploadIDPattern.MatchString(id) {
+		return nil, errNoSuchUpload
+	}
+	var upload multipartUpload
+	found, err := statefile.Load(filepath.Join(m.dir, id, "upload.json"), &upload)
+	if err != nil {
+		return nil, err
+	}
+	if !found || upload.Bucket != bucket || upload.Key != key || upload.User != user {
+		return nil, errNoSuchUpload
+	}
+	return &upload, nil
+}
+
+// partPath returns the file holding one part
+func (m *multipartStore) partPath(id string, partNumber int) string {
+	return filepath.Join(m.dir, id, fmt.Sprintf("%05d.part", partNumber))
+}
+
+// writePart stores a part atomically and returns its MD5
+func (m *multipartStore) writePart(id string, partNumber int, content io.Reader) ([]byte, error) {
+	tmp, err := os.CreateTemp(filepath.Join(m.dir, id), ".part-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create part: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	_, err = io.Copy(tmp, io.TeeReader(content, hash))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sum := hash.Sum(nil)
+	if err := os.WriteFile(m.partPath(id, partNumber)+".md5", []byte(hex.EncodeToString(sum)), 0600); err != nil {
+		return nil, fmt.Errorf("failed to record part checksum: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.partPath(id, partNumber)); err != nil {
+		return nil, fmt.Errorf("failed to store part: %w", err)
+	}
+	return sum, nil
+}
+
+// parts lists the uploaded parts in ascending order
+func (m *multipartStore) parts(id string) ([]PartInfo, error) {
+	entries, err := os.ReadDir(filepath.Join(m.dir, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+
+	var parts []PartInfo
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".part")
+		if !ok {
+			continue
+		}
+		partNumber, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat part: %w", err)
+		}
+		sum, err := os.ReadFile(m.partPath(id, partNumber) + ".md5")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read part checksum: %w", err)
+		}
+		parts = append(parts, PartInfo{
+			PartNumber:   partNumber,
+			LastModified: info.ModTime().UTC(),
+			ETag:         "\"" + string(sum) + "\"",
+			Size:         info.Size(),
+		})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// remove deletes an upload and its parts
+func (m *multipartStore) remove(id string) error {
+	return os.RemoveAll(filepath.Join(m.dir, id))
+}
+
+// partsReader concatenates part files, opening one at a time
+type partsReader struct {
+	paths   []string
+	current *os.File
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.current == nil {
+			if len(p.paths) == 0 {
+				return 0, io.EOF
+			}
+			f, err := os.Open(p.paths[0])
+			if err != nil {
+				return 0, fmt.Errorf("failed to open part: %w", err)
+			}
+			p.current, p.paths = f, p.paths[1:]
+		}
+
+		n, err := p.current.Read(b)
+		if errors.Is(err, io.EOF) {
+			p.current.Close()
+			p.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close releases the part being read
+func (p *partsReader) Close() error {
+	if p.current != nil {
+		return p.current.Close()
+	}
+	return nil
+}
+
+// handleCreateMultipartUpload starts a multipart upload
+func (s *Server) handleCreateMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !s.bucketExists(r.Context(), bucket) {
+		s.sendError(w, r, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+		return
+	}
+
+	id, err := s.multipart.create(multipartUpload{
+		Bucket:    bucket,
+		Key:       key,
+		User:      auth.FromContext(r.Context()).Username,
+		Initiated: time.Now().UTC(),
+	})
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+
+	s.sendXML(w, InitiateMultipartUploadResult{Xmlns: s3Namespace, Bucket: bucket, Key: key, UploadID: id}, http.StatusOK)
+}
+
+// handleUploadPart stores one part of a multipart upload
+func (s *Server) handleUploadPart(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	id := r.URL.Query().Get("uploadId")
+	if _, err := s.multipart.get(id, bucket, key, auth.FromContext(r.Context()).Username); err != nil {
+		s.sendMultipartError(w, r, err)
+		return
+	}
+
+	partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		s.sendError(w, r, "InvalidArgument", "Part number must be an integer between 1 and 10000.", http.StatusBadRequest)
+		return
+	}
+
+	sum, err := s.multipart.writePart(id, partNumber, r.Body)
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+
+	w.Header().Set("ETag", "\""+hex.EncodeToString(sum)+"\"")
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleListParts lists the parts uploaded so far
+func (s *Server) handleListParts(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	id := r.URL.Query().Get("uploadId")
+	if _, err := s.multipart.get(id, bucket, key, auth.FromContext(r.Context()).Username); err != nil {
+		s.sendMultipartError(w, r, err)
+		return
+	}
+
+	parts, err := s.multipart.parts(id)
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+	s.sendXML(w, ListPartsResult{Xmlns: s3Namespace, Bucket: bucket, Key: key, UploadID: id, Parts: parts}, http.StatusOK)
+}
+
+// handleCompleteMultipartUpload assembles the listed parts into the object
+func (s *Server) handleCompleteMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	id := r.URL.Query().Get("uploadId")
+	if _, err := s.multipart.get(id, bucket, key, auth.FromContext(r.Context()).Username); err != nil {
+		s.sendMultipartError(w, r, err)
+		return
+	}
+
+	var request CompleteMultipartUpload
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxCompleteBody)).Decode(&request); err != nil || len(request.Parts) == 0 {
+		s.sendError(w, r, "MalformedXML", "The XML you provided was not well-formed.", http.StatusBadRequest)
+		return
+	}
+
+	uploaded, err := s.multipart.parts(id)
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+	etags := make(map[int]string, len(uploaded))
+	for _, part := range uploaded {
+		etags[part.PartNumber] = part.ETag
+	}
+
+	// Parts must be ascending and match what was uploaded
+	paths := make([]string, 0, len(request.Parts))
+	for i, part := range request.Parts {
+		if i > 0 && part.PartNumber <= request.Parts[i-1].PartNumber {
+			s.sendError(w, r, "InvalidPartOrder", "The list of parts was not in ascending order.", http.StatusBadRequest)
+			return
+		}
+		etag, ok := etags[part.PartNumber]
+		if !ok || strings.Trim(part.ETag, "\"") != strings.Trim(etag, "\"") {
+			s.sendError(w, r, "InvalidPart", "One or more of the specified parts could not be found.", http.StatusBadRequest)
+			return
+		}
+		paths = append(paths, s.multipart.partPath(id, part.PartNumber))
+	}
+
+	reader := &partsReader{paths: paths}
+	defer reader.Close()
+	info, err := s.writeObject(r, objectPath(bucket, key), reader, "")
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+
+	if err := s.multipart.remove(id); err != nil {
+		slog.Warn("failed to remove completed multipart upload", "upload_id", id, "error", err)
+	}
+
+	s.sendXML(w, CompleteMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Location: "/" + bucket + "/" + key,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     fileETag(info),
+	}, http.StatusOK)
+}
+
+// handleAbortMultipartUpload discards an upload and its parts
+func (s *Server) handleAbortMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	id := r.URL.Query().Get("uploadId")
+	if _, err := s.multipart.get(id, bucket, key, auth.FromContext(r.Context()).Username); err != nil {
+		s.sendMultipartError(w, r, err)
+		return
+	}
+	if err := s.multipart.remove(id); err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendMultipartError reports an unknown upload ID or a store failure
+func (s *Server) sendMultipartError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errNoSuchUpload) {
+		s.sendError(w, r, "NoSuchUpload", "The specified multipart upload does not exist.", http.StatusNotFound)
+		return
+	}
+	s.sendServiceError(w, r, err, "NoSuchKey")
+}
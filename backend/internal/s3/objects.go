@@ -0,0 +1,348 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+)
+
+// maxDeleteObjectsBody bounds the DeleteObjects request body (1000 keys per request)
+const maxDeleteObjectsBody = 2 << 20
+
+// fileETag returns the listing ETag for an open file
+func fileETag(info os.FileInfo) string {
+	return itemETag(info.ModTime(), info.Size())
+}
+
+// handleListBuckets lists top-level directories as buckets
+func (s *Server) handleListBuckets(w http.ResponseWriter, r *http.Request) {
+	listing, err := s.svc.ListFiles(r.Context(), "/")
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchBucket")
+		return
+	}
+
+	identity := auth.FromContext(r.Context())
+	result := ListAllMyBucketsResult{
+		Xmlns:   s3Namespace,
+		Owner:   Owner{ID: identity.Username, DisplayName: identity.Username},
+		Buckets: []BucketInfo{},
+	}
+	for _, item := range listing.Items {
+		if item.IsDir && isValidBucket(item.Name) {
+			result.Buckets = append(result.Buckets, BucketInfo{Name: item.Name, CreationDate: item.ModTime.UTC()})
+		}
+	}
+	s.sendXML(w, result, http.StatusOK)
+}
+
+// handleHeadBucket reports whether a bucket exists
+func (s *Server) handleHeadBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	if !s.bucketExists(r.Context(), bucket) {
+		s.sendError(w, r, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("X-Amz-Bucket-Region", defaultRegion)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleCreateBucket creates a top-level directory
+func (s *Server) handleCreateBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	if s.bucketExists(r.Context(), bucket) {
+		s.sendError(w, r, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.", http.StatusConflict)
+		return
+	}
+	if err := s.svc.CreateDirectory(r.Context(), "/"+bucket); err != nil {
+		s.sendServiceError(w, r, err, "NoSuchBucket")
+		return
+	}
+	w.Header().Set("Location", "/"+bucket)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDeleteBucket removes an empty top-level directory
+func (s *Server) handleDeleteBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	listing, err := s.svc.ListFiles(r.Context(), "/"+bucket)
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchBucket")
+		return
+	}
+	if len(listing.Items) > 0 {
+		s.sendError(w, r, "BucketNotEmpty", "The bucket you tried to delete is not empty.", http.StatusConflict)
+		return
+	}
+	if err := s.svc.DeleteFile(r.Context(), "/"+bucket); err != nil {
+		s.sendServiceError(w, r, err, "NoSuchBucket")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetObject serves GetObject and HeadObject, including Range requests
+func (s *Server) handleGetObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	handle, err := s.svc.OpenHandle(r.Context(), objectPath(bucket, key), os.O_RDONLY, 0)
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+	defer handle.Close()
+
+	info, err := handle.Stat()
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+	if info.IsDir() {
+		// Directories answer as empty "dir/" marker objects
+		if !strings.HasSuffix(key, "/") {
+			s.sendError(w, r, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-directory")
+		w.Header().Set("Content-Length", "0")
+		w.Header().Set("ETag", "\""+hex.EncodeToString(md5.New().Sum(nil))+"\"")
+		w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", fileETag(info))
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	http.ServeContent(w, r, "", info.ModTime(), handle)
+}
+
+// handlePutObject writes an object, creating parent directories as needed
+func (s *Server) handlePutObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !s.bucketExists(r.Context(), bucket) {
+		s.sendError(w, r, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+		return
+	}
+
+	// Keys ending in "/" are directory markers
+	if strings.HasSuffix(key, "/") {
+		if err := s.ensureParents(r.Context(), objectPath(bucket, key)+"/"); err != nil {
+			s.sendServiceError(w, r, err, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", "\""+hex.EncodeToString(md5.New().Sum(nil))+"\"")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	info, err := s.writeObject(r, objectPath(bucket, key), r.Body, r.Header.Get("Content-MD5"))
+	if errors.Is(err, errBadDigest) {
+		s.sendError(w, r, "BadDigest", "The Content-MD5 you specified did not match what we received.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+
+	w.Header().Set("ETag", fileETag(info))
+	w.WriteHeader(http.StatusOK)
+}
+
+// errBadDigest is returned when an upload does not match its Content-MD5
+var errBadDigest = errors.New("content MD5 mismatch")
+
+// writeObject streams content into objPath and returns the stored object's
+// info, whose fileETag matches what HEAD, GET and listings report. The object
+// is only replaced once all of content arrived and matches contentMD5, the
+// base64 digest from Content-MD5 when given, so a failed upload leaves an
+// existing object untouched.
+func (s *Server) writeObject(r *http.Request, objPath string, content io.Reader, contentMD5 string) (os.FileInfo, error) {
+	ctx := r.Context()
+	if info, err := s.svc.Stat(ctx, objPath); err == nil && info.IsDir() {
+		return nil, fmt.Errorf("invalid path: %s is a directory", objPath)
+	}
+	if err := s.ensureParents(ctx, objPath); err != nil {
+		return nil, err
+	}
+
+	hash := md5.New()
+	err := s.svc.WriteFile(ctx, objPath, io.TeeReader(content, hash), func() error {
+		if contentMD5 != "" && contentMD5 != base64.StdEncoding.EncodeToString(hash.Sum(nil)) {
+			return errBadDigest
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.svc.Stat(ctx, objPath)
+}
+
+// handleCopyObject copies an object from X-Amz-Copy-Source within the gateway
+func (s *Server) handleCopyObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		s.sendError(w, r, "InvalidArgument", "Invalid copy source.", http.StatusBadRequest)
+		return
+	}
+	source, _, _ = strings.Cut(source, "?")
+	srcBucket, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	if !isValidBucket(srcBucket) || srcKey == "" || !isValidKey(srcKey) {
+		s.sendError(w, r, "InvalidArgument", "Invalid copy source.", http.StatusBadRequest)
+		return
+	}
+	if !s.bucketExists(r.Context(), bucket) {
+		s.sendError(w, r, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+		return
+	}
+
+	srcPath, dstPath := objectPath(srcBucket, srcKey), objectPath(bucket, key)
+	if srcPath == dstPath {
+		s.sendError(w, r, "InvalidRequest", "This copy request is illegal because it is trying to copy an object to itself.", http.StatusBadRequest)
+		return
+	}
+
+	src, err := s.svc.OpenHandle(r.Context(), srcPath, os.O_RDONLY, 0)
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+	defer src.Close()
+
+	if info, err := src.Stat(); err != nil || info.IsDir() {
+		s.sendError(w, r, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound)
+		return
+	}
+
+	info, err := s.writeObject(r, dstPath, src, "")
+	if err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+
+	s.sendXML(w, CopyObjectResult{
+		LastModified: info.ModTime().UTC(),
+		ETag:         fileETag(info),
+	}, http.StatusOK)
+}
+
+// handleDeleteObject removes an object; missing keys succeed as in S3
+func (s *Server) handleDeleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !s.bucketExists(r.Context(), bucket) {
+		s.sendError(w, r, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+		return
+	}
+	if err := s.deleteObject(r, bucket, key); err != nil {
+		s.sendServiceError(w, r, err, "NoSuchKey")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errDirectoryNotEmpty is returned when deleting a directory marker that still has children
+var errDirectoryNotEmpty = errors.New("directory not empty")
+
+// deleteObject removes a file, or an empty directory for keys ending in "/"
+func (s *Server) deleteObject(r *http.Request, bucket, key string) error {
+	objPath := objectPath(bucket, key)
+	info, err := s.svc.Stat(r.Context(), objPath)
+	if err != nil {
+		return nil
+	}
+
+	if info.IsDir() {
+		if !strings.HasSuffix(key, "/") {
+			return nil
+		}
+		listing, err := s.svc.ListFiles(r.Context(), objPath)
+		if err != nil {
+			return err
+		}
+		if len(listing.Items) > 0 {
+			return errDirectoryNotEmpty
+		}
+	}
+	if err := s.svc.DeleteFile(r.Context(), objPath); err != nil {
+		return err
+	}
+
+	s.pruneEmptyParents(r, bucket, objPath)
+	return nil
+}
+
+// pruneEmptyParents removes directories left empty by a delete, stopping at the bucket,
+// so prefixes disappear like they do in S3
+func (s *Server) pruneEmptyParents(r *http.Request, bucket, objPath string) {
+	bucketPath := "/" + bucket
+	for dir := path.Dir(objPath); dir != bucketPath && dir != "/"; dir = path.Dir(dir) {
+		listing, err := s.svc.ListFiles(r.Context(), dir)
+		if err != nil || len(listing.Items) > 0 {
+			return
+		}
+		if err := s.svc.DeleteFile(r.Context(), dir); err != nil {
+			return
+		}
+	}
+}
+
+// handleDeleteObjects removes up to 1000 keys in one request
+func (s *Server) handleDeleteObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	if !s.bucketExists(r.Context(), bucket) {
+		s.sendError(w, r, "NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound)
+		return
+	}
+
+	var request DeleteObjectsRequest
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxDeleteObjectsBody)).Decode(&request); err != nil {
+		s.sendError(w, r, "MalformedXML", "The XML you provided was not well-formed.", http.StatusBadRequest)
+		return
+	}
+	if len(request.Objects) > 1000 {
+		s.sendError(w, r, "MalformedXML", "A maximum of 1000 keys may be deleted per request.", http.StatusBadRequest)
+		return
+	}
+
+	// Delete deeper keys first so directory markers are empty by the time they are reached
+	sort.SliceStable(request.Objects, func(i, j int) bool {
+		return len(request.Objects[i].Key) > len(request.Objects[j].Key)
+	})
+
+	result := DeleteObjectsResult{Xmlns: s3Namespace}
+	for _, object := range request.Objects {
+		var err error
+		if !isValidKey(object.Key) {
+			err = fmt.Errorf("invalid path: %s", object.Key)
+		} else {
+			err = s.deleteObject(r, bucket, object.Key)
+		}
+
+		if err != nil {
+			code := "InternalError"
+			if strings.Contains(err.Error(), "access denied") {
+				code = "AccessDenied"
+			} else if strings.Contains(err.Error(), "invalid path") || errors.Is(err, errDirectoryNotEmpty) {
+				code = "InvalidArgument"
+			}
+			result.Errors = append(result.Errors, DeleteError{Key: object.Key, Code: code, Message: err.Error()})
+			continue
+		}
+		if !request.Quiet {
+			result.Deleted = append(result.Deleted, DeletedObject{Key: object.Key})
+		}
+	}
+	s.sendXML(w, result, http.StatusOK)
+}
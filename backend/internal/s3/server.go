@@ -0,0 +1,286 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/metrics"
+)
+
+// defaultRegion is reported by GetBucketLocation; signatures for any region are accepted
+const defaultRegion = "us-east-1"
+
+// credential is one S3 access key and the user its requests act as
+type credential struct {
+	secretKey string
+	user      string
+}
+
+// Server is an S3-compatible gateway where top-level directories are buckets
+type Server struct {
+	svc         files.FileServiceInterface
+	credentials map[string]credential
+	multipart   *multipartStore
+	now         func() time.Time
+}
+
+// ListenAddr returns the S3 listen address; the gateway is disabled when empty
+func ListenAddr() string {
+	return os.Getenv("S3_ADDR")
+}
+
+// NewServer creates the gateway from S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY and S3_USER
+func NewServer(svc files.FileServiceInterface) (*Server, error) {
+	accessKey := os.Getenv("S3_ACCESS_KEY_ID")
+	secretKey := os.Getenv("S3_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, errors.New("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for the S3 gateway")
+	}
+
+	user := os.Getenv("S3_USER")
+	if user == "" {
+		user = "s3"
+	}
+
+	multipart, err := newMultipartStore(config.StateDir("s3", "multipart"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		svc: svc,
+		credentials: map[string]credential{
+			accessKey: {secretKey: secretKey, user: user},
+		},
+		multipart: multipart,
+		now:       time.Now,
+	}, nil
+}
+
+// Handler returns the gateway wrapped with request logging and metrics
+func (s *Server) Handler() http.Handler {
+	return logging.Middleware(metrics.InstrumentRoute("/s3", s))
+}
+
+// ServeHTTP authenticates the request and dispatches the S3 operation
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	signing, err := s.verifyRequest(r)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("s3 authentication failed", "error", err, "client_ip", auth.RequestClientIP(r))
+		s.sendAuthError(w, r, err)
+		return
+	}
+
+	ctx := auth.WithClientIP(r.Context(), auth.RequestClientIP(r))
+	ctx = auth.WithIdentity(ctx, auth.Identity{Username: signing.user, Role: auth.RoleUser})
+	r = r.WithContext(ctx)
+
+	// Decode or verify the payload according to x-amz-content-sha256
+	switch {
+	case isStreamingPayload(signing.payloadHash):
+		r.Body = io.NopCloser(newChunkedReader(r.Body, signing))
+	case len(signing.payloadHash) == 64:
+		r.Body = io.NopCloser(newHashingReader(r.Body, signing.payloadHash))
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "" && !isValidBucket(bucket) {
+		s.sendError(w, r, "InvalidBucketName", "The specified bucket is not valid.", http.StatusBadRequest)
+		return
+	}
+	if key != "" && !isValidKey(key) {
+		s.sendError(w, r, "InvalidArgument", "The specified key is not valid.", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case bucket == "":
+		if r.Method != http.MethodGet {
+			s.sendError(w, r, "MethodNotAllowed", "The specified method is not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleListBuckets(w, r)
+
+	case key == "":
+		switch {
+		case r.Method == http.MethodGet && query.Has("location"):
+			s.sendXML(w, LocationConstraint{Xmlns: s3Namespace, Location: defaultRegion}, http.StatusOK)
+		case r.Method == http.MethodGet && query.Get("list-type") == "2":
+			s.handleListObjectsV2(w, r, bucket)
+		case r.Method == http.MethodGet && !query.Has("uploads"):
+			s.handleListObjectsV1(w, r, bucket)
+		case r.Method == http.MethodHead:
+			s.handleHeadBucket(w, r, bucket)
+		case r.Method == http.MethodPut:
+			s.handleCreateBucket(w, r, bucket)
+		case r.Method == http.MethodDelete:
+			s.handleDeleteBucket(w, r, bucket)
+		case r.Method == http.MethodPost && query.Has("delete"):
+			s.handleDeleteObjects(w, r, bucket)
+		default:
+			s.sendError(w, r, "NotImplemented", "This operation is not supported.", http.StatusNotImplemented)
+		}
+
+	default:
+		switch {
+		case r.Method == http.MethodGet && query.Has("uploadId"):
+			s.handleListParts(w, r, bucket, key)
+		case r.Method == http.MethodGet:
+			s.handleGetObject(w, r, bucket, key)
+		case r.Method == http.MethodHead:
+			s.handleGetObject(w, r, bucket, key)
+		case r.Method == http.MethodPut && query.Has("uploadId"):
+			s.handleUploadPart(w, r, bucket, key)
+		case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+			s.handleCopyObject(w, r, bucket, key)
+		case r.Method == http.MethodPut:
+			s.handlePutObject(w, r, bucket, key)
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			s.handleCreateMultipartUpload(w, r, bucket, key)
+		case r.Method == http.MethodPost && query.Has("uploadId"):
+			s.handleCompleteMultipartUpload(w, r, bucket, key)
+		case r.Method == http.MethodDelete && query.Has("uploadId"):
+			s.handleAbortMultipartUpload(w, r, bucket, key)
+		case r.Method == http.MethodDelete:
+			s.handleDeleteObject(w, r, bucket, key)
+		default:
+			s.sendError(w, r, "NotImplemented", "This operation is not supported.", http.StatusNotImplemented)
+		}
+	}
+}
+
+var bucketPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._ -]{0,254}$`)
+
+// isValidBucket accepts directory names that cannot escape the base path
+func isValidBucket(bucket string) bool {
+	return bucketPattern.MatchString(bucket) && !strings.Contains(bucket, "..")
+}
+
+// isValidKey rejects keys with traversal segments or NUL bytes
+func isValidKey(key string) bool {
+	if strings.ContainsRune(key, 0) || strings.HasPrefix(key, "/") {
+		return false
+	}
+	for _, segment := range strings.Split(strings.TrimSuffix(key, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// objectPath maps a bucket and key to a file service path
+func objectPath(bucket, key string) string {
+	return path.Join("/", bucket, key)
+}
+
+// sendXML writes an XML response
+func (s *Server) sendXML(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(statusCode)
+	io.WriteString(w, xml.Header)
+	if err := xml.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode S3 response", "error", err)
+	}
+}
+
+// sendError writes an S3 XML error
+func (s *Server) sendError(w http.ResponseWriter, r *http.Request, code, message string, statusCode int) {
+	requestID := logging.RequestID(r.Context())
+	w.Header().Set("X-Amz-Request-Id", requestID)
+	if r.Method == http.MethodHead {
+		w.WriteHeader(statusCode)
+		return
+	}
+	s.sendXML(w, ErrorResponse{
+		Code:      code,
+		Message:   message,
+		Resource:  r.URL.Path,
+		RequestID: requestID,
+	}, statusCode)
+}
+
+// sendAuthError maps signature verification errors to S3 errors
+func (s *Server) sendAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errInvalidAccessKey):
+		s.sendError(w, r, "InvalidAccessKeyId", "The access key ID you provided does not exist.", http.StatusForbidden)
+	case errors.Is(err, errSignatureMismatch):
+		s.sendError(w, r, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden)
+	case errors.Is(err, errRequestExpired):
+		s.sendError(w, r, "RequestTimeTooSkewed", "The difference between the request time and the server's time is too large.", http.StatusForbidden)
+	case errors.Is(err, errMalformedAuth):
+		s.sendError(w, r, "AuthorizationHeaderMalformed", "The authorization header is malformed.", http.StatusBadRequest)
+	default:
+		s.sendError(w, r, "AccessDenied", "Access Denied", http.StatusForbidden)
+	}
+}
+
+// sendServiceError maps file service errors to S3 errors
+func (s *Server) sendServiceError(w http.ResponseWriter, r *http.Request, err error, notFoundCode string) {
+	message := err.Error()
+	switch {
+	case errors.Is(err, errSignatureMismatch):
+		s.sendError(w, r, "SignatureDoesNotMatch", "The chunk signature does not match.", http.StatusForbidden)
+	case errors.Is(err, errContentSHA256):
+		s.sendError(w, r, "XAmzContentSHA256Mismatch", "The provided x-amz-content-sha256 does not match the payload.", http.StatusBadRequest)
+	case errors.Is(err, os.ErrNotExist) || strings.Contains(message, "not found") || strings.Contains(message, "no such file"):
+		if notFoundCode == "NoSuchBucket" {
+			s.sendError(w, r, notFoundCode, "The specified bucket does not exist.", http.StatusNotFound)
+		} else {
+			s.sendError(w, r, notFoundCode, "The specified key does not exist.", http.StatusNotFound)
+		}
+	case strings.Contains(message, "access denied") || strings.Contains(message, "permission denied"):
+		s.sendError(w, r, "AccessDenied", "Access Denied", http.StatusForbidden)
+	case strings.Contains(message, "invalid path"):
+		s.sendError(w, r, "InvalidArgument", "Invalid path.", http.StatusBadRequest)
+	default:
+		logging.FromContext(r.Context()).Error("s3 operation failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		s.sendError(w, r, "InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError)
+	}
+}
+
+// bucketExists reports whether a bucket directory exists
+func (s *Server) bucketExists(ctx context.Context, bucket string) bool {
+	info, err := s.svc.Stat(ctx, "/"+bucket)
+	return err == nil && info.IsDir()
+}
+
+// ensureParents creates the directories leading up to an object
+func (s *Server) ensureParents(ctx context.Context, objPath string) error {
+	dir := path.Dir(objPath)
+	var missing []string
+	for dir != "/" {
+		info, err := s.svc.Stat(ctx, dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("invalid path: %s is a file", dir)
+			}
+			break
+		}
+		missing = append(missing, dir)
+		dir = path.Dir(dir)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := s.svc.CreateDirectory(ctx, missing[i]); err != nil && !strings.Contains(err.Error(), "exists") {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,479 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat = "20060102T150405Z"
+	maxClockSkew  = 15 * time.Minute
+
+	unsignedPayload        = "UNSIGNED-PAYLOAD"
+	streamingPayload       = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingPayloadTrail  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	streamingUnsignedTrail = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+
+	emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// Signature verification errors, mapped to S3 error codes by the server
+var (
+	errAccessDenied      = errors.New("access denied")
+	errInvalidAccessKey  = errors.New("invalid access key")
+	errSignatureMismatch = errors.New("signature does not match")
+	errRequestExpired    = errors.New("request expired")
+	errMalformedAuth     = errors.New("malformed authorization")
+	errContentSHA256     = errors.New("content sha256 mismatch")
+)
+
+// signingContext carries what a verified request needs to verify streamed chunks
+type signingContext struct {
+	accessKey   string
+	user        string
+	amzDate     string
+	scope       string
+	signingKey  []byte
+	seedSig     string
+	payloadHash string
+}
+
+// credentialScope is the parsed Credential component of a SigV4 signature
+type credentialScope struct {
+	accessKey string
+	date      string
+	region    string
+	service   string
+}
+
+// verifyRequest checks the SigV4 signature of a request in either header or presigned form
+func (s *Server) verifyRequest(r *http.Request) (*signingContext, error) {
+	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		return s.verifyPresigned(r)
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, errAccessDenied
+	}
+	if !strings.HasPrefix(header, signAlgorithm+" ") {
+		return nil, errMalformedAuth
+	}
+
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimPrefix(header, signAlgorithm+" "), ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, errMalformedAuth
+		}
+		fields[key] = value
+	}
+
+	scope, err := parseCredential(fields["Credential"])
+	if err != nil {
+		return nil, err
+	}
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	signature := fields["Signature"]
+	if len(signedHeaders) == 0 || signature == "" {
+		return nil, errMalformedAuth
+	}
+
+	requestTime, err := requestDate(r)
+	if err != nil {
+		return nil, err
+	}
+	amzDate := requestTime.Format(amzDateFormat)
+	if err := checkScope(scope, signedHeaders, amzDate); err != nil {
+		return nil, err
+	}
+	if skew := s.now().Sub(requestTime); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, errRequestExpired
+	}
+
+	// Generic SigV4 signers omit x-amz-content-sha256 and sign the hash of the body;
+	// that can only be checked up front when there is no body
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		if r.ContentLength != 0 {
+			return nil, errMalformedAuth
+		}
+		payloadHash = emptySHA256
+	}
+
+	return s.checkSignature(r, scope, amzDate, signedHeaders, signature, payloadHash, r.URL.Query())
+}
+
+// verifyPresigned checks a presigned URL signature
+func (s *Server) verifyPresigned(r *http.Request) (*signingContext, error) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != signAlgorithm {
+		return nil, errMalformedAuth
+	}
+
+	scope, err := parseCredential(query.Get("X-Amz-Credential"))
+	if err != nil {
+		return nil, err
+	}
+
+	amzDate := query.Get("X-Amz-Date")
+	requestTime, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		return nil, errMalformedAuth
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 || expires > 7*24*3600 {
+		return nil, errMalformedAuth
+	}
+
+	signature := query.Get("X-Amz-Signature")
+	signedHeaders := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	if err := checkScope(scope, signedHeaders, amzDate); err != nil {
+		return nil, err
+	}
+	if s.now().After(requestTime.Add(time.Duration(expires) * time.Second)) {
+		return nil, errRequestExpired
+	}
+
+	canonicalQuery := url.Values{}
+	for key, values := range query {
+		if key != "X-Amz-Signature" {
+			canonicalQuery[key] = values
+		}
+	}
+
+	return s.checkSignature(r, scope, amzDate, signedHeaders, signature, unsignedPayload, canonicalQuery)
+}
+
+// checkSignature recomputes the request signature and compares it
+func (s *Server) checkSignature(r *http.Request, scope credentialScope, amzDate string, signedHeaders []string, signature, payloadHash string, query url.Values) (*signingContext, error) {
+	credential, exists := s.credentials[scope.accessKey]
+	if !exists {
+		return nil, errInvalidAccessKey
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		encodePath(r.URL.Path),
+		canonicalQueryString(query),
+		canonicalHeaders(r, signedHeaders),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scopeString := strings.Join([]string{scope.date, scope.region, scope.service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		signAlgorithm,
+		amzDate,
+		scopeString,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := deriveSigningKey(credential.secretKey, scope)
+	expected := hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+		return nil, errSignatureMismatch
+	}
+
+	return &signingContext{
+		accessKey:   scope.accessKey,
+		user:        credential.user,
+		amzDate:     amzDate,
+		scope:       scopeString,
+		signingKey:  signingKey,
+		seedSig:     signature,
+		payloadHash: payloadHash,
+	}, nil
+}
+
+// requestDate returns the signing time from X-Amz-Date, or from the Date
+// header in HTTP format when X-Amz-Date is absent
+func requestDate(r *http.Request) (time.Time, error) {
+	if amzDate := r.Header.Get("X-Amz-Date"); amzDate != "" {
+		requestTime, err := time.Parse(amzDateFormat, amzDate)
+		if err != nil {
+			return time.Time{}, errMalformedAuth
+		}
+		return requestTime, nil
+	}
+	requestTime, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, errMalformedAuth
+	}
+	return requestTime.UTC(), nil
+}
+
+// checkScope requires the credential scope to be for the day the request was
+// signed and the signature to cover the host header
+func checkScope(scope credentialScope, signedHeaders []string, amzDate string) error {
+	if scope.date != amzDate[:len("20060102")] {
+		return errMalformedAuth
+	}
+	for _, name := range signedHeaders {
+		if name == "host" {
+			return nil
+		}
+	}
+	return errMalformedAuth
+}
+
+// parseCredential parses AKID/date/region/service/aws4_request
+func parseCredential(value string) (credentialScope, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return credentialScope{}, errMalformedAuth
+	}
+	return credentialScope{
+		accessKey: parts[0],
+		date:      parts[1],
+		region:    parts[2],
+		service:   parts[3],
+	}, nil
+}
+
+// deriveSigningKey derives the SigV4 signing key for a credential scope
+func deriveSigningKey(secretKey string, scope credentialScope) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), []byte(scope.date))
+	key = hmacSHA256(key, []byte(scope.region))
+	key = hmacSHA256(key, []byte(scope.service))
+	return hmacSHA256(key, []byte("aws4_request"))
+}
+
+// canonicalHeaders builds the canonical header block for the signed headers
+func canonicalHeaders(r *http.Request, signedHeaders []string) string {
+	var b strings.Builder
+	for _, name := range signedHeaders {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = strconv.FormatInt(r.ContentLength, 10)
+			if r.ContentLength < 0 {
+				value = r.Header.Get("Content-Length")
+			}
+		default:
+			value = strings.Join(r.Header.Values(name), ",")
+		}
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(strings.Fields(value), " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// canonicalQueryString sorts and encodes query parameters the way SigV4 expects
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// encodePath encodes a URL path for the canonical request, keeping slashes
+func encodePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return uriEncode(path, false)
+}
+
+// uriEncode percent-encodes everything except unreserved characters
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// isStreamingPayload reports whether the body uses aws-chunked encoding
+func isStreamingPayload(payloadHash string) bool {
+	return payloadHash == streamingPayload || payloadHash == streamingPayloadTrail || payloadHash == streamingUnsignedTrail
+}
+
+// chunkedReader decodes an aws-chunked body, verifying chunk signatures when signed
+type chunkedReader struct {
+	reader   *bufio.Reader
+	signing  *signingContext
+	signed   bool
+	trailer  bool
+	prevSig  string
+	chunk    []byte
+	offset   int
+	finished bool
+	err      error
+}
+
+// newChunkedReader wraps body according to the payload mode of the request
+func newChunkedReader(body io.Reader, signing *signingContext) *chunkedReader {
+	return &chunkedReader{
+		reader:  bufio.NewReaderSize(body, 64*1024),
+		signing: signing,
+		signed:  signing.payloadHash != streamingUnsignedTrail,
+		trailer: signing.payloadHash != streamingPayload,
+		prevSig: signing.seedSig,
+	}
+}
+
+// Read returns decoded payload bytes
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for c.offset >= len(c.chunk) {
+		if c.err != nil {
+			return 0, c.err
+		}
+		if c.finished {
+			return 0, io.EOF
+		}
+		c.err = c.nextChunk()
+	}
+
+	n := copy(p, c.chunk[c.offset:])
+	c.offset += n
+	return n, nil
+}
+
+// nextChunk reads and verifies the next chunk
+func (c *chunkedReader) nextChunk() error {
+	header, err := c.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("malformed chunk header: %w", err)
+	}
+	header = strings.TrimRight(header, "\r\n")
+
+	sizeField, extension, _ := strings.Cut(header, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+	if err != nil || size < 0 || size > 64*1024*1024 {
+		return errors.New("malformed chunk size")
+	}
+
+	chunk := make([]byte, size)
+	if _, err := io.ReadFull(c.reader, chunk); err != nil {
+		return fmt.Errorf("truncated chunk: %w", err)
+	}
+
+	if c.signed {
+		signature := strings.TrimPrefix(extension, "chunk-signature=")
+		if err := c.verifyChunk(chunk, signature); err != nil {
+			return err
+		}
+	}
+
+	if size == 0 {
+		c.finished = true
+		if c.trailer {
+			return c.skipTrailer()
+		}
+		return nil
+	}
+
+	// Chunk data is followed by CRLF
+	if _, err := c.reader.Discard(2); err != nil {
+		return fmt.Errorf("truncated chunk: %w", err)
+	}
+
+	c.chunk = chunk
+	c.offset = 0
+	return nil
+}
+
+// verifyChunk checks one chunk signature against the previous signature
+func (c *chunkedReader) verifyChunk(chunk []byte, signature string) error {
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256-PAYLOAD",
+		c.signing.amzDate,
+		c.signing.scope,
+		c.prevSig,
+		emptySHA256,
+		hexSHA256(chunk),
+	}, "\n")
+
+	expected := hex.EncodeToString(hmacSHA256(c.signing.signingKey, []byte(stringToSign)))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+		return errSignatureMismatch
+	}
+	c.prevSig = signature
+	return nil
+}
+
+// skipTrailer consumes trailing checksum headers after the final chunk
+func (c *chunkedReader) skipTrailer() error {
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("malformed trailer: %w", err)
+		}
+		if len(bytes.TrimSpace([]byte(line))) == 0 {
+			return nil
+		}
+	}
+}
+
+// hashingReader verifies the body against the x-amz-content-sha256 header at EOF
+type hashingReader struct {
+	reader   io.Reader
+	hasher   hash.Hash
+	expected string
+}
+
+// newHashingReader wraps body so a payload hash mismatch surfaces as a read error
+func newHashingReader(body io.Reader, expected string) *hashingReader {
+	return &hashingReader{reader: body, hasher: sha256.New(), expected: expected}
+}
+
+// Read reads from the body and checks the hash once it is exhausted
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.reader.Read(p)
+	h.hasher.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(h.hasher.Sum(nil)) != h.expected {
+		return n, errContentSHA256
+	}
+	return n, err
+}
@@ -0,0 +1,193 @@
+package sftpd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/pkg/sftp"
+)
+
+// handlers serves SFTP requests for one session through the file service.
+// The request context from the sftp library carries no identity, so the
+// session context is used instead.
+type handlers struct {
+	ctx context.Context
+	svc files.FileServiceInterface
+}
+
+// newHandlers returns the sftp request handlers for a session
+func newHandlers(ctx context.Context, svc files.FileServiceInterface) sftp.Handlers {
+	h := &handlers{ctx: ctx, svc: svc}
+	return sftp.Handlers{FileGet: h, FilePut: h, FileCmd: h, FileList: h}
+}
+
+// Fileread opens a file for reading
+func (h *handlers) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	file, err := h.svc.OpenHandle(h.ctx, r.Filepath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &handleAt{file: file}, nil
+}
+
+// Filewrite opens a file for writing with the flags the client asked for
+func (h *handlers) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	pflags := r.Pflags()
+	flag := os.O_WRONLY
+	if pflags.Creat {
+		flag |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flag |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flag |= os.O_EXCL
+	}
+
+	file, err := h.svc.OpenHandle(h.ctx, r.Filepath, flag, 0644)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &handleAt{file: file}, nil
+}
+
+// Filecmd runs commands that change the tree
+func (h *handlers) Filecmd(r *sftp.Request) error {
+	switch r.Method {
+	case "Setstat":
+		// Permissions, ownership and times are managed by the server
+		return nil
+	case "Rename":
+		return mapError(h.svc.MoveFile(h.ctx, r.Filepath, r.Target))
+	case "Mkdir":
+		return mapError(h.svc.CreateDirectory(h.ctx, r.Filepath))
+	case "Rmdir":
+		info, err := h.svc.Stat(h.ctx, r.Filepath)
+		if err != nil {
+			return mapError(err)
+		}
+		if !info.IsDir() {
+			return sftp.ErrSSHFxFailure
+		}
+		// DeleteFile is recursive; SFTP rmdir only removes empty directories
+		listing, err := h.svc.ListFiles(h.ctx, r.Filepath)
+		if err != nil {
+			return mapError(err)
+		}
+		if len(listing.Items) > 0 {
+			return sftp.ErrSSHFxFailure
+		}
+		return mapError(h.svc.DeleteFile(h.ctx, r.Filepath))
+	case "Remove":
+		info, err := h.svc.Stat(h.ctx, r.Filepath)
+		if err != nil {
+			return mapError(err)
+		}
+		if info.IsDir() {
+			return sftp.ErrSSHFxFailure
+		}
+		return mapError(h.svc.DeleteFile(h.ctx, r.Filepath))
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// Filelist lists directories and stats paths
+func (h *handlers) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		dir, err := h.svc.OpenHandle(h.ctx, r.Filepath, os.O_RDONLY, 0)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		defer dir.Close()
+
+		entries, err := dir.Readdir(-1)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		return listerAt(entries), nil
+	case "Stat":
+		info, err := h.svc.Stat(h.ctx, r.Filepath)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		return listerAt{info}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// listerAt serves a fixed slice of entries
+type listerAt []os.FileInfo
+
+// ListAt copies entries starting at offset
+func (l listerAt) ListAt(entries []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(entries, l[offset:])
+	if n < len(entries) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// handleAt adds positional reads and writes to a file service handle.
+// Closing it closes the handle, which is when writes are audited.
+type handleAt struct {
+	mu   sync.Mutex
+	file files.File
+}
+
+// ReadAt reads len(p) bytes at off
+func (h *handleAt) ReadAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := h.file.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(h.file, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// WriteAt writes p at off
+func (h *handleAt) WriteAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := h.file.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return h.file.Write(p)
+}
+
+// Close closes the underlying handle
+func (h *handleAt) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.file.Close()
+}
+
+// mapError converts wrapped service errors into SFTP status codes
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := err.Error()
+	switch {
+	case errors.Is(err, os.ErrNotExist) || strings.Contains(message, "not found") || strings.Contains(message, "does not exist"):
+		return sftp.ErrSSHFxNoSuchFile
+	case errors.Is(err, os.ErrPermission) || strings.Contains(message, "access denied") || strings.Contains(message, "invalid path"):
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	return err
+}
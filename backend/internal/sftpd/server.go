@@ -0,0 +1,220 @@
+package sftpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// roleExtension carries the authenticated role through ssh.Permissions
+const roleExtension = "role"
+
+// ErrServerClosed is returned by Serve after Shutdown
+var ErrServerClosed = errors.New("sftp: server closed")
+
+// Server is an SFTP-only SSH server over the file service
+type Server struct {
+	svc    files.FileServiceInterface
+	config *ssh.ServerConfig
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// ListenAddr returns the SFTP listen address; the server is disabled when empty
+func ListenAddr() string {
+	return os.Getenv("SFTP_ADDR")
+}
+
+// NewServer creates the SFTP server. Users log in with the same passwords as
+// the HTTP API; the host key comes from SFTP_HOST_KEY_FILE or is generated in the state directory.
+func NewServer(svc files.FileServiceInterface, authenticator *auth.Authenticator) (*Server, error) {
+	if !authenticator.HasPasswordUsers() {
+		return nil, errors.New("the SFTP server requires users in AUTH_USERS_FILE")
+	}
+
+	hostKeyPath := os.Getenv("SFTP_HOST_KEY_FILE")
+	if hostKeyPath == "" {
+		hostKeyPath = config.StateDir("sftp", "ssh_host_ed25519_key")
+	}
+	hostKey, err := loadOrCreateHostKey(hostKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sshConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			identity, ok := authenticator.VerifyPassword(conn.User(), string(password))
+			if !ok {
+				slog.Warn("sftp authentication failed", "user", conn.User(), "remote_addr", conn.RemoteAddr().String())
+				return nil, fmt.Errorf("invalid credentials for %s", conn.User())
+			}
+			return &ssh.Permissions{Extensions: map[string]string{roleExtension: identity.Role}}, nil
+		},
+		ServerVersion: "SSH-2.0-homelab-file-manager",
+	}
+	sshConfig.AddHostKey(hostKey)
+
+	return &Server{
+		svc:    svc,
+		config: sshConfig,
+		conns:  make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// ListenAndServe listens on addr and serves connections until Shutdown
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener until Shutdown
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return ErrServerClosed
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections, closes open sessions and waits for them to finish
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track registers an accepted connection unless the server is shutting down
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+// handleConn performs the SSH handshake and serves the connection's sessions
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		slog.Debug("sftp handshake failed", "remote_addr", conn.RemoteAddr().String(), "error", err)
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	// Every operation in the connection shares one request ID in logs and the audit log
+	clientIP, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	ctx = auth.WithClientIP(ctx, clientIP)
+	ctx = auth.WithIdentity(ctx, auth.Identity{Username: sshConn.User(), Role: sshConn.Permissions.Extensions[roleExtension]})
+
+	logger := logging.FromContext(ctx)
+	logger.Info("sftp session opened", "user", sshConn.User(), "remote_addr", conn.RemoteAddr().String())
+	defer logger.Info("sftp session closed", "user", sshConn.User())
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			logger.Warn("failed to accept sftp channel", "error", err)
+			continue
+		}
+		go s.handleSession(ctx, channel, channelRequests)
+	}
+}
+
+// handleSession starts the sftp subsystem and refuses shells, commands and everything else
+func (s *Server) handleSession(ctx context.Context, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for request := range requests {
+		// The subsystem name is an SSH string: a uint32 length followed by the bytes
+		if request.Type != "subsystem" || len(request.Payload) < 4 || string(request.Payload[4:]) != "sftp" {
+			request.Reply(false, nil)
+			continue
+		}
+		request.Reply(true, nil)
+		go ssh.DiscardRequests(requests)
+
+		server := sftp.NewRequestServer(channel, newHandlers(ctx, s.svc))
+		if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			logging.FromContext(ctx).Warn("sftp session ended with error", "error", err)
+		}
+		server.Close()
+		return
+	}
+}
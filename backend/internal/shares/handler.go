@@ -0,0 +1,237 @@
+package shares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/audit"
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/httputil"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ShareInfo is the API representation of a share; it never exposes the password hash
+type ShareInfo struct {
+	Token        string     `json:"token"`
+	URL          string     `json:"url"`
+	Path         string     `json:"path"`
+	IsDir        bool       `json:"isDir"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	MaxDownloads int        `json:"maxDownloads,omitempty"`
+	Downloads    int        `json:"downloads"`
+	HasPassword  bool       `json:"hasPassword"`
+	Active       bool       `json:"active"`
+}
+
+// CreateRequest is the body of POST /shares
+type CreateRequest struct {
+	Path         string     `json:"path"`
+	ExpiresIn    string     `json:"expiresIn,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	MaxDownloads int        `json:"maxDownloads,omitempty"`
+	Password     string     `json:"password,omitempty"`
+}
+
+// ShareResponse is returned when a share is created
+type ShareResponse struct {
+	Success bool      `json:"success"`
+	Share   ShareInfo `json:"share"`
+}
+
+// ShareListResponse is returned by GET /shares
+type ShareListResponse struct {
+	Success     bool        `json:"success"`
+	Shares      []ShareInfo `json:"shares"`
+	TotalItems  int         `json:"totalItems"`
+	RequestTime time.Time   `json:"requestTime"`
+}
+
+// Handler manages shares for authenticated users
+type Handler struct {
+	store *Store
+	svc   files.FileServiceInterface
+	audit audit.Recorder
+}
+
+// NewHandler creates the share management handler for GET, POST and DELETE /shares
+func NewHandler(store *Store, svc files.FileServiceInterface) http.Handler {
+	return &Handler{
+		store: store,
+		svc:   svc,
+		audit: audit.Default,
+	}
+}
+
+// ServeHTTP dispatches on the HTTP method
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.handleList(w, r)
+	case http.MethodPost:
+		h.handleCreate(w, r)
+	case http.MethodDelete:
+		h.handleRevoke(w, r)
+	default:
+		httputil.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleList handles GET /shares - Lists the caller's shares (all shares for admins)
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	identity := auth.FromContext(r.Context())
+
+	owner := identity.Username
+	if identity.IsAdmin() {
+		owner = r.URL.Query().Get("user")
+	}
+
+	shares := h.store.List(owner)
+	infos := make([]ShareInfo, 0, len(shares))
+	for _, share := range shares {
+		infos = append(infos, toInfo(share))
+	}
+
+	httputil.SendJSON(w, ShareListResponse{
+		Success:     true,
+		Shares:      infos,
+		TotalItems:  len(infos),
+		RequestTime: time.Now(),
+	}, http.StatusOK)
+}
+
+// handleCreate handles POST /shares - Creates a share link for a file or directory
+func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateRequest
+	if err := httputil.DecodeJSON(w, r, &req, 64*1024); err != nil {
+		httputil.SendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Path == "" {
+		httputil.SendError(w, "File path is required", http.StatusBadRequest)
+		return
+	}
+	if strings.Contains(req.Path, "..") {
+		httputil.SendError(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+	cleanPath := path.Clean("/" + req.Path)
+
+	expiresAt, err := httputil.ResolveExpiry(req.ExpiresIn, req.ExpiresAt)
+	if err != nil {
+		httputil.SendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.MaxDownloads < 0 {
+		httputil.SendError(w, "maxDownloads must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	// The target must exist inside the sandbox
+	details, err := h.svc.GetFileDetails(ctx, cleanPath)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to resolve share target", "path", cleanPath, "error", err)
+		httputil.SendError(w, "File or directory not found", http.StatusNotFound)
+		return
+	}
+
+	share := Share{
+		Path:         cleanPath,
+		IsDir:        details.IsDir,
+		CreatedBy:    auth.FromContext(ctx).Username,
+		ExpiresAt:    expiresAt,
+		MaxDownloads: req.MaxDownloads,
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		share.PasswordHash = string(hash)
+	}
+
+	created, err := h.store.Create(share)
+	h.audit.Record(ctx, audit.NewEntry(ctx, audit.OpShareCreate, cleanPath, "", 0, err))
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create share", "path", cleanPath, "error", err)
+		httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	logging.FromContext(ctx).Info("created share", "path", cleanPath, "token", created.Token)
+	httputil.SendJSON(w, ShareResponse{Success: true, Share: toInfo(created)}, http.StatusCreated)
+}
+
+// handleRevoke handles DELETE /shares?token= - Revokes a share
+func (h *Handler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		httputil.SendError(w, "Share token is required", http.StatusBadRequest)
+		return
+	}
+
+	share, err := h.store.Get(token)
+	if err != nil {
+		httputil.SendError(w, "Share not found", http.StatusNotFound)
+		return
+	}
+
+	identity := auth.FromContext(ctx)
+	if share.CreatedBy != identity.Username && !identity.IsAdmin() {
+		httputil.SendError(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	err = h.store.Revoke(token)
+	h.audit.Record(ctx, audit.NewEntry(ctx, audit.OpShareRevoke, share.Path, "", 0, err))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			httputil.SendError(w, "Share not found", http.StatusNotFound)
+			return
+		}
+		logging.FromContext(ctx).Error("failed to revoke share", "token", token, "error", err)
+		httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	httputil.SendJSON(w, map[string]interface{}{
+		"success": true,
+		"message": "Share revoked successfully",
+		"token":   token,
+	}, http.StatusOK)
+}
+
+// toInfo converts a stored share into its API representation
+func toInfo(share *Share) ShareInfo {
+	return ShareInfo{
+		Token:        share.Token,
+		URL:          "/s/" + share.Token + "/",
+		Path:         share.Path,
+		IsDir:        share.IsDir,
+		CreatedBy:    share.CreatedBy,
+		CreatedAt:    share.CreatedAt,
+		ExpiresAt:    share.ExpiresAt,
+		MaxDownloads: share.MaxDownloads,
+		Downloads:    share.Downloads,
+		HasPassword:  share.PasswordHash != "",
+		Active:       !share.Expired(time.Now()) && !share.Exhausted(),
+	}
+}
+
+// shareContext tags service calls made on behalf of a share recipient
+func shareContext(ctx context.Context, token string) context.Context {
+	return auth.WithIdentity(ctx, auth.Identity{Username: "share:" + token})
+}
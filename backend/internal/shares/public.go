@@ -0,0 +1,348 @@
+package shares
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/httputil"
+	"github.com/BomScoob12/homelab-file-manager/internal/logging"
+	"github.com/BomScoob12/homelab-file-manager/internal/metrics"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PublicHandler serves the read-only view of shares to anonymous recipients
+type PublicHandler struct {
+	store    *Store
+	svc      files.FileServiceInterface
+	throttle *passwordThrottle
+}
+
+// NewPublicHandler creates the handler mounted at /s/
+//
+//	GET /s/{token}/             HTML listing or download page
+//	GET /s/{token}/list?path=   JSON listing scoped to the share
+//	GET /s/{token}/raw?path=    raw file content
+func NewPublicHandler(store *Store, svc files.FileServiceInterface) http.Handler {
+	return &PublicHandler{
+		store:    store,
+		svc:      svc,
+		throttle: newPasswordThrottle(maxPasswordFailures, passwordLockout),
+	}
+}
+
+// ServeHTTP resolves the share token and dispatches to the view
+func (h *PublicHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		httputil.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/s/")
+	token, action, _ := strings.Cut(rest, "/")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	share, err := h.store.Active(token)
+	if err != nil {
+		h.sendShareError(w, err)
+		return
+	}
+
+	if !h.checkPassword(w, r, share) {
+		return
+	}
+
+	ctx := auth.WithClientIP(r.Context(), auth.RequestClientIP(r))
+	r = r.WithContext(shareContext(ctx, token))
+
+	switch action {
+	case "":
+		h.handleView(w, r, share)
+	case "list":
+		h.handleList(w, r, share)
+	case "raw":
+		h.handleRaw(w, r, share)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleList handles GET /s/{token}/list - Lists a directory inside the share
+func (h *PublicHandler) handleList(w http.ResponseWriter, r *http.Request, share *Share) {
+	if !share.IsDir {
+		httputil.SendError(w, "Share is not a directory", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.list(r, share)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to list share", "token", share.Token, "error", err)
+		httputil.SendError(w, "File or directory not found", http.StatusNotFound)
+		return
+	}
+
+	httputil.SendJSON(w, result, http.StatusOK)
+}
+
+// handleRaw handles GET /s/{token}/raw - Downloads a file inside the share
+func (h *PublicHandler) handleRaw(w http.ResponseWriter, r *http.Request, share *Share) {
+	ctx := r.Context()
+
+	target, ok := h.resolve(ctx, share, r.URL.Query().Get("path"))
+	if !ok {
+		httputil.SendError(w, "Invalid file path provided", http.StatusBadRequest)
+		return
+	}
+
+	// Only count downloads of files that can actually be served
+	details, err := h.svc.GetFileDetails(ctx, target)
+	if err != nil || details.IsDir {
+		httputil.SendError(w, "File not found", http.StatusNotFound)
+		return
+	}
+
+	if countsAsDownload(r, details.ModTime) {
+		if err := h.store.ConsumeDownload(share.Token); err != nil {
+			h.sendShareError(w, err)
+			return
+		}
+	}
+
+	// Shares may be password protected or expire, so shared caches must not keep them
+	w.Header().Set("Cache-Control", "private, no-store")
+	rw := logging.NewResponseWriter(w)
+	err = h.svc.ServeRawFile(ctx, rw, r, target)
+	metrics.RawBytesServed.WithLabelValues("share").Add(float64(rw.BytesWritten()))
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to serve shared file", "token", share.Token, "path", target, "error", err)
+		httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// countsAsDownload reports whether a request fetches a file from its start.
+// Range requests that seek or resume, as media players and download managers
+// send many of, continue a download already counted, and conditional
+// requests answered with 304 transfer nothing.
+func countsAsDownload(r *http.Request, modTime time.Time) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	if byteRange := strings.TrimSpace(r.Header.Get("Range")); byteRange != "" && !strings.HasPrefix(byteRange, "bytes=0-") {
+		return false
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modTime.Truncate(time.Second).After(since) {
+		return false
+	}
+	return true
+}
+
+// handleView handles GET /s/{token}/ - Renders a minimal HTML page for browsers
+func (h *PublicHandler) handleView(w http.ResponseWriter, r *http.Request, share *Share) {
+	data := viewData{
+		Token: share.Token,
+		Name:  path.Base(share.Path),
+		IsDir: share.IsDir,
+	}
+
+	if share.IsDir {
+		result, err := h.list(r, share)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("failed to list share", "token", share.Token, "error", err)
+			http.Error(w, "File or directory not found", http.StatusNotFound)
+			return
+		}
+		data.Path = result.Path
+		data.Items = result.Items
+		if result.Path != "/" {
+			data.Parent = path.Dir(result.Path)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := viewTemplate.Execute(w, data); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render share view", "error", err)
+	}
+}
+
+// list lists the requested directory and rewrites paths relative to the share root
+func (h *PublicHandler) list(r *http.Request, share *Share) (*files.FileListResponse, error) {
+	target, ok := h.resolve(r.Context(), share, r.URL.Query().Get("path"))
+	if !ok {
+		return nil, errors.New("invalid path")
+	}
+
+	result, err := h.svc.ListFiles(r.Context(), target)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Path = toSharePath(share, result.Path)
+	items := result.Items[:0]
+	for _, item := range result.Items {
+		// Links that lead out of the share are not part of it
+		if item.FileType == files.FileTypeSymlink && !h.insideShare(r.Context(), share, item.Path) {
+			result.TotalSize -= item.Size
+			continue
+		}
+		item.Path = toSharePath(share, item.Path)
+		// Owners, inodes and link targets are not for anonymous visitors
+		item.FileMetadata = files.FileMetadata{}
+		items = append(items, item)
+	}
+	result.Items = items
+	result.TotalItems = len(items)
+	return result, nil
+}
+
+// checkPassword enforces the share password through HTTP basic auth. Clients
+// that keep sending wrong passwords are locked out of the share for a while.
+func (h *PublicHandler) checkPassword(w http.ResponseWriter, r *http.Request, share *Share) bool {
+	if share.PasswordHash == "" {
+		return true
+	}
+
+	key := share.Token + "|" + auth.RequestClientIP(r)
+	now := time.Now()
+	if wait := h.throttle.retryAfter(key, now); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+		httputil.SendError(w, "Too many wrong passwords, try again later", http.StatusTooManyRequests)
+		return false
+	}
+
+	_, password, ok := r.BasicAuth()
+	if ok {
+		if bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(password)) == nil {
+			h.throttle.succeed(key)
+			return true
+		}
+		h.throttle.fail(key, now)
+		logging.FromContext(r.Context()).Warn("wrong share password", "token", share.Token, "remote_addr", auth.RequestClientIP(r))
+	}
+
+	w.Header().Set("WWW-Authenticate", `Basic realm="shared link"`)
+	httputil.SendError(w, "Password required", http.StatusUnauthorized)
+	return false
+}
+
+// sendShareError maps store errors to responses
+func (h *PublicHandler) sendShareError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		httputil.SendError(w, "Share not found", http.StatusNotFound)
+	case errors.Is(err, ErrExpired), errors.Is(err, ErrExhausted):
+		httputil.SendError(w, "Share is no longer available", http.StatusGone)
+	default:
+		httputil.SendError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// resolve maps a share-relative path to a service path inside the shared
+// subtree, refusing paths that symbolic links lead out of the share. Paths
+// that do not exist are left for the caller to report.
+func (h *PublicHandler) resolve(ctx context.Context, share *Share, rel string) (string, bool) {
+	target, ok := resolvePath(share, rel)
+	if !ok {
+		return "", false
+	}
+	return target, h.insideShare(ctx, share, target)
+}
+
+// insideShare reports whether target still lies inside the share once every
+// symbolic link along it is followed
+func (h *PublicHandler) insideShare(ctx context.Context, share *Share, target string) bool {
+	real, err := h.svc.RealPath(ctx, target)
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	root, err := h.svc.RealPath(ctx, share.Path)
+	if err != nil {
+		return false
+	}
+	return real == root || root == "/" || strings.HasPrefix(real, root+"/")
+}
+
+// resolvePath maps a share-relative path to a service path inside the shared subtree
+func resolvePath(share *Share, rel string) (string, bool) {
+	if strings.Contains(rel, "..") || strings.Contains(rel, "~") {
+		return "", false
+	}
+
+	clean := path.Clean("/" + rel)
+	if !share.IsDir {
+		// A file share only exposes the file itself
+		return share.Path, clean == "/" || clean == "/"+path.Base(share.Path)
+	}
+	return path.Join(share.Path, clean), true
+}
+
+// toSharePath maps a service path back to a share-relative path
+func toSharePath(share *Share, servicePath string) string {
+	if share.Path == "/" {
+		return servicePath
+	}
+	rel := strings.TrimPrefix(servicePath, share.Path)
+	if rel == "" {
+		return "/"
+	}
+	return rel
+}
+
+// viewData feeds the share view template
+type viewData struct {
+	Token  string
+	Name   string
+	IsDir  bool
+	Path   string
+	Parent string
+	Items  []files.FileItem
+}
+
+var viewTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Name}} - Shared</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: Arial, sans-serif; max-width: 800px; margin: 50px auto; padding: 20px; }
+        table { width: 100%; border-collapse: collapse; }
+        td { padding: 6px 4px; border-bottom: 1px solid #eee; }
+        .size { text-align: right; color: #666; }
+        a { color: #007acc; text-decoration: none; }
+    </style>
+</head>
+<body>
+    <h1>{{.Name}}</h1>
+{{if .IsDir}}
+    <p>{{.Path}}</p>
+    <table>
+    {{if .Parent}}<tr><td><a href="/s/{{.Token}}/?path={{.Parent}}">..</a></td><td></td></tr>{{end}}
+    {{range .Items}}
+        <tr>
+        {{if .IsDir}}
+            <td>📁 <a href="/s/{{$.Token}}/?path={{.Path}}">{{.Name}}</a></td><td></td>
+        {{else}}
+            <td>📄 <a href="/s/{{$.Token}}/raw?path={{.Path}}">{{.Name}}</a></td><td class="size">{{.Size}} bytes</td>
+        {{end}}
+        </tr>
+    {{end}}
+    </table>
+{{else}}
+    <p><a href="/s/{{.Token}}/raw">Download {{.Name}}</a></p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,178 @@
+package shares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/files"
+	"github.com/BomScoob12/homelab-file-manager/internal/filestest"
+	"github.com/BomScoob12/homelab-file-manager/internal/fs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestShare stores share in a fresh store over the directory root and
+// returns the public handler, the store and the share token
+func newTestShare(t *testing.T, root string, share Share) (http.Handler, *Store, string) {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "shares.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := store.Create(share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := files.NewFileServiceWithBackend(fs.NewLocalFS(root))
+	return NewPublicHandler(store, svc), store, created.Token
+}
+
+// get sends a GET for target with the given headers through h
+func get(h http.Handler, target string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRawDownloadCounting(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "whole file", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "range from the start", header: map[string]string{"Range": "bytes=0-99"}, wantStatus: http.StatusPartialContent, wantCount: 1},
+		{name: "open range from the start", header: map[string]string{"Range": "bytes=0-"}, wantStatus: http.StatusPartialContent, wantCount: 1},
+		{name: "seek", header: map[string]string{"Range": "bytes=500-"}, wantStatus: http.StatusPartialContent},
+		{name: "suffix range", header: map[string]string{"Range": "bytes=-100"}, wantStatus: http.StatusPartialContent},
+		{name: "not modified", header: map[string]string{"If-Modified-Since": future}, wantStatus: http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, store, token := newTestShare(t, filestest.Tree(t, map[string]string{"/video.bin": string(make([]byte, 1000))}), Share{Path: "/video.bin", MaxDownloads: 5})
+
+			w := get(h, "/s/"+token+"/raw", tt.header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("Cache-Control"); got != "private, no-store" {
+				t.Fatalf("Cache-Control = %q, want private, no-store", got)
+			}
+			share, err := store.Get(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if share.Downloads != tt.wantCount {
+				t.Fatalf("downloads = %d, want %d", share.Downloads, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRawDownloadLimit(t *testing.T) {
+	h, _, token := newTestShare(t, filestest.Tree(t, map[string]string{"/file.txt": "content"}), Share{Path: "/file.txt", MaxDownloads: 2})
+
+	for i := 0; i < 2; i++ {
+		if w := get(h, "/s/"+token+"/raw", nil); w.Code != http.StatusOK {
+			t.Fatalf("download %d status = %d, want %d", i+1, w.Code, http.StatusOK)
+		}
+	}
+	if w := get(h, "/s/"+token+"/raw", nil); w.Code != http.StatusGone {
+		t.Fatalf("download over the limit status = %d, want %d", w.Code, http.StatusGone)
+	}
+}
+
+func TestSharePaths(t *testing.T) {
+	root := filestest.Tree(t, map[string]string{
+		"/public/a.txt":  "a",
+		"/private/b.txt": "secret",
+		"/other.txt":     "other",
+	})
+	h, _, token := newTestShare(t, root, Share{Path: "/public", IsDir: true})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/a.txt", want: http.StatusOK},
+		{path: "/../private/b.txt", want: http.StatusBadRequest},
+		{path: "/missing.txt", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := get(h, "/s/"+token+"/raw?path="+tt.path, nil); w.Code != tt.want {
+			t.Errorf("raw %s status = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+
+	// A file share only exposes the file itself
+	h, _, token = newTestShare(t, root, Share{Path: "/other.txt"})
+	if w := get(h, "/s/"+token+"/raw?path=/a.txt", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("raw of another file status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestShareSymlinkOutOfShare(t *testing.T) {
+	root := filestest.Tree(t, map[string]string{"/public/a.txt": "a", "/private/b.txt": "secret"})
+	if err := os.Symlink("../private/b.txt", filepath.Join(root, "public", "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	h, _, token := newTestShare(t, root, Share{Path: "/public", IsDir: true})
+
+	if w := get(h, "/s/"+token+"/raw?path=/link.txt", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("raw through a link out of the share status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestPasswordThrottle(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _, token := newTestShare(t, filestest.Tree(t, map[string]string{"/file.txt": "content"}), Share{Path: "/file.txt", PasswordHash: string(hash)})
+	send := func(password string) int {
+		r := httptest.NewRequest(http.MethodGet, "/s/"+token+"/raw", nil)
+		r.SetBasicAuth("", password)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := send("secret"); code != http.StatusOK {
+		t.Fatalf("right password status = %d, want %d", code, http.StatusOK)
+	}
+	for i := 0; i < maxPasswordFailures; i++ {
+		if code := send("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("wrong password %d status = %d, want %d", i+1, code, http.StatusUnauthorized)
+		}
+	}
+	if code := send("secret"); code != http.StatusTooManyRequests {
+		t.Fatalf("right password while locked out status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestPasswordThrottleExpires(t *testing.T) {
+	throttle := newPasswordThrottle(2, time.Minute)
+	start := time.Now()
+	throttle.fail("key", start)
+	throttle.fail("key", start)
+
+	if wait := throttle.retryAfter("key", start.Add(10*time.Second)); wait != 50*time.Second {
+		t.Fatalf("retryAfter() = %v, want 50s", wait)
+	}
+	if wait := throttle.retryAfter("other", start); wait != 0 {
+		t.Fatalf("retryAfter() of another client = %v, want 0", wait)
+	}
+	if wait := throttle.retryAfter("key", start.Add(time.Minute)); wait != 0 {
+		t.Fatalf("retryAfter() after the lockout = %v, want 0", wait)
+	}
+}
@@ -0,0 +1,192 @@
+package shares
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/statefile"
+)
+
+// Errors returned by the share store
+var (
+	ErrNotFound  = errors.New("share not found")
+	ErrExpired   = errors.New("share expired")
+	ErrExhausted = errors.New("share download limit reached")
+)
+
+// Share maps a random token to a file or directory below the base path
+type Share struct {
+	Token        string     `json:"token"`
+	Path         string     `json:"path"`
+	IsDir        bool       `json:"isDir"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	MaxDownloads int        `json:"maxDownloads,omitempty"`
+	Downloads    int        `json:"downloads"`
+	PasswordHash string     `json:"passwordHash,omitempty"`
+}
+
+// Expired reports whether the share is past its expiry time
+func (s *Share) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && now.After(*s.ExpiresAt)
+}
+
+// Exhausted reports whether the share has used up its download allowance
+func (s *Share) Exhausted() bool {
+	return s.MaxDownloads > 0 && s.Downloads >= s.MaxDownloads
+}
+
+// Store keeps shares in memory and persists them to a JSON file
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	shares map[string]*Share
+}
+
+// NewStore loads the share store from path, starting empty if it does not exist
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:   path,
+		shares: make(map[string]*Share),
+	}
+
+	var shares []*Share
+	if _, err := statefile.Load(path, &shares); err != nil {
+		return nil, fmt.Errorf("failed to load share store: %w", err)
+	}
+	for _, share := range shares {
+		s.shares[share.Token] = share
+	}
+
+	return s, nil
+}
+
+// Create stores a new share under a fresh random token
+func (s *Store) Create(share Share) (*Share, error) {
+	token, err := auth.RandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	share.Token = token
+	share.CreatedAt = time.Now().UTC()
+	share.Downloads = 0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shares[token] = &share
+	if err := s.save(); err != nil {
+		delete(s.shares, token)
+		return nil, err
+	}
+
+	result := share
+	return &result, nil
+}
+
+// Get returns a copy of the share with the given token
+func (s *Store) Get(token string) (*Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, exists := s.shares[token]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	result := *share
+	return &result, nil
+}
+
+// Active returns the share if it exists, has not expired and has downloads left
+func (s *Store) Active(token string) (*Share, error) {
+	share, err := s.Get(token)
+	if err != nil {
+		return nil, err
+	}
+	if share.Expired(time.Now()) {
+		return nil, ErrExpired
+	}
+	if share.Exhausted() {
+		return nil, ErrExhausted
+	}
+	return share, nil
+}
+
+// List returns all shares created by owner, or every share when owner is empty
+func (s *Store) List(owner string) []*Share {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []*Share{}
+	for _, share := range s.shares {
+		if owner == "" || share.CreatedBy == owner {
+			copied := *share
+			result = append(result, &copied)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Revoke deletes a share
+func (s *Store) Revoke(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, exists := s.shares[token]
+	if !exists {
+		return ErrNotFound
+	}
+
+	delete(s.shares, token)
+	if err := s.save(); err != nil {
+		s.shares[token] = share
+		return err
+	}
+	return nil
+}
+
+// ConsumeDownload atomically checks the share limits and counts one download
+func (s *Store) ConsumeDownload(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, exists := s.shares[token]
+	if !exists {
+		return ErrNotFound
+	}
+	if share.Expired(time.Now()) {
+		return ErrExpired
+	}
+	if share.Exhausted() {
+		return ErrExhausted
+	}
+
+	share.Downloads++
+	if err := s.save(); err != nil {
+		share.Downloads--
+		return err
+	}
+	return nil
+}
+
+// save writes the store atomically; the caller must hold the lock
+func (s *Store) save() error {
+	shares := make([]*Share, 0, len(s.shares))
+	for _, share := range s.shares {
+		shares = append(shares, share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].CreatedAt.Before(shares[j].CreatedAt)
+	})
+
+	return statefile.Save(s.path, shares)
+}
@@ -0,0 +1,85 @@
+package shares
+
+import (
+	"sync"
+	"time"
+)
+
+// Limits on wrong share passwords, per share and client address
+const (
+	maxPasswordFailures = 5
+	passwordLockout     = 15 * time.Minute
+)
+
+// passwordThrottle counts wrong passwords so a share password cannot be
+// guessed quickly. A client that fails maxPasswordFailures times is locked
+// out of that share until lockout has passed since its first failure.
+type passwordThrottle struct {
+	mu       sync.Mutex
+	max      int
+	lockout  time.Duration
+	failures map[string]*passwordFailures
+}
+
+// passwordFailures is the failure count of one client on one share
+type passwordFailures struct {
+	count int
+	since time.Time
+}
+
+func newPasswordThrottle(max int, lockout time.Duration) *passwordThrottle {
+	return &passwordThrottle{
+		max:      max,
+		lockout:  lockout,
+		failures: make(map[string]*passwordFailures),
+	}
+}
+
+// retryAfter returns how long key is still locked out, or 0 when it may try
+func (t *passwordThrottle) retryAfter(key string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures, ok := t.failures[key]
+	if !ok {
+		return 0
+	}
+	if expires := failures.since.Add(t.lockout); !now.Before(expires) {
+		delete(t.failures, key)
+		return 0
+	} else if failures.count >= t.max {
+		return expires.Sub(now)
+	}
+	return 0
+}
+
+// fail records a wrong password for key
+func (t *passwordThrottle) fail(key string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures, ok := t.failures[key]
+	if !ok || !now.Before(failures.since.Add(t.lockout)) {
+		t.prune(now)
+		failures = &passwordFailures{since: now}
+		t.failures[key] = failures
+	}
+	failures.count++
+}
+
+// succeed forgets the failures of key
+func (t *passwordThrottle) succeed(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.failures, key)
+}
+
+// prune drops expired failure counts; the caller holds the lock
+func (t *passwordThrottle) prune(now time.Time) {
+	for key, failures := range t.failures {
+		if !now.Before(failures.since.Add(t.lockout)) {
+			delete(t.failures, key)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package versions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxEditDistance bounds the work done by Diff; larger differences are reported as too large
+const maxEditDistance = 4000
+
+// ErrDiffTooLarge is returned when two texts differ in too many lines to diff
+var ErrDiffTooLarge = errors.New("files differ too much to diff")
+
+// edit kinds of a line in the edit script
+const (
+	editEqual = iota
+	editDelete
+	editInsert
+)
+
+// lineEdit is one line of the edit script turning a into b
+type lineEdit struct {
+	kind int
+	text string
+}
+
+// Diff returns a unified diff turning a into b, labelled with the two names;
+// identical texts give an empty diff
+func Diff(a, b, nameA, nameB string) (string, error) {
+	if a == b {
+		return "", nil
+	}
+
+	edits, err := diffLines(splitLines(a), splitLines(b))
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	writeHunks(&out, edits)
+	return out.String(), nil
+}
+
+// splitLines splits text after each newline, keeping the line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script with Myers' algorithm, keeping
+// only the active diagonals of each step so memory grows with the square of
+// the edit distance rather than the file sizes
+func diffLines(a, b []string) ([]lineEdit, error) {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD > maxEditDistance {
+		maxD = maxEditDistance
+	}
+
+	// trace[d][k+d] is the furthest x reached on diagonal k after d edits
+	var trace [][]int
+	v := map[int]int{1: 0}
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		row := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1] < v[k+1]) {
+				x = v[k+1]
+			} else {
+				x = v[k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k] = x
+			row[k+d] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+		trace = append(trace, row)
+	}
+	if !found {
+		return nil, ErrDiffTooLarge
+	}
+
+	// Walk the trace backwards to recover the script
+	var edits []lineEdit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, lineEdit{editEqual, a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, lineEdit{editInsert, b[y]})
+		} else {
+			x--
+			edits = append(edits, lineEdit{editDelete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, lineEdit{editEqual, a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits, nil
+}
+
+// writeHunks writes the edit script as unified diff hunks with context lines
+func writeHunks(out *strings.Builder, edits []lineEdit) {
+	// Line numbers in a and b before each edit
+	lineA := make([]int, len(edits)+1)
+	lineB := make([]int, len(edits)+1)
+	for i, e := range edits {
+		lineA[i+1], lineB[i+1] = lineA[i], lineB[i]
+		if e.kind != editInsert {
+			lineA[i+1]++
+		}
+		if e.kind != editDelete {
+			lineB[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].kind == editEqual {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are within two contexts of each other
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].kind != editEqual {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		stop := end + diffContext
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		countA, countB := lineA[stop]-lineA[start], lineB[stop]-lineB[start]
+		fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(lineA[start], countA), hunkRange(lineB[start], countB))
+		for _, e := range edits[start:stop] {
+			prefix := " "
+			switch e.kind {
+			case editDelete:
+				prefix = "-"
+			case editInsert:
+				prefix = "+"
+			}
+			out.WriteString(prefix + e.text)
+			if !strings.HasSuffix(e.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+}
+
+// hunkRange formats the start,count pair of a hunk header
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,59 @@
+package versions
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{name: "identical", a: "a\nb\n", b: "a\nb\n", want: ""},
+		{
+			name: "changed line",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "added to an empty file",
+			a:    "",
+			b:    "a\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.a, tt.b, "old", "new")
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Diff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTooLarge(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < maxEditDistance+1; i++ {
+		a.WriteString("a\n")
+		b.WriteString("b\n")
+	}
+	if _, err := Diff(a.String(), b.String(), "old", "new"); !errors.Is(err, ErrDiffTooLarge) {
+		t.Fatalf("Diff() error = %v, want %v", err, ErrDiffTooLarge)
+	}
+}
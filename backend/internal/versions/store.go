@@ -0,0 +1,311 @@
+package versions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/config"
+	"github.com/BomScoob12/homelab-file-manager/internal/statefile"
+)
+
+// Defaults used when the VERSIONS_* variables are unset
+const (
+	defaultMaxCount      = 20
+	defaultMaxAge        = 30 * 24 * time.Hour
+	defaultMaxFileSizeMB = 10
+)
+
+// Reasons recorded with a version
+const (
+	ReasonEdit      = "edit"
+	ReasonOverwrite = "overwrite"
+	ReasonRestore   = "restore"
+)
+
+// ErrNotFound is returned for unknown version IDs
+var ErrNotFound = errors.New("version not found")
+
+// Version is one kept copy of a file's earlier content
+type Version struct {
+	ID      string    `json:"id"`
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SavedAt time.Time `json:"savedAt"`
+	SavedBy string    `json:"savedBy"`
+	Reason  string    `json:"reason"`
+}
+
+// Store keeps earlier file versions in a content-addressed object directory,
+// so identical content is stored once however many versions refer to it.
+// The index maps virtual paths to their versions, newest first.
+type Store struct {
+	mu          sync.Mutex
+	dir         string
+	maxCount    int
+	maxAge      time.Duration
+	maxFileSize int64
+	index       map[string][]Version
+}
+
+// Default is the process-wide version store; versioning is off until Setup is called
+var Default = &Store{}
+
+// Setup opens the default store under the state directory. VERSIONS_MAX_COUNT
+// and VERSIONS_MAX_AGE bound how many versions of each file are kept and for
+// how long (0 keeps them forever); VERSIONS_MAX_FILE_SIZE_MB skips larger
+// files, and 0 turns versioning off.
+func Setup() error {
+	maxCount := config.EnvInt("VERSIONS_MAX_COUNT", defaultMaxCount, 0)
+	maxFileSizeMB := config.EnvInt("VERSIONS_MAX_FILE_SIZE_MB", defaultMaxFileSizeMB, 0)
+
+	maxAge := defaultMaxAge
+	if value := os.Getenv("VERSIONS_MAX_AGE"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("VERSIONS_MAX_AGE must be a duration such as 720h")
+		}
+		maxAge = parsed
+	}
+
+	if maxFileSizeMB == 0 {
+		slog.Info("file versioning disabled")
+		return nil
+	}
+
+	store, err := NewStore(config.StateDir("versions"), maxCount, maxAge, int64(maxFileSizeMB)*1024*1024)
+	if err != nil {
+		return err
+	}
+	Default = store
+	return nil
+}
+
+// NewStore loads the store in dir and applies the age limit to versions already kept
+func NewStore(dir string, maxCount int, maxAge time.Duration, maxFileSize int64) (*Store, error) {
+	s := &Store{
+		dir:         dir,
+		maxCount:    maxCount,
+		maxAge:      maxAge,
+		maxFileSize: maxFileSize,
+		index:       make(map[string][]Version),
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create version store: %w", err)
+	}
+	if _, err := statefile.Load(s.indexPath(), &s.index); err != nil {
+		return nil, fmt.Errorf("failed to load version index: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var dropped []Version
+	for path := range s.index {
+		dropped = append(dropped, s.prune(path)...)
+	}
+	if len(dropped) > 0 {
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+		s.removeUnreferenced(dropped)
+	}
+	return s, nil
+}
+
+// Enabled reports whether versions are being kept
+func (s *Store) Enabled() bool {
+	return s.dir != ""
+}
+
+// Accepts reports whether a file of the given size is versioned
+func (s *Store) Accepts(size int64) bool {
+	return s.Enabled() && size <= s.maxFileSize
+}
+
+// Snapshot keeps content as the newest version of path. info describes the
+// content being replaced; a snapshot identical to the newest version is skipped.
+func (s *Store) Snapshot(ctx context.Context, path string, content io.Reader, info os.FileInfo, reason string) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	id, err := auth.RandomToken(9)
+	if err != nil {
+		return fmt.Errorf("failed to generate version id: %w", err)
+	}
+
+	// Store under the lock so pruning cannot remove an object that is being reused
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, size, err := s.storeObject(content)
+	if err != nil {
+		return err
+	}
+
+	existing := s.index[path]
+	if len(existing) > 0 && existing[0].Hash == hash {
+		return nil
+	}
+
+	version := Version{
+		ID:      id,
+		Hash:    hash,
+		Size:    size,
+		ModTime: info.ModTime().UTC(),
+		SavedAt: time.Now().UTC(),
+		SavedBy: auth.FromContext(ctx).Username,
+		Reason:  reason,
+	}
+	s.index[path] = append([]Version{version}, existing...)
+	dropped := s.prune(path)
+
+	if err := s.save(); err != nil {
+		s.index[path] = existing
+		return err
+	}
+	s.removeUnreferenced(dropped)
+	return nil
+}
+
+// List returns the versions of path, newest first
+func (s *Store) List(path string) []Version {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := make([]Version, len(s.index[path]))
+	copy(versions, s.index[path])
+	return versions
+}
+
+// Get returns one version of path
+func (s *Store) Get(path, id string) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, version := range s.index[path] {
+		if version.ID == id {
+			return version, nil
+		}
+	}
+	return Version{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Open opens the stored content of a version
+func (s *Store) Open(version Version) (*os.File, error) {
+	file, err := os.Open(s.objectPath(version.Hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open version content: %w", err)
+	}
+	return file, nil
+}
+
+// storeObject writes content to the object directory under its SHA-256,
+// keeping the existing object when the same content is already stored
+func (s *Store) storeObject(content io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, "objects"), ".incoming-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to store version: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to store version: %w", err)
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	target := s.objectPath(hash)
+	if _, err := os.Stat(target); err == nil {
+		return hash, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return "", 0, fmt.Errorf("failed to store version: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", 0, fmt.Errorf("failed to store version: %w", err)
+	}
+	return hash, size, nil
+}
+
+// prune applies the count and age limits to the versions of path and returns
+// the versions it dropped; the caller holds the lock
+func (s *Store) prune(path string) []Version {
+	versions := s.index[path]
+	cutoff := time.Now().Add(-s.maxAge)
+
+	keep := len(versions)
+	if s.maxCount > 0 && keep > s.maxCount {
+		keep = s.maxCount
+	}
+	if s.maxAge > 0 {
+		// Versions are newest first, so the expired ones form the tail
+		keep = sort.Search(keep, func(i int) bool { return versions[i].SavedAt.Before(cutoff) })
+	}
+
+	dropped := versions[keep:]
+	if keep == 0 {
+		delete(s.index, path)
+	} else {
+		s.index[path] = versions[:keep:keep]
+	}
+	return dropped
+}
+
+// removeUnreferenced deletes the objects of dropped versions that no remaining
+// version refers to; the caller holds the lock
+func (s *Store) removeUnreferenced(dropped []Version) {
+	candidates := make(map[string]bool)
+	for _, version := range dropped {
+		candidates[version.Hash] = true
+	}
+	for _, versions := range s.index {
+		for _, version := range versions {
+			delete(candidates, version.Hash)
+		}
+	}
+
+	for hash := range candidates {
+		if err := os.Remove(s.objectPath(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("failed to remove version object", "hash", hash, "error", err)
+		}
+	}
+}
+
+// save persists the index; the caller holds the lock
+func (s *Store) save() error {
+	if err := statefile.Save(s.indexPath(), s.index); err != nil {
+		return fmt.Errorf("failed to save version index: %w", err)
+	}
+	return nil
+}
+
+// indexPath returns the location of the version index
+func (s *Store) indexPath() string {
+	return filepath.Join(s.dir, "index.json")
+}
+
+// objectPath returns where content with the given hash is stored
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.dir, "objects", hash[:2], hash)
+}
@@ -0,0 +1,155 @@
+package versions
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+)
+
+// testInfo describes the content being replaced in a snapshot
+type testInfo struct {
+	os.FileInfo
+	modTime time.Time
+}
+
+func (i testInfo) ModTime() time.Time { return i.modTime }
+
+// snapshot keeps content as a version of path
+func snapshot(t *testing.T, s *Store, path, content string) {
+	t.Helper()
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Username: "alice"})
+	if err := s.Snapshot(ctx, path, strings.NewReader(content), testInfo{modTime: time.Now()}, ReasonEdit); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+}
+
+// readVersion returns the stored content of a version
+func readVersion(t *testing.T, s *Store, version Version) string {
+	t.Helper()
+	file, err := s.Open(version)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// objectCount returns how many objects the store holds
+func objectCount(t *testing.T, dir string) int {
+	t.Helper()
+	count := 0
+	err := filepath.Walk(filepath.Join(dir, "objects"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestStoreSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir, 0, 0, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot(t, s, "/notes.txt", "one")
+	snapshot(t, s, "/notes.txt", "two")
+	snapshot(t, s, "/notes.txt", "two")
+	snapshot(t, s, "/other.txt", "one")
+
+	list := s.List("/notes.txt")
+	if len(list) != 2 {
+		t.Fatalf("List() = %d versions, want 2 with the repeated snapshot skipped", len(list))
+	}
+	if got := readVersion(t, s, list[0]); got != "two" {
+		t.Fatalf("newest version = %q, want %q", got, "two")
+	}
+	if list[0].SavedBy != "alice" || list[0].Reason != ReasonEdit || list[0].Size != 3 {
+		t.Fatalf("newest version = %+v, want alice's 3 byte edit", list[0])
+	}
+	if count := objectCount(t, dir); count != 2 {
+		t.Fatalf("objects = %d, want identical content stored once", count)
+	}
+
+	if _, err := s.Get("/notes.txt", list[1].ID); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := s.Get("/other.txt", list[1].ID); err == nil {
+		t.Fatal("Get() found a version of another file")
+	}
+
+	// The index survives a restart
+	reopened, err := NewStore(dir, 0, 0, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.List("/notes.txt"); len(got) != 2 || got[0].ID != list[0].ID {
+		t.Fatalf("reopened List() = %+v, want the same versions", got)
+	}
+}
+
+func TestStorePrunes(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir, 2, 0, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot(t, s, "/notes.txt", "one")
+	snapshot(t, s, "/notes.txt", "two")
+	snapshot(t, s, "/notes.txt", "three")
+
+	list := s.List("/notes.txt")
+	if len(list) != 2 || readVersion(t, s, list[1]) != "two" {
+		t.Fatalf("List() = %+v, want the two newest versions", list)
+	}
+	if count := objectCount(t, dir); count != 2 {
+		t.Fatalf("objects = %d, want the pruned content removed", count)
+	}
+
+	// Versions past the age limit are dropped when the store is opened
+	time.Sleep(10 * time.Millisecond)
+	reopened, err := NewStore(dir, 2, time.Millisecond, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.List("/notes.txt"); len(got) != 0 {
+		t.Fatalf("List() after expiry = %+v, want none", got)
+	}
+	if count := objectCount(t, dir); count != 0 {
+		t.Fatalf("objects after expiry = %d, want none", count)
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	s, err := NewStore(t.TempDir(), 0, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Accepts(10) || s.Accepts(11) {
+		t.Fatal("Accepts() does not follow the size limit")
+	}
+
+	var disabled Store
+	if disabled.Enabled() || disabled.Accepts(0) {
+		t.Fatal("a store without a directory keeps versions")
+	}
+	if err := disabled.Snapshot(context.Background(), "/a", strings.NewReader("a"), testInfo{}, ReasonEdit); err != nil {
+		t.Fatalf("Snapshot() on a disabled store error = %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// directory is one watched directory with its subscribers and recent history
+type directory struct {
+	hub       *Hub
+	path      string
+	epoch     string
+	listeners []Listener
+
+	mu          sync.Mutex
+	snapshot    map[string]os.FileInfo
+	pending     map[string]fsnotify.Op
+	order       []string
+	timer       *time.Timer
+	seq         uint64
+	history     []sequenced
+	subscribers map[*Subscription]bool
+	pinned      bool
+	closed      bool
+}
+
+// sequenced is an event with its sequence number
+type sequenced struct {
+	seq   uint64
+	event Event
+}
+
+// readSnapshot lists a directory without following symbolic links, leaving
+// out readiness probe files
+func readSnapshot(dir string) (map[string]os.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	snapshot := make(map[string]os.FileInfo, len(entries))
+	for _, entry := range entries {
+		if isProbe(entry.Name()) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			snapshot[entry.Name()] = info
+		}
+	}
+	return snapshot, nil
+}
+
+// subscribe adds a subscriber, replaying the events after lastEventID when
+// they are still in the history
+func (d *directory) subscribe(lastEventID string) *Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var replay []Event
+	resync := false
+	if lastEventID != "" {
+		epoch, lastSeq, ok := parseEventID(lastEventID)
+		oldest := d.seq + 1
+		if len(d.history) > 0 {
+			oldest = d.history[0].seq
+		}
+		if ok && epoch == d.epoch && lastSeq <= d.seq && lastSeq+1 >= oldest {
+			for _, entry := range d.history {
+				if entry.seq > lastSeq {
+					replay = append(replay, entry.event)
+				}
+			}
+		} else {
+			resync = true
+		}
+	}
+
+	c := make(chan Event, subscriberBuffer+len(replay))
+	for _, event := range replay {
+		c <- event
+	}
+	sub := &Subscription{C: c, c: c, dir: d, Resync: resync}
+	d.subscribers[sub] = true
+	return sub
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	d := s.dir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if s.done {
+		return
+	}
+	d.drop(s)
+	if len(d.subscribers) == 0 && !d.pinned && !d.closed {
+		d.hub.release(d)
+	}
+}
+
+// drop removes a subscriber and closes its channel; the caller holds the lock
+func (d *directory) drop(s *Subscription) {
+	s.done = true
+	delete(d.subscribers, s)
+	close(s.c)
+}
+
+// pin keeps the directory watched without subscribers
+func (d *directory) pin() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pinned = true
+}
+
+// inUse reports whether the directory is pinned or has subscribers
+func (d *directory) inUse() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pinned || len(d.subscribers) > 0
+}
+
+// close ends all subscriptions of the directory
+func (d *directory) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	for sub := range d.subscribers {
+		d.drop(sub)
+	}
+}
+
+// record notes a raw change of an entry and schedules a flush after the debounce period
+func (d *directory) record(name string, op fsnotify.Op) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+	if _, seen := d.pending[name]; !seen {
+		d.order = append(d.order, name)
+	}
+	d.pending[name] |= op
+	if d.timer == nil {
+		d.timer = time.AfterFunc(d.hub.debounce, d.flush)
+	}
+}
+
+// flush turns the changes collected since the last flush into events by
+// comparing the entries before and after: a file created and deleted again
+// produces nothing, a file written many times one modify, and an entry
+// renamed within the directory one rename.
+func (d *directory) flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending, order := d.pending, d.order
+	d.pending, d.order, d.timer = make(map[string]fsnotify.Op), nil, nil
+	if d.closed {
+		return
+	}
+
+	current := make(map[string]os.FileInfo, len(order))
+	for _, name := range order {
+		if info, err := os.Lstat(filepath.Join(d.path, name)); err == nil {
+			current[name] = info
+		}
+	}
+
+	// Pair entries renamed away with new entries that are the same file
+	renamedTo := make(map[string]string)
+	paired := make(map[string]bool)
+	for _, oldName := range order {
+		before, existed := d.snapshot[oldName]
+		if _, exists := current[oldName]; !existed || exists || pending[oldName]&fsnotify.Rename == 0 {
+			continue
+		}
+		for _, newName := range order {
+			if _, existed := d.snapshot[newName]; existed || paired[newName] {
+				continue
+			}
+			if after, exists := current[newName]; exists && os.SameFile(before, after) {
+				renamedTo[oldName] = newName
+				paired[newName] = true
+				break
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	var events []Event
+	for _, name := range order {
+		if paired[name] {
+			continue
+		}
+		before, existed := d.snapshot[name]
+		after, exists := current[name]
+
+		event := Event{Name: name, Info: after, Time: now}
+		switch {
+		case renamedTo[name] != "":
+			newName := renamedTo[name]
+			event = Event{Type: EventRename, Name: newName, From: name, Info: current[newName], Time: now}
+			delete(d.snapshot, name)
+			d.snapshot[newName] = current[newName]
+		case !existed && exists:
+			event.Type = EventCreate
+			d.snapshot[name] = after
+		case existed && !exists:
+			event.Type = EventDelete
+			delete(d.snapshot, name)
+		case existed && exists && changed(before, after):
+			event.Type = EventModify
+			d.snapshot[name] = after
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+
+	for i := range events {
+		event := &events[i]
+		d.seq++
+		event.ID = fmt.Sprintf("%s-%d", d.epoch, d.seq)
+		d.history = append(d.history, sequenced{seq: d.seq, event: *event})
+		if len(d.history) > historySize {
+			d.history = d.history[len(d.history)-historySize:]
+		}
+		d.publish(*event)
+	}
+	if len(events) > 0 {
+		for _, listener := range d.listeners {
+			listener(d.path, events)
+		}
+	}
+}
+
+// publish sends an event to every subscriber; subscribers that fell too far
+// behind are dropped and told to resync. The caller holds the lock.
+func (d *directory) publish(event Event) {
+	for sub := range d.subscribers {
+		select {
+		case sub.c <- event:
+		default:
+			sub.Resync = true
+			d.drop(sub)
+		}
+	}
+}
+
+// changed reports whether an entry was replaced or its metadata changed
+func changed(before, after os.FileInfo) bool {
+	return !os.SameFile(before, after) ||
+		before.Size() != after.Size() ||
+		!before.ModTime().Equal(after.ModTime()) ||
+		before.Mode() != after.Mode()
+}
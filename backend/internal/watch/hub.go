@@ -0,0 +1,301 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/BomScoob12/homelab-file-manager/internal/auth"
+	"github.com/BomScoob12/homelab-file-manager/internal/health"
+)
+
+// Event types
+const (
+	EventCreate = "create"
+	EventModify = "modify"
+	EventDelete = "delete"
+	EventRename = "rename"
+)
+
+// Defaults used when the WATCH_* variables are unset
+const (
+	defaultDebounce = 250 * time.Millisecond
+	defaultLinger   = 30 * time.Second
+	// historySize is how many events of each directory are kept for resuming streams
+	historySize = 1000
+	// subscriberBuffer is how many events a slow subscriber may fall behind before it is dropped
+	subscriberBuffer = 256
+)
+
+// ErrClosed is returned when subscribing to a closed hub
+var ErrClosed = errors.New("watch hub closed")
+
+// Event is a coalesced change of one entry of a watched directory
+type Event struct {
+	ID   string
+	Type string
+	// Name is the entry's name in the directory; From is its previous name for renames
+	Name string
+	From string
+	// Info describes the entry after the change; it is nil for deletes
+	Info os.FileInfo
+	Time time.Time
+}
+
+// Subscription receives the events of one directory until it is closed
+type Subscription struct {
+	// C delivers events; it is closed when the directory goes away, the hub
+	// closes, or the subscriber falls too far behind
+	C <-chan Event
+	// Resync is set when events were lost and the listing must be reloaded:
+	// the requested Last-Event-ID could not be resumed from, or the
+	// subscriber fell behind
+	Resync bool
+
+	c    chan Event
+	dir  *directory
+	done bool
+}
+
+// Hub shares one inotify instance between all watched directories, and one
+// watch between all subscribers of a directory. Directories stay watched for
+// a while after their last subscriber leaves, so reconnecting clients can
+// resume where they stopped.
+type Hub struct {
+	mu        sync.Mutex
+	watcher   *fsnotify.Watcher
+	dirs      map[string]*directory
+	debounce  time.Duration
+	linger    time.Duration
+	listeners []Listener
+	closed    bool
+}
+
+// Listener is called with the events of every watched directory, in order.
+// It runs while the directory is locked and must not block or call the hub.
+type Listener func(dir string, events []Event)
+
+// Default is the process-wide watch hub
+var Default = NewHub(defaultDebounce, defaultLinger)
+
+// Setup configures the default hub; WATCH_DEBOUNCE sets how long changes are
+// collected before they are coalesced and sent, as a duration such as 250ms
+func Setup() error {
+	debounce := defaultDebounce
+	if value := os.Getenv("WATCH_DEBOUNCE"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("WATCH_DEBOUNCE must be a duration such as 250ms")
+		}
+		debounce = parsed
+	}
+	Default = NewHub(debounce, defaultLinger)
+	return nil
+}
+
+// NewHub creates a hub; the inotify instance is created with the first subscription
+func NewHub(debounce, linger time.Duration) *Hub {
+	return &Hub{
+		dirs:     make(map[string]*directory),
+		debounce: debounce,
+		linger:   linger,
+	}
+}
+
+// Subscribe watches the local directory dir. lastEventID resumes a stream
+// after the given event; when that is not possible the subscription is
+// marked for resync.
+func (h *Hub) Subscribe(dir, lastEventID string) (*Subscription, error) {
+	dir = filepath.Clean(dir)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	d, err := h.directory(dir)
+	if err != nil {
+		return nil, err
+	}
+	return d.subscribe(lastEventID), nil
+}
+
+// Pin keeps the local directory dir watched without subscribers, until it is
+// removed or renamed, so listeners see its changes
+func (h *Hub) Pin(dir string) error {
+	dir = filepath.Clean(dir)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	d, err := h.directory(dir)
+	if err != nil {
+		return err
+	}
+	d.pin()
+	return nil
+}
+
+// Listen adds a listener for the events of the directories watched from now on
+func (h *Hub) Listen(listener Listener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listeners = append(h.listeners, listener)
+}
+
+// directory returns the watched directory dir, starting to watch it when
+// needed; the caller holds the lock
+func (h *Hub) directory(dir string) (*directory, error) {
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if h.watcher == nil {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start watcher: %w", err)
+		}
+		h.watcher = watcher
+		go h.run(watcher)
+	}
+
+	if d, exists := h.dirs[dir]; exists {
+		return d, nil
+	}
+	d, err := h.watchDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	h.dirs[dir] = d
+	return d, nil
+}
+
+// Close ends all subscriptions and stops watching; used on shutdown so open
+// streams do not hold up the server
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	for path, d := range h.dirs {
+		d.close()
+		delete(h.dirs, path)
+	}
+	if h.watcher != nil {
+		return h.watcher.Close()
+	}
+	return nil
+}
+
+// watchDirectory starts watching dir; the caller holds the lock
+func (h *Hub) watchDirectory(dir string) (*directory, error) {
+	epoch, err := auth.RandomToken(6)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start watcher: %w", err)
+	}
+
+	d := &directory{
+		hub:         h,
+		path:        dir,
+		listeners:   h.listeners,
+		epoch:       strings.ReplaceAll(epoch, "-", "_"),
+		subscribers: make(map[*Subscription]bool),
+		pending:     make(map[string]fsnotify.Op),
+	}
+	// Take the snapshot before adding the watch, so no change is missed in between
+	if d.snapshot, err = readSnapshot(dir); err != nil {
+		return nil, err
+	}
+	if err := h.watcher.Add(dir); err != nil {
+		return nil, fmt.Errorf("failed to watch directory: %w", err)
+	}
+	return d, nil
+}
+
+// release stops watching a directory once it has been unused for the linger period
+func (h *Hub) release(d *directory) {
+	time.AfterFunc(h.linger, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if h.dirs[d.path] != d || d.inUse() {
+			return
+		}
+		delete(h.dirs, d.path)
+		d.close()
+		if err := h.watcher.Remove(d.path); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
+			slog.Debug("failed to remove watch", "path", d.path, "error", err)
+		}
+	})
+}
+
+// run dispatches raw inotify events to the watched directories
+func (h *Hub) run(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			h.dispatch(event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("directory watcher error", "error", err)
+		}
+	}
+}
+
+// dispatch hands an event to the directory it happened in
+func (h *Hub) dispatch(event fsnotify.Event) {
+	if isProbe(filepath.Base(event.Name)) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// A watched directory went away; the watches below it would keep
+	// reporting their old paths, so they end too. Its parent still records
+	// the removal.
+	if _, watched := h.dirs[event.Name]; watched && event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		for path, d := range h.dirs {
+			if path == event.Name || strings.HasPrefix(path, event.Name+string(filepath.Separator)) {
+				delete(h.dirs, path)
+				d.close()
+				if err := h.watcher.Remove(path); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
+					slog.Debug("failed to remove watch", "path", path, "error", err)
+				}
+			}
+		}
+	}
+
+	if d, exists := h.dirs[filepath.Dir(event.Name)]; exists {
+		d.record(filepath.Base(event.Name), event.Op)
+	}
+}
+
+// isProbe reports whether name is a readiness probe file, which is created
+// and removed in the base path on every /readyz call
+func isProbe(name string) bool {
+	return strings.HasPrefix(name, health.ProbePrefix)
+}
+
+// parseEventID splits an event ID into the epoch of its directory watch and its sequence number
+func parseEventID(id string) (string, uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return "", 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return epoch, n, err == nil
+}